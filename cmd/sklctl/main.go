@@ -0,0 +1,288 @@
+// Command sklctl inspects SkipList and ZSet snapshots.
+//
+// A snapshot is a JSON array of two-element arrays, as produced by
+// encoding the result of ZSet.Marshal (member, score pairs in rank
+// order) or the key-value pairs of a SkipList in key order. Keys and
+// scores must be either all numbers or all strings.
+//
+// Usage:
+//
+//	sklctl [-kind zset|map] <snapshot.json> <command> [arguments]
+//
+// The commands are:
+//
+//	query <key>        print the score (zset) or value (map) of key
+//	rank <key>         print the 1-based rank of key
+//	range <from> <to>  print the elements ranked from..to (inclusive)
+//	stats              print the number of elements and the extremes
+//	validate           check that the snapshot can be loaded
+//
+// Command arguments are parsed as JSON when possible and taken as
+// strings otherwise, so both 42 and alice are valid keys.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+
+	"github.com/longzhiri/goskiplist/skiplist"
+)
+
+var kind = flag.String("kind", "zset", "snapshot kind: zset or map")
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: sklctl [-kind zset|map] <snapshot.json> <command> [arguments]\n")
+	fmt.Fprintf(os.Stderr, "commands: query <key>, rank <key>, range <from> <to>, stats, validate\n")
+	flag.PrintDefaults()
+	os.Exit(2)
+}
+
+func fatalf(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, "sklctl: "+format+"\n", args...)
+	os.Exit(1)
+}
+
+// lessThan orders the values produced by encoding/json. Numbers and
+// strings are supported; validate makes sure they are not mixed.
+func lessThan(l, r interface{}) bool {
+	switch l := l.(type) {
+	case float64:
+		return l < r.(float64)
+	case string:
+		return l < r.(string)
+	}
+	panic(fmt.Sprintf("sklctl: unsupported key type %T", l))
+}
+
+// parseArg interprets a command line argument as a JSON value, falling
+// back to a plain string.
+func parseArg(arg string) interface{} {
+	var v interface{}
+	if err := json.Unmarshal([]byte(arg), &v); err != nil {
+		return arg
+	}
+	return v
+}
+
+func load(path string) ([][2]interface{}, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var elements [][2]interface{}
+	if err := json.Unmarshal(data, &elements); err != nil {
+		return nil, err
+	}
+	return elements, nil
+}
+
+// validate returns an error describing the first element that would
+// prevent elements from being loaded as a snapshot of the given kind.
+func validate(elements [][2]interface{}, kind string) error {
+	// Map snapshots are ordered by keys, ZSet snapshots by scores.
+	ordered := 0
+	if kind == "zset" {
+		ordered = 1
+	}
+	seen := make(map[interface{}]int, len(elements))
+	for i, elem := range elements {
+		switch elem[ordered].(type) {
+		case float64, string:
+		default:
+			return fmt.Errorf("element %d: unsupported type %T", i, elem[ordered])
+		}
+		if i > 0 {
+			prev := elements[i-1][ordered]
+			if fmt.Sprintf("%T", prev) != fmt.Sprintf("%T", elem[ordered]) {
+				return fmt.Errorf("element %d: %T mixed with %T", i, elem[ordered], prev)
+			}
+			if lessThan(elem[ordered], prev) || (kind == "map" && !lessThan(prev, elem[ordered])) {
+				return fmt.Errorf("element %d: %v is out of order after %v", i, elem[ordered], prev)
+			}
+		}
+		if kind == "zset" {
+			switch elem[0].(type) {
+			case float64, string, bool, nil:
+			default:
+				return fmt.Errorf("element %d: unsupported member type %T", i, elem[0])
+			}
+			if j, ok := seen[elem[0]]; ok {
+				return fmt.Errorf("element %d: member %v already present at element %d", i, elem[0], j)
+			}
+			seen[elem[0]] = i
+		}
+	}
+	return nil
+}
+
+func parseRank(arg string) (uint32, error) {
+	rank, err := strconv.ParseUint(arg, 10, 32)
+	if err != nil || rank == 0 {
+		return 0, fmt.Errorf("invalid rank %q", arg)
+	}
+	return uint32(rank), nil
+}
+
+// parseRanks parses the from and to arguments of range.
+func parseRanks(args []string) (from, to uint32, err error) {
+	if from, err = parseRank(args[0]); err != nil {
+		return 0, 0, err
+	}
+	to, err = parseRank(args[1])
+	return from, to, err
+}
+
+// parseKey parses a key argument like parseArg, and checks that it can
+// be looked up among elements: it must be a scalar and, if ordered is
+// not negative, of the same type as elem[ordered], by which elements
+// are ordered.
+func parseKey(arg string, elements [][2]interface{}, ordered int) (interface{}, error) {
+	key := parseArg(arg)
+	switch key.(type) {
+	case float64, string, bool, nil:
+	default:
+		return nil, fmt.Errorf("unsupported key %s", arg)
+	}
+	if ordered >= 0 && len(elements) > 0 {
+		if want := elements[0][ordered]; fmt.Sprintf("%T", key) != fmt.Sprintf("%T", want) {
+			return nil, fmt.Errorf("key %s is a %T, the snapshot holds %T keys", arg, key, want)
+		}
+	}
+	return key, nil
+}
+
+func runZSet(w io.Writer, elements [][2]interface{}, cmd string, args []string) error {
+	zs := skiplist.NewCustomZSet(lessThan)
+	zs.Unmarshal(elements)
+
+	switch cmd {
+	case "query":
+		key, err := parseKey(args[0], elements, -1)
+		if err != nil {
+			return err
+		}
+		if zs.Rank(key) == 0 {
+			return fmt.Errorf("member %v not found", key)
+		}
+		fmt.Fprintln(w, zs.Score(key))
+	case "rank":
+		key, err := parseKey(args[0], elements, -1)
+		if err != nil {
+			return err
+		}
+		rank := zs.Rank(key)
+		if rank == 0 {
+			return fmt.Errorf("member %v not found", key)
+		}
+		fmt.Fprintln(w, rank)
+	case "range":
+		from, to, err := parseRanks(args)
+		if err != nil {
+			return err
+		}
+		for i, elem := range zs.RangeByRank(from, to) {
+			fmt.Fprintf(w, "%d\t%v\t%v\n", from+uint32(i), elem[0], elem[1])
+		}
+	case "stats":
+		fmt.Fprintf(w, "members: %d\n", zs.Card())
+		if zs.Card() > 0 {
+			first := zs.RangeByRank(1, 1)[0]
+			last := zs.RangeByRank(uint32(zs.Card()), uint32(zs.Card()))[0]
+			fmt.Fprintf(w, "lowest: %v (%v)\n", first[0], first[1])
+			fmt.Fprintf(w, "highest: %v (%v)\n", last[0], last[1])
+		}
+	}
+	return nil
+}
+
+func runMap(w io.Writer, elements [][2]interface{}, cmd string, args []string) error {
+	s := skiplist.NewCustomMap(lessThan)
+	s.FillBySortedSlice(elements)
+
+	switch cmd {
+	case "query":
+		key, err := parseKey(args[0], elements, 0)
+		if err != nil {
+			return err
+		}
+		value, ok := s.Get(key)
+		if !ok {
+			return fmt.Errorf("key %v not found", key)
+		}
+		fmt.Fprintln(w, value)
+	case "rank":
+		key, err := parseKey(args[0], elements, 0)
+		if err != nil {
+			return err
+		}
+		rank := s.Rank(key)
+		if rank == 0 {
+			return fmt.Errorf("key %v not found", key)
+		}
+		fmt.Fprintln(w, rank)
+	case "range":
+		from, to, err := parseRanks(args)
+		if err != nil {
+			return err
+		}
+		i := s.GetElemByRank(from)
+		for rank := from; i != nil && rank <= to; rank++ {
+			fmt.Fprintf(w, "%d\t%v\t%v\n", rank, i.Key(), i.Value())
+			if !i.Next() {
+				break
+			}
+		}
+	case "stats":
+		fmt.Fprintf(w, "keys: %d\n", s.Len())
+		if first := s.SeekToFirst(); first != nil {
+			fmt.Fprintf(w, "first: %v\n", first.Key())
+			fmt.Fprintf(w, "last: %v\n", s.SeekToLast().Key())
+		}
+	}
+	return nil
+}
+
+// run runs cmd with args on the snapshot of the given kind at path,
+// writing its output to w.
+func run(w io.Writer, path, kind, cmd string, args []string) error {
+	elements, err := load(path)
+	if err != nil {
+		return err
+	}
+	if err := validate(elements, kind); err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	if cmd == "validate" {
+		fmt.Fprintf(w, "%s: ok (%d elements)\n", path, len(elements))
+		return nil
+	}
+
+	if kind == "zset" {
+		return runZSet(w, elements, cmd, args)
+	}
+	return runMap(w, elements, cmd, args)
+}
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() < 2 || (*kind != "zset" && *kind != "map") {
+		usage()
+	}
+	path, cmd, args := flag.Arg(0), flag.Arg(1), flag.Args()[2:]
+
+	arity := map[string]int{"query": 1, "rank": 1, "range": 2, "stats": 0, "validate": 0}
+	n, ok := arity[cmd]
+	if !ok || len(args) != n {
+		usage()
+	}
+
+	if err := run(os.Stdout, path, *kind, cmd, args); err != nil {
+		fatalf("%v", err)
+	}
+}
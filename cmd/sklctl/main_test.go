@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func writeSnapshot(t *testing.T, dir, name, data string) string {
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	zset := writeSnapshot(t, dir, "zset.json", `[["bob",10],["carol",20],["alice",30]]`)
+	numbers := writeSnapshot(t, dir, "numbers.json", `[[1,"one"],[2,"two"],[3,"three"]]`)
+	names := writeSnapshot(t, dir, "names.json", `[["alice",1],["bob",2]]`)
+	unsorted := writeSnapshot(t, dir, "unsorted.json", `[[2,"two"],[1,"one"]]`)
+
+	for _, c := range []struct {
+		path, kind, cmd string
+		args            []string
+		expected        string
+		fails           bool
+	}{
+		{zset, "zset", "query", []string{"carol"}, "20\n", false},
+		{zset, "zset", "query", []string{"dave"}, "", true},
+		{zset, "zset", "query", []string{`{"a":1}`}, "", true},
+		{zset, "zset", "rank", []string{"alice"}, "3\n", false},
+		{zset, "zset", "rank", []string{"[1]"}, "", true},
+		{zset, "zset", "rank", []string{"42"}, "", true},
+		{zset, "zset", "range", []string{"2", "3"}, "2\tcarol\t20\n3\talice\t30\n", false},
+		{zset, "zset", "range", []string{"0", "3"}, "", true},
+		{zset, "zset", "stats", nil, "members: 3\nlowest: bob (10)\nhighest: alice (30)\n", false},
+		{zset, "zset", "validate", nil, zset + ": ok (3 elements)\n", false},
+		{numbers, "map", "query", []string{"2"}, "two\n", false},
+		{numbers, "map", "query", []string{"alice"}, "", true},
+		{numbers, "map", "query", []string{"[1]"}, "", true},
+		{numbers, "map", "query", []string{"4"}, "", true},
+		{names, "map", "query", []string{"42"}, "", true},
+		{numbers, "map", "rank", []string{"3"}, "3\n", false},
+		{numbers, "map", "rank", []string{"true"}, "", true},
+		{numbers, "map", "range", []string{"2", "5"}, "2\t2\ttwo\n3\t3\tthree\n", false},
+		{numbers, "map", "range", []string{"x", "5"}, "", true},
+		{numbers, "map", "stats", nil, "keys: 3\nfirst: 1\nlast: 3\n", false},
+		{unsorted, "map", "validate", nil, "", true},
+		{filepath.Join(dir, "missing.json"), "map", "stats", nil, "", true},
+	} {
+		var out bytes.Buffer
+		err := run(&out, c.path, c.kind, c.cmd, c.args)
+		if (err != nil) != c.fails || out.String() != c.expected {
+			t.Errorf("%s %s %v: got %q, %v.", c.kind, c.cmd, c.args, out.String(), err)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+// Package memtable implements the in-memory write buffer of a
+// log-structured merge (LSM) storage engine, in the style of LevelDB,
+// on top of a skip list.
+//
+// Every write is an entry identified by an internal key: the user key,
+// a sequence number, and a kind telling whether the entry sets a value
+// or deletes the key (a tombstone). Entries are never overwritten, so
+// that reads at an older sequence number, and the eventual flush to
+// disk, see the history of every key.
+package memtable
+
+import (
+	"bytes"
+
+	"github.com/longzhiri/goskiplist/skiplist"
+)
+
+// Kind is the kind of an entry.
+type Kind uint8
+
+const (
+	// KindDelete marks the deletion of a key.
+	KindDelete Kind = iota
+	// KindSet marks a value set for a key.
+	KindSet
+)
+
+// MaxSequence is the largest sequence number, to read the latest
+// entries.
+const MaxSequence = 1<<56 - 1
+
+// entryOverhead approximates the memory used by an entry besides its
+// key and value: the skip list node, the internal key and the
+// interfaces holding them.
+const entryOverhead = 96
+
+// An InternalKey identifies an entry. Internal keys are ordered by
+// ascending user key, then by descending sequence number, so that the
+// latest entry for a key comes first.
+type InternalKey struct {
+	UserKey []byte
+	Seq     uint64
+	Kind    Kind
+}
+
+func lessThan(l, r interface{}) bool {
+	lk, rk := l.(InternalKey), r.(InternalKey)
+	if c := bytes.Compare(lk.UserKey, rk.UserKey); c != 0 {
+		return c < 0
+	}
+	if lk.Seq != rk.Seq {
+		return lk.Seq > rk.Seq
+	}
+	return lk.Kind > rk.Kind
+}
+
+// A MemTable holds the recent entries of an LSM engine. Like
+// skiplist.SkipList, it is not safe for concurrent use.
+type MemTable struct {
+	list *skiplist.SkipList
+}
+
+// New returns a new, empty MemTable.
+func New() *MemTable {
+	return &MemTable{
+		list: skiplist.NewCustomMap(lessThan, skiplist.WithSizer(func(key, value interface{}) int {
+			return len(key.(InternalKey).UserKey) + len(value.([]byte)) + entryOverhead
+		})),
+	}
+}
+
+// Add adds an entry setting key to value (or deleting key, for
+// KindDelete, in which case value is ignored) at sequence number seq.
+// Sequence numbers must be unique; key and value must not be modified
+// afterwards.
+func (m *MemTable) Add(seq uint64, kind Kind, key, value []byte) {
+	if kind == KindDelete {
+		value = nil
+	}
+	if value == nil {
+		value = []byte{}
+	}
+	m.list.Set(InternalKey{UserKey: key, Seq: seq, Kind: kind}, value)
+}
+
+// Get returns the latest value of key as of sequence number seq. ok is
+// false if the memtable holds no entry for key at or before seq, in
+// which case older data must be consulted; deleted is true if the
+// latest such entry is a tombstone.
+func (m *MemTable) Get(key []byte, seq uint64) (value []byte, deleted, ok bool) {
+	actualKey, v, found := m.list.GetGreaterOrEqual(InternalKey{UserKey: key, Seq: seq, Kind: KindSet})
+	if !found {
+		return nil, false, false
+	}
+	ik := actualKey.(InternalKey)
+	if !bytes.Equal(ik.UserKey, key) {
+		return nil, false, false
+	}
+	if ik.Kind == KindDelete {
+		return nil, true, true
+	}
+	return v.([]byte), false, true
+}
+
+// Len returns the number of entries in m.
+func (m *MemTable) Len() int {
+	return m.list.Len()
+}
+
+// ApproximateMemoryUsage returns an estimate of the number of bytes
+// used by m, to decide when to flush it.
+func (m *MemTable) ApproximateMemoryUsage() int {
+	return int(m.list.TotalBytes())
+}
+
+// NewIterator returns an iterator over the entries of m, in internal
+// key order. It is not positioned until one of its Seek methods is
+// called.
+func (m *MemTable) NewIterator() *Iterator {
+	return &Iterator{list: m.list}
+}
+
+// An Iterator iterates over the entries of a MemTable. m must not be
+// modified while the iterator is in use.
+type Iterator struct {
+	list  *skiplist.SkipList
+	it    skiplist.Iterator
+	valid bool
+}
+
+// Valid returns true if the iterator is positioned at an entry.
+func (i *Iterator) Valid() bool {
+	return i.valid
+}
+
+func (i *Iterator) position(it skiplist.Iterator) {
+	i.it = it
+	i.valid = it != nil
+}
+
+// SeekToFirst positions the iterator at the first entry.
+func (i *Iterator) SeekToFirst() {
+	i.position(i.list.SeekToFirst())
+}
+
+// SeekToLast positions the iterator at the last entry.
+func (i *Iterator) SeekToLast() {
+	i.position(i.list.SeekToLast())
+}
+
+// Seek positions the iterator at the latest entry for the first user
+// key greater or equal to key.
+func (i *Iterator) Seek(key []byte) {
+	i.position(i.list.Seek(InternalKey{UserKey: key, Seq: MaxSequence, Kind: KindSet}))
+}
+
+// Next moves to the next entry. The iterator must be valid.
+func (i *Iterator) Next() {
+	i.valid = i.it.Next()
+}
+
+// Prev moves to the previous entry. The iterator must be valid.
+func (i *Iterator) Prev() {
+	i.valid = i.it.Previous()
+}
+
+// Key returns the internal key of the current entry.
+func (i *Iterator) Key() InternalKey {
+	return i.it.Key().(InternalKey)
+}
+
+// Value returns the value of the current entry (empty for
+// tombstones).
+func (i *Iterator) Value() []byte {
+	return i.it.Value().([]byte)
+}
@@ -0,0 +1,78 @@
+package memtable
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestGet(t *testing.T) {
+	m := New()
+	m.Add(1, KindSet, []byte("a"), []byte("a1"))
+	m.Add(2, KindSet, []byte("b"), []byte("b2"))
+	m.Add(3, KindSet, []byte("a"), []byte("a3"))
+	m.Add(4, KindDelete, []byte("b"), nil)
+
+	for _, c := range []struct {
+		key     string
+		seq     uint64
+		value   string
+		deleted bool
+		ok      bool
+	}{
+		{"a", MaxSequence, "a3", false, true},
+		{"a", 2, "a1", false, true},
+		{"a", 0, "", false, false},
+		{"b", 3, "b2", false, true},
+		{"b", 4, "", true, true},
+		{"c", MaxSequence, "", false, false},
+	} {
+		value, deleted, ok := m.Get([]byte(c.key), c.seq)
+		if string(value) != c.value || deleted != c.deleted || ok != c.ok {
+			t.Errorf("Get(%q, %d) returned %q, %v, %v.", c.key, c.seq, value, deleted, ok)
+		}
+	}
+	if m.Len() != 4 || m.ApproximateMemoryUsage() < 4*entryOverhead {
+		t.Errorf("Wrong size: %d entries, %d bytes.", m.Len(), m.ApproximateMemoryUsage())
+	}
+}
+
+func TestIterator(t *testing.T) {
+	m := New()
+	i := m.NewIterator()
+	if i.SeekToFirst(); i.Valid() {
+		t.Errorf("Iterator over an empty memtable should not be valid.")
+	}
+
+	m.Add(1, KindSet, []byte("b"), []byte("b1"))
+	m.Add(2, KindSet, []byte("a"), []byte("a2"))
+	m.Add(3, KindDelete, []byte("b"), nil)
+	m.Add(4, KindSet, []byte("c"), []byte("c4"))
+
+	var got []string
+	for i.SeekToFirst(); i.Valid(); i.Next() {
+		k := i.Key()
+		got = append(got, string(k.UserKey)+string(rune('0'+k.Seq))+string(i.Value()))
+	}
+	expected := []string{"a2a2", "b3", "b1b1", "c4c4"}
+	if len(got) != len(expected) {
+		t.Fatalf("Wrong entries: %v.", got)
+	}
+	for n := range expected {
+		if got[n] != expected[n] {
+			t.Errorf("Wrong entries: %v.", got)
+		}
+	}
+
+	if i.Seek([]byte("b")); !i.Valid() || i.Key().Seq != 3 || i.Key().Kind != KindDelete {
+		t.Errorf("Seek should find the latest entry for b.")
+	}
+	if i.Prev(); !i.Valid() || !bytes.Equal(i.Key().UserKey, []byte("a")) {
+		t.Errorf("Prev should move to a.")
+	}
+	if i.SeekToLast(); !i.Valid() || i.Key().Seq != 4 {
+		t.Errorf("SeekToLast should find c.")
+	}
+	if i.Seek([]byte("d")); i.Valid() {
+		t.Errorf("Seek past the last key should not be valid.")
+	}
+}
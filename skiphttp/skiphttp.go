@@ -0,0 +1,368 @@
+// Package skiphttp exposes the SkipLists and ZSets of a running
+// process over a small read-only HTTP+JSON API, so that other
+// processes and debugging tools can query them.
+//
+// Lists and sets are registered under a name, with the function that
+// parses the keys given in requests. The endpoints are:
+//
+//	GET /lists                                 names of the lists and zsets
+//	GET /lists/{name}/get?key=K                value of K
+//	GET /lists/{name}/rank?key=K               1-based rank of K
+//	GET /lists/{name}/range?from=K&to=K&limit=N elements in [from, to)
+//	GET /zsets/{name}/score?member=M           score of M
+//	GET /zsets/{name}/rank?member=M            1-based rank of M
+//	GET /zsets/{name}/top?n=N                  the N first members
+//	GET /zsets/{name}/range?from=R&to=R        members ranked from..to
+//
+// Missing keys and members are reported with 404 Not Found. Range
+// bounds are optional, and limit defaults to DefaultLimit.
+package skiphttp
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/longzhiri/goskiplist/skiplist"
+)
+
+// DefaultLimit is the maximum number of elements returned by range and
+// top queries which do not give one.
+const DefaultLimit = 100
+
+// MaxLimit is the maximum number of elements returned by any query.
+const MaxLimit = 10000
+
+// A ParseKey function converts a key given in a request into a key of
+// a list or a member of a zset.
+type ParseKey func(s string) (interface{}, error)
+
+// StringKey is the ParseKey function for string keys.
+func StringKey(s string) (interface{}, error) {
+	return s, nil
+}
+
+// IntKey is the ParseKey function for int keys.
+func IntKey(s string) (interface{}, error) {
+	return strconv.Atoi(s)
+}
+
+type registeredList struct {
+	list     *skiplist.ConcurrentSkipList
+	parseKey ParseKey
+}
+
+type registeredZSet struct {
+	zset     *skiplist.ZSet
+	mu       sync.Locker
+	parseKey ParseKey
+}
+
+// A Server is an http.Handler serving the registered lists and zsets.
+type Server struct {
+	mu    sync.RWMutex
+	lists map[string]registeredList
+	zsets map[string]registeredZSet
+}
+
+var listQueries = map[string]func(l registeredList, r *http.Request) (interface{}, error){
+	"get":   listGet,
+	"rank":  listRank,
+	"range": listRange,
+}
+
+var zsetQueries = map[string]func(z registeredZSet, r *http.Request) (interface{}, error){
+	"score": zsetScore,
+	"rank":  zsetRank,
+	"top":   zsetTop,
+	"range": zsetRange,
+}
+
+// NewServer returns a Server without lists or zsets.
+func NewServer() *Server {
+	return &Server{
+		lists: make(map[string]registeredList),
+		zsets: make(map[string]registeredZSet),
+	}
+}
+
+// RegisterList serves list under name, which must not contain slashes,
+// parsing keys with parseKey.
+// It replaces any list previously registered under name.
+func (srv *Server) RegisterList(name string, list *skiplist.ConcurrentSkipList, parseKey ParseKey) {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	srv.lists[name] = registeredList{list, parseKey}
+}
+
+// RegisterZSet serves zset under name, which must not contain slashes,
+// parsing members with parseKey.
+// As ZSets are not safe for concurrent use, queries hold mu, which must
+// be the lock guarding the writes to zset. It replaces any zset
+// previously registered under name.
+func (srv *Server) RegisterZSet(name string, zset *skiplist.ZSet, mu sync.Locker, parseKey ParseKey) {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	srv.zsets[name] = registeredZSet{zset, mu, parseKey}
+}
+
+// Unregister stops serving the list or zset registered under name.
+func (srv *Server) Unregister(name string) {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	delete(srv.lists, name)
+	delete(srv.zsets, name)
+}
+
+// ServeHTTP implements http.Handler.
+func (srv *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		reply(w, nil, errorf(http.StatusMethodNotAllowed, "method %s not allowed", r.Method))
+		return
+	}
+	if r.URL.Path == "/lists" {
+		srv.names(w)
+		return
+	}
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if len(parts) != 3 {
+		reply(w, nil, errorf(http.StatusNotFound, "no endpoint %s", r.URL.Path))
+		return
+	}
+	kind, name, query := parts[0], parts[1], parts[2]
+
+	srv.mu.RLock()
+	l, isList := srv.lists[name]
+	z, isZSet := srv.zsets[name]
+	srv.mu.RUnlock()
+	var result interface{}
+	var err error
+	switch {
+	case kind == "lists" && listQueries[query] != nil:
+		if !isList {
+			err = errorf(http.StatusNotFound, "no list %q", name)
+			break
+		}
+		result, err = listQueries[query](l, r)
+	case kind == "zsets" && zsetQueries[query] != nil:
+		if !isZSet {
+			err = errorf(http.StatusNotFound, "no zset %q", name)
+			break
+		}
+		z.mu.Lock()
+		result, err = zsetQueries[query](z, r)
+		z.mu.Unlock()
+	default:
+		err = errorf(http.StatusNotFound, "no endpoint %s", r.URL.Path)
+	}
+	reply(w, result, err)
+}
+
+// An httpError is an error reported with a status code.
+type httpError struct {
+	code int
+	msg  string
+}
+
+func (e *httpError) Error() string {
+	return e.msg
+}
+
+func errorf(code int, format string, args ...interface{}) error {
+	return &httpError{code, fmt.Sprintf(format, args...)}
+}
+
+// reply writes result, or err, as JSON.
+func reply(w http.ResponseWriter, result interface{}, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	if err == nil {
+		var body []byte
+		if body, err = json.Marshal(result); err == nil {
+			w.Write(body)
+			return
+		}
+	}
+	code := http.StatusInternalServerError
+	if e, ok := err.(*httpError); ok {
+		code = e.code
+	}
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
+
+func (srv *Server) names(w http.ResponseWriter) {
+	srv.mu.RLock()
+	names := map[string][]string{"lists": {}, "zsets": {}}
+	for name := range srv.lists {
+		names["lists"] = append(names["lists"], name)
+	}
+	for name := range srv.zsets {
+		names["zsets"] = append(names["zsets"], name)
+	}
+	srv.mu.RUnlock()
+	sort.Strings(names["lists"])
+	sort.Strings(names["zsets"])
+	reply(w, names, nil)
+}
+
+// key parses the query parameter param with parseKey. It returns nil
+// if the parameter is missing and optional.
+func key(r *http.Request, param string, parseKey ParseKey, optional bool) (interface{}, error) {
+	s, ok := r.URL.Query()[param]
+	if !ok {
+		if optional {
+			return nil, nil
+		}
+		return nil, errorf(http.StatusBadRequest, "missing %s", param)
+	}
+	k, err := parseKey(s[0])
+	if err != nil {
+		return nil, errorf(http.StatusBadRequest, "invalid %s %q: %v", param, s[0], err)
+	}
+	return k, nil
+}
+
+// number parses the query parameter param as a positive number, which
+// defaults to def.
+func number(r *http.Request, param string, def int) (int, error) {
+	s := r.URL.Query().Get(param)
+	if s == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return 0, errorf(http.StatusBadRequest, "invalid %s %q", param, s)
+	}
+	return n, nil
+}
+
+// limit is like number for the parameters limiting the number of
+// elements returned, which are capped at MaxLimit.
+func limit(r *http.Request, param string) (int, error) {
+	n, err := number(r, param, DefaultLimit)
+	if n > MaxLimit {
+		n = MaxLimit
+	}
+	return n, err
+}
+
+func listGet(l registeredList, r *http.Request) (interface{}, error) {
+	k, err := key(r, "key", l.parseKey, false)
+	if err != nil {
+		return nil, err
+	}
+	value, ok := l.list.Get(k)
+	if !ok {
+		return nil, errorf(http.StatusNotFound, "no key %v", k)
+	}
+	return value, nil
+}
+
+func listRank(l registeredList, r *http.Request) (interface{}, error) {
+	k, err := key(r, "key", l.parseKey, false)
+	if err != nil {
+		return nil, err
+	}
+	rank := l.list.Rank(k)
+	if rank == 0 {
+		return nil, errorf(http.StatusNotFound, "no key %v", k)
+	}
+	return rank, nil
+}
+
+func listRange(l registeredList, r *http.Request) (interface{}, error) {
+	from, err := key(r, "from", l.parseKey, true)
+	if err != nil {
+		return nil, err
+	}
+	to, err := key(r, "to", l.parseKey, true)
+	if err != nil {
+		return nil, err
+	}
+	max, err := limit(r, "limit")
+	if err != nil {
+		return nil, err
+	}
+
+	var opts []skiplist.IterOption
+	if from != nil {
+		opts = append(opts, skiplist.WithLowerBound(from))
+	}
+	if to != nil {
+		opts = append(opts, skiplist.WithUpperBoundExclusive(to))
+	}
+	elements := [][2]interface{}{}
+	l.list.Read(func(s *skiplist.SkipList) {
+		for i := s.Iterator(opts...); len(elements) < max && i.Next(); {
+			elements = append(elements, [2]interface{}{i.Key(), i.Value()})
+		}
+	})
+	return elements, nil
+}
+
+func zsetScore(z registeredZSet, r *http.Request) (interface{}, error) {
+	m, err := key(r, "member", z.parseKey, false)
+	if err != nil {
+		return nil, err
+	}
+	if !z.zset.Exists(m) {
+		return nil, errorf(http.StatusNotFound, "no member %v", m)
+	}
+	return z.zset.Score(m), nil
+}
+
+func zsetRank(z registeredZSet, r *http.Request) (interface{}, error) {
+	m, err := key(r, "member", z.parseKey, false)
+	if err != nil {
+		return nil, err
+	}
+	if !z.zset.Exists(m) {
+		return nil, errorf(http.StatusNotFound, "no member %v", m)
+	}
+	return z.zset.Rank(m), nil
+}
+
+func zsetTop(z registeredZSet, r *http.Request) (interface{}, error) {
+	n, err := limit(r, "n")
+	if err != nil {
+		return nil, err
+	}
+	return nonNil(z.zset.RangeByRank(1, uint32(n))), nil
+}
+
+func zsetRange(z registeredZSet, r *http.Request) (interface{}, error) {
+	from, err := number(r, "from", 1)
+	if err != nil {
+		return nil, err
+	}
+	to, err := number(r, "to", from+DefaultLimit-1)
+	if err != nil {
+		return nil, err
+	}
+	if from == 0 {
+		from = 1
+	}
+	if to-from >= MaxLimit {
+		to = from + MaxLimit - 1
+	}
+	if int64(to) > math.MaxUint32 {
+		to = math.MaxUint32
+	}
+	if from > to {
+		return [][2]interface{}{}, nil
+	}
+	return nonNil(z.zset.RangeByRank(uint32(from), uint32(to))), nil
+}
+
+// nonNil makes empty results encode as [] rather than null.
+func nonNil(elements [][2]interface{}) [][2]interface{} {
+	if elements == nil {
+		return [][2]interface{}{}
+	}
+	return elements
+}
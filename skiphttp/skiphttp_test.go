@@ -0,0 +1,127 @@
+package skiphttp
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/longzhiri/goskiplist/skiplist"
+)
+
+func TestServer(t *testing.T) {
+	list := skiplist.NewIntMap().Synced()
+	for i := 0; i < 20; i++ {
+		list.Set(i, i*i)
+	}
+	zset := skiplist.NewCustomZSet(func(l, r interface{}) bool {
+		return l.(int) > r.(int)
+	})
+	zset.Add("alice", 30)
+	zset.Add("bob", 10)
+	zset.Add("carol", 20)
+
+	srv := NewServer()
+	srv.RegisterList("squares", list, IntKey)
+	srv.RegisterZSet("scores", zset, new(sync.Mutex), StringKey)
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	for _, c := range []struct {
+		path     string
+		code     int
+		expected string
+	}{
+		{"/lists", 200, `{"lists":["squares"],"zsets":["scores"]}`},
+		{"/lists/squares/get?key=7", 200, `49`},
+		{"/lists/squares/get?key=70", 404, `{"error":"no key 70"}`},
+		{"/lists/squares/get?key=x", 400, ``},
+		{"/lists/squares/get", 400, `{"error":"missing key"}`},
+		{"/lists/squares/rank?key=3", 200, `4`},
+		{"/lists/squares/range?from=5&to=8", 200, `[[5,25],[6,36],[7,49]]`},
+		{"/lists/squares/range?from=17", 200, `[[17,289],[18,324],[19,361]]`},
+		{"/lists/squares/range?limit=2", 200, `[[0,0],[1,1]]`},
+		{"/lists/nope/get?key=1", 404, `{"error":"no list \"nope\""}`},
+		{"/zsets/scores/score?member=carol", 200, `20`},
+		{"/zsets/scores/score?member=dave", 404, `{"error":"no member dave"}`},
+		{"/zsets/scores/rank?member=bob", 200, `3`},
+		{"/zsets/scores/top?n=2", 200, `[["alice",30],["carol",20]]`},
+		{"/zsets/scores/top?n=0", 200, `[]`},
+		{"/zsets/scores/range?from=2&to=5", 200, `[["carol",20],["bob",10]]`},
+		{"/zsets/scores/range?from=x", 400, ``},
+	} {
+		resp, err := http.Get(ts.URL + c.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got, expected interface{}
+		err = json.NewDecoder(resp.Body).Decode(&got)
+		resp.Body.Close()
+		if err != nil {
+			t.Errorf("GET %s: invalid JSON: %v.", c.path, err)
+			continue
+		}
+		if resp.StatusCode != c.code {
+			t.Errorf("GET %s: expected status %d, got %d (%v).", c.path, c.code, resp.StatusCode, got)
+		}
+		if c.expected == "" {
+			continue
+		}
+		json.Unmarshal([]byte(c.expected), &expected)
+		if !reflect.DeepEqual(got, expected) {
+			t.Errorf("GET %s: expected %s, got %v.", c.path, c.expected, got)
+		}
+	}
+
+	srv.Unregister("squares")
+	if resp, err := http.Get(ts.URL + "/lists/squares/get?key=1"); err != nil || resp.StatusCode != 404 {
+		t.Errorf("Unregistered lists should not be served.")
+	}
+}
+
+func TestZSetRangeBeyondMaxLimit(t *testing.T) {
+	zset := skiplist.NewCustomZSet(func(l, r interface{}) bool {
+		return l.(int) < r.(int)
+	})
+	for i := 1; i <= MaxLimit*2+5; i++ {
+		zset.Add(strconv.Itoa(i), i)
+	}
+	srv := NewServer()
+	srv.RegisterZSet("z", zset, new(sync.Mutex), StringKey)
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	for _, c := range []struct {
+		path     string
+		expected int
+	}{
+		{"/zsets/z/range?from=20000&to=20002", 3},
+		{"/zsets/z/range?from=20004&to=30000", 2},
+		{"/zsets/z/range?from=1&to=20005", MaxLimit},
+		{"/zsets/z/top?n=20005", MaxLimit},
+		{"/zsets/z/range?from=99999999999&to=99999999999", 0},
+	} {
+		resp, err := http.Get(ts.URL + c.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var elements [][2]interface{}
+		err = json.NewDecoder(resp.Body).Decode(&elements)
+		resp.Body.Close()
+		if err != nil || resp.StatusCode != 200 || len(elements) != c.expected {
+			t.Errorf("%s: got %d elements, expected %d (%v).", c.path, len(elements), c.expected, err)
+		}
+	}
+	resp, err := http.Get(ts.URL + "/zsets/z/range?from=20000&to=20002")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var elements [][2]interface{}
+	if json.NewDecoder(resp.Body).Decode(&elements); len(elements) != 3 || elements[0][0] != "20000" {
+		t.Errorf("Wrong members for ranks 20000 to 20002: %v.", elements)
+	}
+}
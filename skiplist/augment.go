@@ -0,0 +1,181 @@
+package skiplist
+
+// A Monoid describes an aggregate of the elements of a SkipList, like
+// the sum or the minimum of their values (see WithAggregate).
+// Combine must be associative, and Identity must be its neutral
+// element: Combine(Identity, a) == Combine(a, Identity) == a.
+type Monoid struct {
+	// Lift returns the aggregate of a single element.
+	Lift func(key, value interface{}) interface{}
+	// Combine returns the aggregate of two adjacent runs of elements,
+	// given their aggregates in order.
+	Combine func(a, b interface{}) interface{}
+	// Identity is the aggregate of no elements.
+	Identity interface{}
+}
+
+// WithAggregate makes the list maintain, for every level pointer, the
+// aggregate described by m of the elements it skips over, just like it
+// maintains spans. This makes Aggregate and AggregateByRank O(log n)
+// at the cost of O(log n) calls to Combine per insertion and deletion.
+//
+// The aggregate of level i of node x covers the elements after x up to
+// and including x.levels[i].forward, or up to the end of the list if
+// that pointer is nil. It can be computed from the aggregates of level
+// i-1 of the nodes in between, which is what keeps the updates cheap.
+func WithAggregate(m *Monoid) Option {
+	return func(s *SkipList) {
+		s.augment = m
+	}
+}
+
+// setAugmentation starts maintaining the aggregates described by m
+// (nil to stop), computing them for the current elements.
+func (s *SkipList) setAugmentation(m *Monoid) {
+	s.unshare()
+	s.augment, s.aggs = m, nil
+	if m != nil {
+		s.rebuildAggregates()
+	}
+}
+
+// recomputeAggregate recomputes the aggregate of level i of x,
+// assuming the ones of level i-1 are correct.
+func (s *SkipList) recomputeAggregate(x *node, i int) {
+	m := s.augment
+	aggs := s.aggs[x]
+	if len(aggs) < len(x.levels) {
+		aggs = append(aggs, make([]interface{}, len(x.levels)-len(aggs))...)
+		s.aggs[x] = aggs
+	}
+
+	target := x.levels[i].forward
+	if i == 0 {
+		if target == nil {
+			aggs[0] = m.Identity
+		} else {
+			aggs[0] = m.Lift(target.key, s.decode(target.value))
+		}
+		return
+	}
+
+	acc := m.Identity
+	for y := x; y != target; y = y.levels[i-1].forward {
+		acc = m.Combine(acc, s.aggs[y][i-1])
+	}
+	aggs[i] = acc
+}
+
+// fixAggregates recomputes the aggregates affected by inserting
+// newNode, deleting a node, or changing the value of a node, given the
+// nodes preceding the change at each level in update. newNode is nil
+// unless a node was inserted.
+func (s *SkipList) fixAggregates(update []*node, newNode *node) {
+	for i := 0; i <= s.level(); i++ {
+		if newNode != nil && i < len(newNode.levels) {
+			s.recomputeAggregate(newNode, i)
+		}
+		s.recomputeAggregate(update[i], i)
+	}
+}
+
+// rebuildAggregates recomputes all the aggregates of s, level by level.
+func (s *SkipList) rebuildAggregates() {
+	s.aggs = make(map[*node][]interface{}, s.length+1)
+	for i := 0; i <= s.level(); i++ {
+		for x := s.header; x != nil; x = x.levels[i].forward {
+			s.recomputeAggregate(x, i)
+		}
+	}
+}
+
+func (s *SkipList) mustAugment() {
+	if s.augment == nil {
+		panic("goskiplist: no aggregate configured, see WithAggregate")
+	}
+}
+
+// Aggregate returns the aggregate of all the elements of s. It panics
+// if s was not created with WithAggregate.
+func (s *SkipList) Aggregate() interface{} {
+	s.mustAugment()
+	top := s.level()
+	acc := s.augment.Identity
+	for x := s.header; x != nil; x = x.levels[top].forward {
+		acc = s.augment.Combine(acc, s.aggs[x][top])
+	}
+	return acc
+}
+
+// AggregateByRank returns the aggregate of the elements whose ranks
+// are in [from, to]. It panics if s was not created with
+// WithAggregate.
+func (s *SkipList) AggregateByRank(from, to uint32) interface{} {
+	s.mustAugment()
+	if from == 0 {
+		from = 1
+	}
+	if to > uint32(s.length) {
+		to = uint32(s.length)
+	}
+	if from > to {
+		return s.augment.Identity
+	}
+	return s.aggregateAfter(s.nodeByRank(from-1), from-1, to)
+}
+
+// RangeAggregate returns the aggregate described by agg of the
+// elements whose keys are greater or equal than from, but less than
+// to. If agg is nil or the Monoid s was created with, the maintained
+// aggregates are used and it takes O(log n) time. Otherwise the range
+// is scanned, which takes time proportional to its length but works
+// for any list.
+func (s *SkipList) RangeAggregate(from, to interface{}, agg *Monoid) interface{} {
+	if agg == nil || agg == s.augment {
+		s.mustAugment()
+		start, end := s.countLess(from), s.countLess(to)
+		if start >= end {
+			return s.augment.Identity
+		}
+		return s.aggregateAfter(s.nodeByRank(start), start, end)
+	}
+
+	acc := agg.Identity
+	for x := s.getLowerBound(s.header, from); x != nil && s.lessThan(x.key, to); x = x.next() {
+		acc = agg.Combine(acc, agg.Lift(x.key, s.decode(x.value)))
+	}
+	return acc
+}
+
+// aggregateAfter returns the aggregate of the elements following x
+// (whose rank is rank) up to the element ranked to. At every step it
+// takes the highest pointer that does not overshoot.
+func (s *SkipList) aggregateAfter(x *node, rank, to uint32) interface{} {
+	acc := s.augment.Identity
+	for rank < to {
+		i := len(x.levels) - 1
+		for i > 0 && (x.levels[i].forward == nil || rank+x.levels[i].span > to) {
+			i--
+		}
+		acc = s.augment.Combine(acc, s.aggs[x][i])
+		rank += x.levels[i].span
+		x = x.levels[i].forward
+	}
+	return acc
+}
+
+// nodeAtWeight returns the first node at which the running sum of
+// float64 aggregates exceeds w, or the last node if there is none.
+func (s *SkipList) nodeAtWeight(w float64) *node {
+	x := s.header
+	for i := s.level(); i >= 0; i-- {
+		for x.levels[i].forward != nil && s.aggs[x][i].(float64) <= w {
+			w -= s.aggs[x][i].(float64)
+			x = x.levels[i].forward
+		}
+	}
+	if next := x.next(); next != nil {
+		return next
+	}
+	return s.footer
+}
@@ -0,0 +1,165 @@
+package skiplist
+
+import (
+	"math/rand"
+	"testing"
+)
+
+var sumMonoid = Monoid{
+	Lift: func(key, value interface{}) interface{} {
+		return value.(int)
+	},
+	Combine: func(a, b interface{}) interface{} {
+		return a.(int) + b.(int)
+	},
+	Identity: 0,
+}
+
+var minMonoid = Monoid{
+	Lift: func(key, value interface{}) interface{} {
+		return value.(int)
+	},
+	Combine: func(a, b interface{}) interface{} {
+		if b.(int) < a.(int) {
+			return b
+		}
+		return a
+	},
+	Identity: int(^uint(0) >> 1),
+}
+
+// checkAggregates compares the aggregates of all rank ranges of s with
+// the ones computed by brute force.
+func checkAggregates(t *testing.T, s *SkipList, m *Monoid) {
+	var values []int
+	for i := s.Iterator(); i.Next(); {
+		values = append(values, i.Value().(int))
+	}
+	for from := 1; from <= len(values); from++ {
+		want := m.Identity
+		for to := from; to <= len(values); to++ {
+			want = m.Combine(want, values[to-1])
+			if got := s.AggregateByRank(uint32(from), uint32(to)); got != want {
+				t.Fatalf("AggregateByRank(%d, %d) is %v, expected %v.", from, to, got, want)
+			}
+		}
+	}
+	total := m.Identity
+	for _, v := range values {
+		total = m.Combine(total, v)
+	}
+	if got := s.Aggregate(); got != total {
+		t.Fatalf("Aggregate() is %v, expected %v.", got, total)
+	}
+}
+
+func TestAggregates(t *testing.T) {
+	for _, m := range []*Monoid{&sumMonoid, &minMonoid} {
+		s := NewIntMap(WithAggregate(m))
+		checkAggregates(t, s, m)
+		for i := 0; i < 300; i++ {
+			k := rand.Intn(100)
+			switch rand.Intn(3) {
+			case 0:
+				s.Delete(k)
+			default:
+				s.Set(k, rand.Intn(1000)-500)
+			}
+		}
+		checkAggregates(t, s, m)
+
+		s.Clear()
+		checkAggregates(t, s, m)
+		elements := make([][2]interface{}, 100)
+		for i := range elements {
+			elements[i] = [2]interface{}{i, rand.Intn(1000)}
+		}
+		s.FillBySortedSlice(elements)
+		checkAggregates(t, s, m)
+	}
+
+	s := NewIntMap(WithAggregate(&sumMonoid))
+	for i := 1; i <= 10; i++ {
+		s.Set(i, i)
+	}
+	if got := s.AggregateByRank(0, 100); got != 55 {
+		t.Errorf("AggregateByRank(0, 100) should clamp to the whole list, got %v.", got)
+	}
+	if got := s.AggregateByRank(5, 4); got != 0 {
+		t.Errorf("AggregateByRank(5, 4) should be the identity, got %v.", got)
+	}
+}
+
+func TestAggregateWithoutMonoid(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Aggregate should panic without an aggregate.")
+		}
+	}()
+	NewIntMap().Aggregate()
+}
+
+func TestRangeAggregate(t *testing.T) {
+	s := NewIntMap(WithAggregate(&sumMonoid))
+	plain := NewIntMap()
+	for i := 0; i < 200; i++ {
+		k := rand.Intn(1000)
+		s.Set(k, k)
+		plain.Set(k, k)
+	}
+
+	for j := 0; j < 200; j++ {
+		from, to := rand.Intn(1100)-50, rand.Intn(1100)-50
+		want := 0
+		for i := s.Iterator(); i.Next(); {
+			if k := i.Key().(int); k >= from && k < to {
+				want += k
+			}
+		}
+		if got := s.RangeAggregate(from, to, nil); got != want {
+			t.Fatalf("RangeAggregate(%d, %d) is %v, expected %v.", from, to, got, want)
+		}
+		if got := plain.RangeAggregate(from, to, &sumMonoid); got != want {
+			t.Fatalf("Scanning RangeAggregate(%d, %d) is %v, expected %v.", from, to, got, want)
+		}
+		if got, want := s.RangeAggregate(from, to, &minMonoid), plain.RangeAggregate(from, to, &minMonoid); got != want {
+			t.Fatalf("RangeAggregate(%d, %d) with another monoid is %v, expected %v.", from, to, got, want)
+		}
+	}
+}
+
+func TestAggregatesKeptAside(t *testing.T) {
+	if NewIntMap().aggs != nil {
+		t.Errorf("Lists without aggregates should not allocate any.")
+	}
+
+	s := NewIntMap(WithAggregate(&sumMonoid))
+	for i := 0; i < 100; i++ {
+		s.Set(i, i)
+	}
+	sn := s.Snapshot()
+	for i := 0; i < 100; i += 3 {
+		s.Delete(i)
+	}
+	checkAggregates(t, s, &sumMonoid)
+	if got := sn.list.Aggregate(); got != 4950 {
+		t.Errorf("The snapshot should keep its aggregates, got %v.", got)
+	}
+	clone := NewBTree(WithAggregate(&sumMonoid))
+	clone.s = s
+	c := clone.Clone()
+	s.Set(1000, 1000)
+	checkAggregates(t, s, &sumMonoid)
+	checkAggregates(t, c.s, &sumMonoid)
+
+	other := s.Split(50)
+	checkAggregates(t, s, &sumMonoid)
+	checkAggregates(t, other, &sumMonoid)
+	if len(s.aggs) != s.Len()+1 || len(other.aggs) != other.Len()+1 {
+		t.Errorf("Split should move the aggregates of the nodes: %d and %d for %d and %d nodes.", len(s.aggs), len(other.aggs), s.Len(), other.Len())
+	}
+	s.DeleteRange(10, 20)
+	if len(s.aggs) != s.Len()+1 {
+		t.Errorf("Deleted nodes should drop their aggregates: %d for %d nodes.", len(s.aggs), s.Len())
+	}
+}
@@ -0,0 +1,120 @@
+package skiplist
+
+import "math"
+
+// FilterStats describes how useful the Bloom filter of a SkipList is
+// (see WithBloomFilter).
+type FilterStats struct {
+	// Rejected is the number of lookups answered by the filter
+	// alone.
+	Rejected uint64
+	// Passed is the number of lookups the filter let through to the
+	// list, and FalsePositives the number of those which did not
+	// find their key.
+	Passed         uint64
+	FalsePositives uint64
+	// Stale is the number of keys deleted since the filter was last
+	// built. Bloom filters cannot forget keys, so deleted keys keep
+	// passing the filter until RebuildFilter is called.
+	Stale int
+}
+
+type bloomFilter struct {
+	hash         func(key interface{}) uint64
+	bits         []uint64
+	hashes       uint32
+	expectedSize int
+	rate         float64
+	stale        int
+	stats        FilterStats
+}
+
+// WithBloomFilter maintains a Bloom filter of the keys alongside the
+// list, so that Get can report most missing keys without searching
+// the list. hash must return well distributed values for keys (see
+// hash/maphash), and keys deemed equal by the comparison function must
+// hash to the same value. The filter is sized for expectedSize keys
+// with a false positive rate of falsePositiveRate. It panics unless
+// expectedSize is positive and falsePositiveRate between 0 and 1,
+// both excluded.
+func WithBloomFilter(hash func(key interface{}) uint64, expectedSize int, falsePositiveRate float64) Option {
+	if expectedSize <= 0 {
+		panic("goskiplist: Bloom filter expected size must be positive")
+	}
+	if !(falsePositiveRate > 0 && falsePositiveRate < 1) {
+		panic("goskiplist: Bloom filter false positive rate must be between 0 and 1")
+	}
+	return func(s *SkipList) {
+		s.filter = &bloomFilter{hash: hash, rate: falsePositiveRate}
+		s.filter.reset(expectedSize)
+	}
+}
+
+// reset empties f, sizing it for n keys.
+func (f *bloomFilter) reset(n int) {
+	n = maxInt(n, 1)
+	m := math.Ceil(-float64(n) * math.Log(f.rate) / (math.Ln2 * math.Ln2))
+	f.bits = make([]uint64, (int(m)+63)/64)
+	f.hashes = uint32(math.Max(1, math.Round(m/float64(n)*math.Ln2)))
+	f.expectedSize = n
+	f.stale = 0
+}
+
+// probe calls fn with the bit index of each of the hash functions of
+// key, derived from a single hash by double hashing.
+func (f *bloomFilter) probe(key interface{}, fn func(bit uint64) bool) bool {
+	h := f.hash(key)
+	h1, h2 := h&0xffffffff, h>>32|1
+	m := uint64(len(f.bits)) * 64
+	for i := uint32(0); i < f.hashes; i++ {
+		if !fn((h1 + uint64(i)*h2) % m) {
+			return false
+		}
+	}
+	return true
+}
+
+func (f *bloomFilter) add(key interface{}) {
+	f.probe(key, func(bit uint64) bool {
+		f.bits[bit/64] |= 1 << (bit % 64)
+		return true
+	})
+}
+
+// check returns false if key is certainly not in the filter, and
+// records the outcome in the stats.
+func (f *bloomFilter) check(key interface{}) bool {
+	ok := f.probe(key, func(bit uint64) bool {
+		return f.bits[bit/64]&(1<<(bit%64)) != 0
+	})
+	if ok {
+		f.stats.Passed++
+	} else {
+		f.stats.Rejected++
+	}
+	return ok
+}
+
+// FilterStats returns the statistics of the Bloom filter of s, and
+// false if s has no filter.
+func (s *SkipList) FilterStats() (stats FilterStats, ok bool) {
+	if s.filter == nil {
+		return FilterStats{}, false
+	}
+	stats = s.filter.stats
+	stats.Stale = s.filter.stale
+	return stats, true
+}
+
+// RebuildFilter rebuilds the Bloom filter of s from its current keys,
+// forgetting the deleted ones and growing the filter if s holds more
+// keys than it was sized for. It does nothing if s has no filter.
+func (s *SkipList) RebuildFilter() {
+	if s.filter == nil {
+		return
+	}
+	s.filter.reset(maxInt(s.filter.expectedSize, s.length))
+	for n := s.header.next(); n != nil; n = n.next() {
+		s.filter.add(n.key)
+	}
+}
@@ -0,0 +1,93 @@
+package skiplist
+
+import (
+	"hash/fnv"
+	"strconv"
+	"testing"
+)
+
+func hashInt(key interface{}) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(strconv.Itoa(key.(int))))
+	return h.Sum64()
+}
+
+func TestBloomFilter(t *testing.T) {
+	s := NewIntMap(WithBloomFilter(hashInt, 1000, 0.01))
+	for i := 0; i < 1000; i += 2 {
+		s.Set(i, i)
+	}
+
+	for i := 0; i < 1000; i++ {
+		if _, ok := s.Get(i); ok != (i%2 == 0) {
+			t.Fatalf("Unexpected presence of key %d: %v.", i, ok)
+		}
+	}
+
+	stats, ok := s.FilterStats()
+	if !ok {
+		t.Fatalf("Expected the list to have a filter.")
+	}
+	if stats.Passed+stats.Rejected != 1000 || stats.Passed-stats.FalsePositives != 500 {
+		t.Errorf("Inconsistent stats: %+v.", stats)
+	}
+	if stats.FalsePositives > 50 {
+		t.Errorf("Too many false positives: %+v.", stats)
+	}
+
+	for i := 0; i < 1000; i += 2 {
+		s.Delete(i)
+	}
+	if stats, _ := s.FilterStats(); stats.Stale != 500 {
+		t.Errorf("Expected 500 stale keys, got %d.", stats.Stale)
+	}
+	s.RebuildFilter()
+	stats, _ = s.FilterStats()
+	if stats.Stale != 0 {
+		t.Errorf("Expected no stale keys after rebuilding, got %d.", stats.Stale)
+	}
+	rejected := stats.Rejected
+	for i := 0; i < 1000; i += 2 {
+		s.Get(i)
+	}
+	if stats, _ := s.FilterStats(); stats.Rejected-rejected < 450 {
+		t.Errorf("Expected the rebuilt filter to reject deleted keys, rejected %d.", stats.Rejected-rejected)
+	}
+
+	if _, ok := NewIntMap().FilterStats(); ok {
+		t.Errorf("Expected no filter by default.")
+	}
+}
+
+func TestBloomFilterParameters(t *testing.T) {
+	for _, c := range []struct {
+		expectedSize int
+		rate         float64
+	}{
+		{1000, 0},
+		{1000, 1},
+		{1000, -0.5},
+		{1000, 2},
+		{0, 0.01},
+		{-1, 0.01},
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("WithBloomFilter(%d, %v) should panic.", c.expectedSize, c.rate)
+				}
+			}()
+			WithBloomFilter(hashInt, c.expectedSize, c.rate)
+		}()
+	}
+
+	for _, rate := range []float64{1e-9, 0.999} {
+		s := NewIntMap(WithBloomFilter(hashInt, 1, rate))
+		for i := 0; i < 100; i++ {
+			s.Set(i, i)
+		}
+		if _, ok := s.Get(50); !ok {
+			t.Errorf("Get(50) failed with a false positive rate of %v.", rate)
+		}
+	}
+}
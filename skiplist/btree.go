@@ -0,0 +1,211 @@
+package skiplist
+
+// An Item is an element of a BTree. It mirrors the Item interface of
+// github.com/google/btree, so that types written for it only need
+// their Less method to take a skiplist.Item.
+type Item interface {
+	// Less tests whether the item is less than than. Items for which
+	// neither a.Less(b) nor b.Less(a) holds are considered equal, and
+	// only one of them can be in a BTree at once.
+	Less(than Item) bool
+}
+
+// An ItemIterator is called by the Ascend and Descend methods of a
+// BTree with every item in the range, and stops them by returning
+// false.
+type ItemIterator func(item Item) bool
+
+// BTree adapts a SkipList to the API of BTree from
+// github.com/google/btree, so that code written against it can switch
+// to a skip list by changing its types and constructor only. Unlike
+// that BTree, it has no degree and no free list. To copy the items of
+// an existing btree.BTree instead, see FromBTree and ToBTree.
+type BTree struct {
+	s *SkipList
+}
+
+func itemLess(l, r interface{}) bool {
+	return l.(Item).Less(r.(Item))
+}
+
+// NewBTree returns a new, empty BTree configured by opts.
+func NewBTree(opts ...Option) *BTree {
+	return &BTree{s: NewCustomMap(itemLess, opts...)}
+}
+
+// Len returns the number of items in t.
+func (t *BTree) Len() int {
+	return t.s.Len()
+}
+
+// ReplaceOrInsert adds item to t. If an equal item was already in t,
+// it is replaced and returned; otherwise it returns nil.
+func (t *BTree) ReplaceOrInsert(item Item) Item {
+	if item == nil {
+		panic("goskiplist: nil item being added to BTree")
+	}
+	// The items are stored as values, so that replacing one replaces
+	// the item seen by Get and iterations.
+	if old, existed, _ := t.s.SetReturning(item, item); existed {
+		return old.(Item)
+	}
+	return nil
+}
+
+// Get returns the item of t equal to key, or nil if there is none.
+func (t *BTree) Get(key Item) Item {
+	if value, ok := t.s.Get(key); ok {
+		return value.(Item)
+	}
+	return nil
+}
+
+// Has returns true if t holds an item equal to key.
+func (t *BTree) Has(key Item) bool {
+	_, ok := t.s.Get(key)
+	return ok
+}
+
+// Delete removes the item of t equal to item and returns it, or
+// returns nil if there is none.
+func (t *BTree) Delete(item Item) Item {
+	if value, ok := t.s.Delete(item); ok {
+		return value.(Item)
+	}
+	return nil
+}
+
+// Min returns the smallest item of t, or nil if t is empty.
+func (t *BTree) Min() Item {
+	if _, value, ok := t.s.Min(); ok {
+		return value.(Item)
+	}
+	return nil
+}
+
+// Max returns the largest item of t, or nil if t is empty.
+func (t *BTree) Max() Item {
+	if _, value, ok := t.s.Max(); ok {
+		return value.(Item)
+	}
+	return nil
+}
+
+// DeleteMin removes the smallest item of t and returns it, or returns
+// nil if t is empty.
+func (t *BTree) DeleteMin() Item {
+	if key, _, ok := t.s.Min(); ok {
+		return t.Delete(key.(Item))
+	}
+	return nil
+}
+
+// DeleteMax removes the largest item of t and returns it, or returns
+// nil if t is empty.
+func (t *BTree) DeleteMax() Item {
+	if key, _, ok := t.s.Max(); ok {
+		return t.Delete(key.(Item))
+	}
+	return nil
+}
+
+// Clear removes all the items of t. addNodesToFreelist is ignored.
+func (t *BTree) Clear(addNodesToFreelist bool) {
+	t.s.Clear()
+}
+
+// Clone returns a copy of t, configured like t, in O(1). Like
+// snapshots, the copies share their nodes until either of them is
+// modified, which copies them first. t and the copy can then be used
+// independently, but not from different goroutines at once. The nodes
+// are copied right away if t has a Bloom filter or a shadow check (see
+// SkipList.Clone).
+func (t *BTree) Clone() *BTree {
+	if t.s.filter != nil || t.s.shadow != nil {
+		return &BTree{s: t.s.Clone()}
+	}
+	c := NewWithOptions(t.s.options()...)
+	c.header, c.footer, c.length = t.s.header, t.s.footer, t.s.length
+	c.aggs = t.s.aggs
+	if c.quota != nil {
+		c.quota.total = t.s.quota.total
+	}
+	c.shared, t.s.shared = true, true
+	return &BTree{s: c}
+}
+
+// ascend calls iterator with the items from n onward, while they are
+// less than lessThan (if not nil) and iterator returns true.
+func (t *BTree) ascend(n *node, lessThan Item, iterator ItemIterator) {
+	for ; n != nil; n = n.next() {
+		if lessThan != nil && !n.key.(Item).Less(lessThan) {
+			return
+		}
+		if !iterator(t.s.decode(n.value).(Item)) {
+			return
+		}
+	}
+}
+
+// descend calls iterator with the items from n backward, while they are
+// greater than greaterThan (if not nil) and iterator returns true.
+func (t *BTree) descend(n *node, greaterThan Item, iterator ItemIterator) {
+	for ; n != nil && n != t.s.header; n = n.backward {
+		if greaterThan != nil && !greaterThan.Less(n.key.(Item)) {
+			return
+		}
+		if !iterator(t.s.decode(n.value).(Item)) {
+			return
+		}
+	}
+}
+
+// Ascend calls iterator with every item of t, in ascending order, until
+// it returns false.
+func (t *BTree) Ascend(iterator ItemIterator) {
+	t.ascend(t.s.header.next(), nil, iterator)
+}
+
+// AscendGreaterOrEqual calls iterator with the items greater or equal
+// to pivot, in ascending order, until it returns false.
+func (t *BTree) AscendGreaterOrEqual(pivot Item, iterator ItemIterator) {
+	t.ascend(t.s.getLowerBound(t.s.header, pivot), nil, iterator)
+}
+
+// AscendLessThan calls iterator with the items less than pivot, in
+// ascending order, until it returns false.
+func (t *BTree) AscendLessThan(pivot Item, iterator ItemIterator) {
+	t.ascend(t.s.header.next(), pivot, iterator)
+}
+
+// AscendRange calls iterator with the items greater or equal to
+// greaterOrEqual, but less than lessThan, in ascending order, until it
+// returns false.
+func (t *BTree) AscendRange(greaterOrEqual, lessThan Item, iterator ItemIterator) {
+	t.ascend(t.s.getLowerBound(t.s.header, greaterOrEqual), lessThan, iterator)
+}
+
+// Descend calls iterator with every item of t, in descending order,
+// until it returns false.
+func (t *BTree) Descend(iterator ItemIterator) {
+	t.descend(t.s.footer, nil, iterator)
+}
+
+// DescendLessOrEqual calls iterator with the items less or equal to
+// pivot, in descending order, until it returns false.
+func (t *BTree) DescendLessOrEqual(pivot Item, iterator ItemIterator) {
+	t.descend(t.s.lastLessOrEqual(pivot), nil, iterator)
+}
+
+// DescendGreaterThan calls iterator with the items greater than pivot,
+// in descending order, until it returns false.
+func (t *BTree) DescendGreaterThan(pivot Item, iterator ItemIterator) {
+	t.descend(t.s.footer, pivot, iterator)
+}
+
+// DescendRange calls iterator with the items less or equal to
+// lessOrEqual, but greater than greaterThan, in descending order, until
+// it returns false.
+func (t *BTree) DescendRange(lessOrEqual, greaterThan Item, iterator ItemIterator) {
+	t.descend(t.s.lastLessOrEqual(lessOrEqual), greaterThan, iterator)
+}
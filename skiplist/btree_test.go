@@ -0,0 +1,142 @@
+package skiplist
+
+import "testing"
+
+type btreeInt int
+
+func (i btreeInt) Less(than Item) bool {
+	return i < than.(btreeInt)
+}
+
+type btreePair struct {
+	key   int
+	value string
+}
+
+func (e btreePair) Less(than Item) bool {
+	return e.key < than.(btreePair).key
+}
+
+func collectItems(walk func(ItemIterator), limit int) []int {
+	var items []int
+	walk(func(item Item) bool {
+		items = append(items, int(item.(btreeInt)))
+		return len(items) < limit
+	})
+	return items
+}
+
+func TestBTree(t *testing.T) {
+	tree := NewBTree()
+	for i := 0; i < 20; i += 2 {
+		if old := tree.ReplaceOrInsert(btreeInt(i)); old != nil {
+			t.Errorf("ReplaceOrInsert(%d) returned %v for a new item.", i, old)
+		}
+	}
+	if tree.Len() != 10 || !tree.Has(btreeInt(4)) || tree.Has(btreeInt(5)) || tree.Get(btreeInt(5)) != nil {
+		t.Errorf("Wrong contents: %d items.", tree.Len())
+	}
+	if tree.Min() != btreeInt(0) || tree.Max() != btreeInt(18) {
+		t.Errorf("Wrong Min and Max: %v, %v.", tree.Min(), tree.Max())
+	}
+
+	for _, c := range []struct {
+		name     string
+		walk     func(ItemIterator)
+		limit    int
+		expected []int
+	}{
+		{"Ascend", tree.Ascend, 3, []int{0, 2, 4}},
+		{"AscendGreaterOrEqual", func(it ItemIterator) { tree.AscendGreaterOrEqual(btreeInt(13), it) }, 100, []int{14, 16, 18}},
+		{"AscendLessThan", func(it ItemIterator) { tree.AscendLessThan(btreeInt(6), it) }, 100, []int{0, 2, 4}},
+		{"AscendRange", func(it ItemIterator) { tree.AscendRange(btreeInt(4), btreeInt(10), it) }, 100, []int{4, 6, 8}},
+		{"Descend", tree.Descend, 3, []int{18, 16, 14}},
+		{"DescendLessOrEqual", func(it ItemIterator) { tree.DescendLessOrEqual(btreeInt(4), it) }, 100, []int{4, 2, 0}},
+		{"DescendGreaterThan", func(it ItemIterator) { tree.DescendGreaterThan(btreeInt(13), it) }, 100, []int{18, 16, 14}},
+		{"DescendRange", func(it ItemIterator) { tree.DescendRange(btreeInt(9), btreeInt(4), it) }, 100, []int{8, 6}},
+		{"DescendLessOrEqual below the minimum", func(it ItemIterator) { tree.DescendLessOrEqual(btreeInt(-1), it) }, 100, nil},
+	} {
+		if items := collectItems(c.walk, c.limit); !equalInts(items, c.expected) {
+			t.Errorf("%s yielded %v, expected %v.", c.name, items, c.expected)
+		}
+	}
+
+	clone := tree.Clone()
+	if tree.DeleteMin() != btreeInt(0) || tree.DeleteMax() != btreeInt(18) || tree.Delete(btreeInt(8)) != btreeInt(8) || tree.Delete(btreeInt(9)) != nil {
+		t.Errorf("Wrong deleted items.")
+	}
+	clone.ReplaceOrInsert(btreeInt(1))
+	if items := collectItems(tree.Ascend, 100); !equalInts(items, []int{2, 4, 6, 10, 12, 14, 16}) {
+		t.Errorf("Wrong items after deletions: %v.", items)
+	}
+	if items := collectItems(clone.Ascend, 100); !equalInts(items, []int{0, 1, 2, 4, 6, 8, 10, 12, 14, 16, 18}) {
+		t.Errorf("Clone should not see the deletions: %v.", items)
+	}
+
+	tree.Clear(true)
+	if tree.Len() != 0 || tree.Min() != nil || tree.DeleteMax() != nil {
+		t.Errorf("Clear left %d items.", tree.Len())
+	}
+}
+
+func TestBTreeReplace(t *testing.T) {
+	tree := NewBTree()
+	tree.ReplaceOrInsert(btreePair{1, "a"})
+	if old := tree.ReplaceOrInsert(btreePair{1, "b"}); old != (btreePair{1, "a"}) {
+		t.Errorf("ReplaceOrInsert should return the replaced item, got %v.", old)
+	}
+	if item := tree.Get(btreePair{key: 1}); item != (btreePair{1, "b"}) {
+		t.Errorf("Get should return the new item, got %v.", item)
+	}
+	tree.Ascend(func(item Item) bool {
+		if item != (btreePair{1, "b"}) {
+			t.Errorf("Ascend should yield the new item, got %v.", item)
+		}
+		return true
+	})
+
+	profiled := NewBTree(WithAccessProfiling())
+	profiled.ReplaceOrInsert(btreeInt(1))
+	profiled.ReplaceOrInsert(btreeInt(1))
+	if profiled.s.profile.ops[opGet].ops != 0 || profiled.s.profile.ops[opSet].ops != 2 {
+		t.Errorf("ReplaceOrInsert should search once.")
+	}
+}
+
+func TestBTreeCloneOptions(t *testing.T) {
+	sum := &Monoid{
+		Identity: 0,
+		Lift: func(key, value interface{}) interface{} {
+			return int(key.(btreePair).key)
+		},
+		Combine: func(a, b interface{}) interface{} {
+			return a.(int) + b.(int)
+		},
+	}
+	tree := NewBTree(WithValueCodec(func(value interface{}) interface{} {
+		e := value.(btreePair)
+		return [2]interface{}{e.key, e.value}
+	}, func(value interface{}) interface{} {
+		e := value.([2]interface{})
+		return btreePair{e[0].(int), e[1].(string)}
+	}), WithAggregate(sum))
+	for i := 0; i < 10; i++ {
+		tree.ReplaceOrInsert(btreePair{i, "v"})
+	}
+	clone := tree.Clone()
+	clone.ReplaceOrInsert(btreePair{10, "w"})
+	var items []Item
+	clone.Ascend(func(item Item) bool {
+		items = append(items, item)
+		return true
+	})
+	if len(items) != 11 || items[10] != (btreePair{10, "w"}) {
+		t.Errorf("Wrong items in the clone: %v.", items)
+	}
+	if agg := clone.s.Aggregate(); agg != 55 {
+		t.Errorf("Expected the clone to aggregate 55, got %v.", agg)
+	}
+	if agg := tree.s.Aggregate(); agg != 45 {
+		t.Errorf("Expected the tree to aggregate 45, got %v.", agg)
+	}
+}
@@ -0,0 +1,129 @@
+package skiplist
+
+import "math/rand"
+
+// A Builder gathers the configuration of a container, and builds
+// SkipLists, Sets, ZSets or ConcurrentSkipLists with it, so that the
+// configuration can be set up once, in one place, and reused:
+//
+//	b := skiplist.NewBuilder().Comparator(byTime).MaxLevel(16).NodeBlockSize(64)
+//	recent, pending := b.Set(), b.Concurrent()
+//
+// Its methods modify and return the Builder, and can be chained. The
+// containers built share no state, but for the source given to
+// RandSource, and a Builder can be reconfigured after building without
+// affecting them.
+//
+// Whether a container is safe for concurrent use is not part of the
+// configuration: it is chosen by building it with Concurrent rather
+// than SkipList. The elements of the containers do not expire, so
+// there is no time to live to configure either.
+type Builder struct {
+	lessThan func(l, r interface{}) bool
+	// structure holds the options shaping the skip lists, which apply
+	// to all the containers, and opts the others.
+	structure []Option
+	opts      []Option
+}
+
+// NewBuilder returns a Builder with the default configuration, that of
+// New.
+func NewBuilder() *Builder {
+	return &Builder{}
+}
+
+// Comparator sets the comparison function of the keys of SkipLists
+// and ConcurrentSkipLists and of the elements of Sets (see
+// WithComparator), but that of the scores for ZSets, whose members are
+// not ordered. The keys, elements or scores must implement Ordered if
+// it is not set.
+func (b *Builder) Comparator(lessThan func(l, r interface{}) bool) *Builder {
+	b.lessThan = lessThan
+	return b
+}
+
+// MaxLevel is like WithMaxLevel.
+func (b *Builder) MaxLevel(maxLevel int) *Builder {
+	b.structure = append(b.structure, WithMaxLevel(maxLevel))
+	return b
+}
+
+// P is like WithP.
+func (b *Builder) P(p float64) *Builder {
+	b.structure = append(b.structure, WithP(p))
+	return b
+}
+
+// RandSource is like WithRandSource. The source is shared by all the
+// containers built, which must therefore not be used from different
+// goroutines.
+func (b *Builder) RandSource(src rand.Source) *Builder {
+	b.structure = append(b.structure, WithRandSource(src))
+	return b
+}
+
+// NodeBlockSize is like WithNodeBlockSize.
+func (b *Builder) NodeBlockSize(n int) *Builder {
+	b.structure = append(b.structure, WithNodeBlockSize(n))
+	return b
+}
+
+// Cap limits the number of elements to n: when an insertion exceeds
+// it, the element with the smallest key is deleted and passed to
+// onEvict (if not nil), like with WithByteLimit. ZSet panics if it is
+// set.
+func (b *Builder) Cap(n int, onEvict func(key, value interface{})) *Builder {
+	b.opts = append(b.opts, WithSizer(func(key, value interface{}) int {
+		return 1
+	}), WithByteLimit(int64(n), onEvict))
+	return b
+}
+
+// Options adds opts to the configuration, for the options that Builder
+// has no method for. ZSet only supports those shaping the levels of the
+// list, like WithMaxLevel, and panics on the others.
+func (b *Builder) Options(opts ...Option) *Builder {
+	b.opts = append(b.opts, opts...)
+	return b
+}
+
+// options returns all the options of the configuration.
+func (b *Builder) options() []Option {
+	opts := make([]Option, 0, len(b.structure)+len(b.opts)+1)
+	if b.lessThan != nil {
+		opts = append(opts, WithComparator(b.lessThan))
+	}
+	opts = append(opts, b.structure...)
+	return append(opts, b.opts...)
+}
+
+// SkipList returns a new SkipList with the configuration of b.
+func (b *Builder) SkipList() *SkipList {
+	return NewWithOptions(b.options()...)
+}
+
+// Set returns a new Set with the configuration of b.
+func (b *Builder) Set() *Set {
+	return &Set{skiplist: *b.SkipList()}
+}
+
+// Concurrent returns a new ConcurrentSkipList guarding a SkipList with
+// the configuration of b.
+func (b *Builder) Concurrent() *ConcurrentSkipList {
+	return b.SkipList().Synced()
+}
+
+// ZSet returns a new ZSet ordering its scores with the comparator of
+// b, or as Ordered values if none was set. Only the options shaping the
+// skip list are supported: MaxLevel, P, RandSource and NodeBlockSize
+// apply, and ZSet panics if Cap or Options added any other option.
+func (b *Builder) ZSet() *ZSet {
+	z := NewZSet()
+	if b.lessThan != nil {
+		z = NewCustomZSet(b.lessThan)
+	}
+	opts := append(append([]Option(nil), b.structure...), b.opts...)
+	z.sl = NewCustomMap(zsetScoreLessThan(z.scoreLessThan), opts...)
+	z.sl.checkShapeOptions("Builder.ZSet")
+	return z
+}
@@ -0,0 +1,66 @@
+package skiplist
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestBuilder(t *testing.T) {
+	byInt := func(l, r interface{}) bool {
+		return l.(int) < r.(int)
+	}
+	var evicted []interface{}
+	b := NewBuilder().Comparator(byInt).MaxLevel(4).P(0.5).RandSource(rand.NewSource(1)).NodeBlockSize(8)
+	s := b.SkipList()
+	if s.MaxLevel != 4 || s.p != 0.5 || s.rand == nil || s.nodeBlockSize != 8 {
+		t.Errorf("Wrong configuration: %d, %v, %d.", s.MaxLevel, s.p, s.nodeBlockSize)
+	}
+
+	b.Cap(3, func(key, value interface{}) {
+		evicted = append(evicted, key)
+	})
+	capped := b.Set()
+	for i := 5; i > 0; i-- {
+		capped.Add(i)
+	}
+	if capped.Len() != 3 || len(evicted) != 2 || evicted[0] != 2 || evicted[1] != 1 {
+		t.Errorf("Expected 3 elements after evicting 2 and 1, got %d and %v.", capped.Len(), evicted)
+	}
+	for i := 0; i < 10; i++ {
+		s.Set(i, i)
+	}
+	if s.Len() != 10 {
+		t.Errorf("Cap should not apply to the lists built before.")
+	}
+
+	c := b.Options(WithShadowCheck(nil)).Concurrent()
+	for i := 0; i < 5; i++ {
+		c.Set(i, i)
+	}
+	if c.Len() != 3 || !c.exclusiveReads {
+		t.Errorf("Wrong concurrent list: %d elements.", c.Len())
+	}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("ZSet should reject Cap.")
+			}
+		}()
+		b.ZSet()
+	}()
+
+	z := NewBuilder().Comparator(byInt).MaxLevel(4).Options(WithP(0.5)).ZSet()
+	for i := 0; i < 5; i++ {
+		z.Add(i, 10-i)
+	}
+	if z.Card() != 5 || z.Rank(4) != 1 || z.sl.MaxLevel != 4 || z.sl.p != 0.5 {
+		t.Errorf("Wrong ZSet: %d members.", z.Card())
+	}
+	if err := z.Audit(); err != nil {
+		t.Errorf("Built ZSet: %v.", err)
+	}
+	if z := NewBuilder().ZSet(); z.Card() != 0 || z.sl.MaxLevel != DefaultMaxLevel {
+		t.Errorf("Wrong default ZSet.")
+	}
+}
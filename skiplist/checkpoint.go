@@ -0,0 +1,162 @@
+package skiplist
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+)
+
+// checkpoint is the serialized state of an iterator.
+type checkpoint struct {
+	// Key is the current key, or nil if the iterator has not been
+	// advanced yet.
+	Key interface{}
+	// Lower, Upper and Prefix are the bounds of the iterator, nil if
+	// unbounded.
+	Lower, Upper interface{}
+	Prefix       []byte
+}
+
+// A Checkpointer is an Iterator whose position can be serialized. The
+// iterators of SkipList, Snapshot and TieredMap, and those returned by
+// the set operations, FilterIter and MapIter, implement it:
+//
+//	if c, ok := it.(skiplist.Checkpointer); ok {
+//		state, err := c.Checkpoint()
+//		// ...
+//	}
+type Checkpointer interface {
+	Iterator
+	// Checkpoint serializes the position of the iterator (and its
+	// bounds, if any) in terms of the current key, so that it can be
+	// resumed with ResumeIterator, even by another process. The keys
+	// must be encodable with encoding/gob; types other than the basic
+	// ones must be registered with gob.Register. Checkpoint returns an
+	// error if they are not.
+	Checkpoint() ([]byte, error)
+}
+
+func (c *checkpoint) encode() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(c); err != nil {
+		return nil, fmt.Errorf("goskiplist: cannot checkpoint iterator: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func decodeCheckpoint(state []byte) (*checkpoint, error) {
+	c := new(checkpoint)
+	if err := gob.NewDecoder(bytes.NewReader(state)).Decode(c); err != nil {
+		return nil, fmt.Errorf("goskiplist: invalid iterator checkpoint: %v", err)
+	}
+	return c, nil
+}
+
+func (i *iter) Checkpoint() ([]byte, error) {
+	return (&checkpoint{Key: i.key}).encode()
+}
+
+func (i *rangeIterator) Checkpoint() ([]byte, error) {
+	return (&checkpoint{Key: i.key, Lower: i.lowerLimit, Upper: i.upperLimit}).encode()
+}
+
+func (i *boundedIterator) Checkpoint() ([]byte, error) {
+	return (&checkpoint{Key: i.key, Lower: i.lowerBound, Upper: i.upperBound, Prefix: i.prefix}).encode()
+}
+
+func (i *tieredIterator) Checkpoint() ([]byte, error) {
+	return (&checkpoint{Key: i.Key()}).encode()
+}
+
+// lastLessOrEqual returns the last node whose key is less or equal to
+// key, or the header if there is none.
+func (s *SkipList) lastLessOrEqual(key interface{}) *node {
+	current := s.header
+	for i := s.level(); i >= 0; i-- {
+		for current.levels[i].forward != nil && !s.lessThan(key, current.levels[i].forward.key) {
+			current = current.levels[i].forward
+		}
+	}
+	return current
+}
+
+// checkpointOf returns the Checkpoint of it, or an error if it is not a
+// Checkpointer.
+func checkpointOf(it Iterator) ([]byte, error) {
+	c, ok := it.(Checkpointer)
+	if !ok {
+		return nil, fmt.Errorf("goskiplist: cannot checkpoint iterator of type %T", it)
+	}
+	return c.Checkpoint()
+}
+
+// ResumeIterator returns an iterator positioned where the iterator
+// that produced state with Checkpoint was, so that Next continues with
+// the first key greater than the checkpointed one. The list does not
+// need to be the same one, or to be unmodified: if the checkpointed key
+// was deleted, Next continues with the key that followed it.
+func (s *SkipList) ResumeIterator(state []byte) (Iterator, error) {
+	c, err := decodeCheckpoint(state)
+	if err != nil {
+		return nil, err
+	}
+
+	var opts []IterOption
+	if c.Lower != nil {
+		opts = append(opts, WithLowerBound(c.Lower))
+	}
+	if c.Upper != nil {
+		opts = append(opts, WithUpperBoundExclusive(c.Upper))
+	}
+	if c.Prefix != nil {
+		opts = append(opts, WithPrefix(c.Prefix))
+	}
+	it := s.Iterator(opts...)
+	if c.Key == nil {
+		return it, nil
+	}
+
+	current := s.lastLessOrEqual(c.Key)
+	var i *iter
+	switch it := it.(type) {
+	case *iter:
+		i = it
+	case *boundedIterator:
+		i = &it.iter
+		if current == s.header || !it.contains(current.key) {
+			// Nothing within the bounds precedes the key.
+			return it, nil
+		}
+	}
+	i.current = current
+	if current != s.header {
+		i.key = current.key
+		i.value = s.decode(current.value)
+	}
+	return it, nil
+}
+
+// ResumeIterator is like SkipList.ResumeIterator for the iterators of
+// t.
+func (t *TieredMap) ResumeIterator(state []byte) (Iterator, error) {
+	c, err := decodeCheckpoint(state)
+	if err != nil {
+		return nil, err
+	}
+
+	it := t.Iterator().(*tieredIterator)
+	if c.Key == nil {
+		return it, nil
+	}
+	// Start after the checkpointed key in both lists, and move back to
+	// the last element up to it, like SkipList.ResumeIterator. If there
+	// is none, the new iterator already starts after the key.
+	resumed := *it
+	resumed.hotGE = t.hot.lastLessOrEqual(c.Key).next()
+	resumed.coldGE = t.cold.lastLessOrEqual(c.Key).next()
+	resumed.started, resumed.valid = true, true
+	if resumed.Previous() {
+		*it = resumed
+	}
+	return it, nil
+}
@@ -0,0 +1,154 @@
+package skiplist
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestCheckpoint(t *testing.T) {
+	s := NewIntMap()
+	for i := 0; i < 20; i++ {
+		s.Set(i, i)
+	}
+
+	it := s.Iterator()
+	start := mustCheckpoint(t, it)
+	for j := 0; j < 5; j++ {
+		it.Next()
+	}
+	state := mustCheckpoint(t, it)
+
+	resumed, err := s.ResumeIterator(state)
+	if err != nil {
+		t.Fatalf("ResumeIterator returned %v.", err)
+	}
+	if resumed.Key() != 4 || !resumed.Next() || resumed.Key() != 5 {
+		t.Errorf("Expected to resume after key 4, got %v.", resumed.Key())
+	}
+
+	s.Delete(4)
+	resumed, _ = s.ResumeIterator(state)
+	if !resumed.Next() || resumed.Key() != 5 {
+		t.Errorf("Expected to resume after deleted key 4 with 5, got %v.", resumed.Key())
+	}
+
+	resumed, _ = s.ResumeIterator(start)
+	if !resumed.Next() || resumed.Key() != 0 {
+		t.Errorf("Expected an unstarted checkpoint to resume at 0, got %v.", resumed.Key())
+	}
+
+	r := s.Range(10, 15)
+	r.Next()
+	r.Next()
+	resumed, _ = s.ResumeIterator(mustCheckpoint(t, r))
+	var seen []interface{}
+	for resumed.Next() {
+		seen = append(seen, resumed.Key())
+	}
+	if len(seen) != 3 || seen[0] != 12 || seen[2] != 14 {
+		t.Errorf("Expected to resume the range with 12, 13 and 14, got %v.", seen)
+	}
+
+	b := s.Iterator(WithLowerBound(8))
+	resumed, _ = s.ResumeIterator(mustCheckpoint(t, b))
+	if !resumed.Next() || resumed.Key() != 8 {
+		t.Errorf("Expected an unstarted bounded checkpoint to resume at 8, got %v.", resumed.Key())
+	}
+
+	if _, err := s.ResumeIterator([]byte("garbage")); err == nil {
+		t.Errorf("Expected an error for an invalid checkpoint.")
+	}
+}
+
+func TestTieredCheckpoint(t *testing.T) {
+	tm := NewTieredMap(func(l, r interface{}) bool {
+		return l.(int) < r.(int)
+	}, 4)
+	for i := 0; i < 10; i++ {
+		tm.Set(i, i)
+	}
+	it := tm.Iterator()
+	for j := 0; j < 3; j++ {
+		it.Next()
+	}
+	resumed, err := tm.ResumeIterator(mustCheckpoint(t, it))
+	if err != nil {
+		t.Fatalf("ResumeIterator returned %v.", err)
+	}
+	if resumed.Key() != 2 || !resumed.Next() || resumed.Key() != 3 {
+		t.Errorf("Expected to resume after key 2, got %v.", resumed.Key())
+	}
+
+	// Resume after a deleted key, with values going through a codec.
+	tm = NewTieredMap(intLessThan, 100, WithValueCodec(func(value interface{}) interface{} {
+		return []byte(value.(string))
+	}, func(value interface{}) interface{} {
+		return string(value.([]byte))
+	}))
+	for i := 0; i < 10; i++ {
+		tm.Set(i, strconv.Itoa(i))
+	}
+	tm.Compact()
+	it = tm.Iterator()
+	for j := 0; j < 6; j++ {
+		it.Next()
+	}
+	state := mustCheckpoint(t, it)
+	tm.Delete(5)
+	tm.Delete(4)
+	tm.Set(3, "three")
+	resumed, err = tm.ResumeIterator(state)
+	if err != nil {
+		t.Fatalf("ResumeIterator returned %v.", err)
+	}
+	if resumed.Key() != 3 || resumed.Value() != "three" || !resumed.Next() || resumed.Key() != 6 || resumed.Value() != "6" {
+		t.Errorf("Expected to resume on key 3 before key 6, got %v, %v.", resumed.Key(), resumed.Value())
+	}
+	it = tm.Iterator()
+	it.Next()
+	it.Next()
+	state = mustCheckpoint(t, it)
+	tm.Delete(0)
+	tm.Delete(1)
+	resumed, _ = tm.ResumeIterator(state)
+	if !resumed.Next() || resumed.Key() != 2 || resumed.Value() != "2" {
+		t.Errorf("Expected to resume with key 2, got %v.", resumed.Key())
+	}
+}
+
+// mustCheckpoint returns the checkpoint of it, failing t if there is
+// none.
+func mustCheckpoint(t *testing.T, it Iterator) []byte {
+	t.Helper()
+	state, err := checkpointOf(it)
+	if err != nil {
+		t.Fatalf("Checkpoint returned %v.", err)
+	}
+	return state
+}
+
+func TestCheckpointErrors(t *testing.T) {
+	z := NewCustomZSet(func(l, r interface{}) bool {
+		return l.(int) < r.(int)
+	})
+	z.Add("a", 1)
+	it := z.sl.Iterator()
+	it.Next()
+	if _, err := it.(Checkpointer).Checkpoint(); err == nil {
+		t.Errorf("Expected an error for keys gob cannot encode.")
+	}
+
+	s := NewIntMap()
+	s.Set(1, 1)
+	if _, err := checkpointOf(FilterIter(s.Iterator(), nil)); err != nil {
+		t.Errorf("Expected a filtered iterator to be checkpointed, got %v.", err)
+	}
+	if _, err := checkpointOf(MapIter(opaqueIterator{s.Iterator()}, nil)); err == nil {
+		t.Errorf("Expected an error for an iterator that cannot be checkpointed.")
+	}
+}
+
+// opaqueIterator hides the optional methods of an Iterator.
+type opaqueIterator struct {
+	Iterator
+}
@@ -0,0 +1,40 @@
+package skiplist
+
+// valueCodec holds the functions given to WithValueCodec.
+type valueCodec struct {
+	encode, decode func(value interface{}) interface{}
+}
+
+// WithValueCodec makes the list store encode(v) instead of every value
+// v it is given, and return decode(stored) wherever it returns a value:
+// Get, iterators, Min, Delete and so on. This lets lists holding large
+// values, like documents or blobs, keep them compressed without
+// changing the code using them, trading CPU time for memory.
+//
+// decode(encode(v)) must be equal to v. Both functions are called with
+// every value, including nil and the internal values of wrappers like
+// TieredMap, so they should pass through values they do not handle.
+// Sizers (see WithSizer) measure the encoded values; everything else,
+// including aggregates and the onEvict callback, sees decoded ones.
+// Merging lists with different codecs is not supported.
+func WithValueCodec(encode, decode func(value interface{}) interface{}) Option {
+	return func(s *SkipList) {
+		s.codec = &valueCodec{encode: encode, decode: decode}
+	}
+}
+
+// encode returns value as stored in the nodes of s.
+func (s *SkipList) encode(value interface{}) interface{} {
+	if s.codec == nil {
+		return value
+	}
+	return s.codec.encode(value)
+}
+
+// decode returns the value stored in a node of s as given to s.
+func (s *SkipList) decode(stored interface{}) interface{} {
+	if s.codec == nil {
+		return stored
+	}
+	return s.codec.decode(stored)
+}
@@ -0,0 +1,141 @@
+package skiplist
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"strings"
+	"testing"
+)
+
+// gzipped is how gzipCodec stores values.
+type gzipped string
+
+func gzipEncode(value interface{}) interface{} {
+	str, ok := value.(string)
+	if !ok {
+		return value
+	}
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	w.Write([]byte(str))
+	w.Close()
+	return gzipped(buf.String())
+}
+
+func gzipDecode(stored interface{}) interface{} {
+	z, ok := stored.(gzipped)
+	if !ok {
+		return stored
+	}
+	r, err := gzip.NewReader(strings.NewReader(string(z)))
+	if err != nil {
+		panic(err)
+	}
+	b, err := io.ReadAll(r)
+	if err != nil {
+		panic(err)
+	}
+	return string(b)
+}
+
+func TestValueCodec(t *testing.T) {
+	var stored int
+	sizer := func(key, value interface{}) int {
+		if z, ok := value.(gzipped); ok {
+			return len(z)
+		}
+		return 0
+	}
+	s := NewIntMap(WithValueCodec(gzipEncode, gzipDecode), WithSizer(sizer), WithShadowCheck(nil))
+	blob := func(i int) string {
+		return strings.Repeat(string(rune('a'+i%26)), 1000)
+	}
+	for i := 0; i < 50; i++ {
+		s.Set(i, blob(i))
+		stored += len(blob(i))
+	}
+	s.Set(50, 50)
+
+	if v, ok := s.Get(7); !ok || v != blob(7) {
+		t.Errorf("Get should decode values, got %.10v.", v)
+	}
+	if v, _ := s.Get(50); v != 50 {
+		t.Errorf("Values not handled by the codec should pass through, got %v.", v)
+	}
+	if _, ok := s.header.next().value.(gzipped); !ok {
+		t.Errorf("Values should be stored encoded.")
+	}
+	if s.TotalBytes() >= int64(stored)/10 {
+		t.Errorf("Sizer should see the compressed values: %d bytes.", s.TotalBytes())
+	}
+
+	i := 0
+	for it := s.Iterator(); it.Next() && i < 50; i++ {
+		if it.Value() != blob(i) {
+			t.Errorf("Iterator returned %.10v for %v.", it.Value(), it.Key())
+		}
+	}
+	if prev, existed, _ := s.SetReturning(3, "x"); !existed || prev != blob(3) {
+		t.Errorf("SetReturning should return the decoded value, got %.10v.", prev)
+	}
+	if !s.CompareAndSwap(3, "x", "y") {
+		t.Errorf("CompareAndSwap should compare decoded values.")
+	}
+	if v, ok := s.Delete(3); !ok || v != "y" {
+		t.Errorf("Delete should return the decoded value, got %v.", v)
+	}
+
+	c := s.Clone()
+	if !c.Equal(s, nil) {
+		t.Errorf("The clone should be equal to the original.")
+	}
+	s.RebuildOptimal()
+	if v, _ := s.Get(10); v != blob(10) || !c.Equal(s, nil) {
+		t.Errorf("RebuildOptimal should keep the values, got %.10v.", v)
+	}
+	if err := s.CheckShadow(); err != nil {
+		t.Errorf("ValueCodec: %v.", err)
+	}
+}
+
+func TestValueCodecSnapshot(t *testing.T) {
+	s := NewIntMap(WithValueCodec(gzipEncode, gzipDecode))
+	for i := 0; i < 10; i++ {
+		s.Set(i, strings.Repeat("a", i))
+	}
+	sn := s.Snapshot()
+	s.Set(3, "changed")
+	if v, ok := sn.Get(3); !ok || v != "aaa" {
+		t.Errorf("Expected the snapshot to decode values, got %#v.", v)
+	}
+	if _, v, ok := sn.GetGreaterOrEqual(4); !ok || v != "aaaa" {
+		t.Errorf("Expected GetGreaterOrEqual to decode values, got %#v.", v)
+	}
+	for it := sn.Iterator(); it.Next(); {
+		if it.Value() != strings.Repeat("a", it.Key().(int)) {
+			t.Errorf("Expected snapshot iterators to decode values, got %#v.", it.Value())
+		}
+	}
+	if it := sn.GetElemByRank(6); it.Value() != "aaaaa" {
+		t.Errorf("Expected GetElemByRank to decode values, got %#v.", it.Value())
+	}
+}
+
+func TestValueCodecSwapValues(t *testing.T) {
+	s := NewIntMap(WithValueCodec(gzipEncode, gzipDecode), WithShadowCheck(nil))
+	s.Set(1, "a")
+	s.Set(2, "b")
+	if !s.SwapValues(1, 2) {
+		t.Fatalf("Expected SwapValues to find both keys.")
+	}
+	if v, _ := s.Get(1); v != "b" {
+		t.Errorf("Expected the swapped value to be encoded once, got %#v.", v)
+	}
+	if v, _ := s.Get(2); v != "a" {
+		t.Errorf("Expected the swapped value to be encoded once, got %#v.", v)
+	}
+	if err := s.CheckShadow(); err != nil {
+		t.Errorf("SwapValues: %v.", err)
+	}
+}
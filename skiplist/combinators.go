@@ -0,0 +1,74 @@
+package skiplist
+
+// FilterIter returns an iterator over the elements of it for which pred
+// returns true, so that range pipelines can skip elements without
+// collecting them first. Moving the returned iterator moves it.
+//
+// If no further (or previous) element satisfies pred, Next (or
+// Previous) returns false and stays on the current element. Checkpoint
+// records the position of it, if it is a Checkpointer, but not pred:
+// pass the resumed iterator to FilterIter again.
+func FilterIter(it Iterator, pred func(key, value interface{}) bool) Iterator {
+	return &filterIterator{Iterator: it, pred: pred}
+}
+
+type filterIterator struct {
+	Iterator
+	pred func(key, value interface{}) bool
+}
+
+// move moves the iterator with step until an element satisfies pred,
+// or moves it back with undo if there is none.
+func (i *filterIterator) move(step, undo func() bool) bool {
+	moved := 0
+	for step() {
+		moved++
+		if i.pred(i.Iterator.Key(), i.Iterator.Value()) {
+			return true
+		}
+	}
+	for ; moved > 0; moved-- {
+		undo()
+	}
+	return false
+}
+
+func (i *filterIterator) Checkpoint() ([]byte, error) {
+	return checkpointOf(i.Iterator)
+}
+
+func (i *filterIterator) Next() bool {
+	return i.move(i.Iterator.Next, i.Iterator.Previous)
+}
+
+func (i *filterIterator) Previous() bool {
+	return i.move(i.Iterator.Previous, i.Iterator.Next)
+}
+
+func (i *filterIterator) Seek(key interface{}) bool {
+	if !i.Iterator.Seek(key) {
+		return false
+	}
+	return i.pred(i.Iterator.Key(), i.Iterator.Value()) || i.Next()
+}
+
+// MapIter returns an iterator over the elements of it whose values are
+// replaced by fn(key, value), computed when Value is called. Keys, and
+// therefore the order and the movements of the iterator, are those of
+// it.
+func MapIter(it Iterator, fn func(key, value interface{}) interface{}) Iterator {
+	return &mapIterator{Iterator: it, fn: fn}
+}
+
+type mapIterator struct {
+	Iterator
+	fn func(key, value interface{}) interface{}
+}
+
+func (i *mapIterator) Checkpoint() ([]byte, error) {
+	return checkpointOf(i.Iterator)
+}
+
+func (i *mapIterator) Value() interface{} {
+	return i.fn(i.Iterator.Key(), i.Iterator.Value())
+}
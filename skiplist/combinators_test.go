@@ -0,0 +1,65 @@
+package skiplist
+
+import "testing"
+
+func TestFilterIter(t *testing.T) {
+	s := NewIntMap()
+	for i := 0; i < 20; i++ {
+		s.Set(i, i)
+	}
+	even := func(key, value interface{}) bool {
+		return key.(int)%2 == 0
+	}
+
+	it := FilterIter(s.Range(3, 12), even)
+	var keys []int
+	for it.Next() {
+		keys = append(keys, it.Key().(int))
+	}
+	if !equalInts(keys, []int{4, 6, 8, 10}) {
+		t.Errorf("Expected even keys in [3, 12), got %v.", keys)
+	}
+	if it.Key() != 10 {
+		t.Errorf("Exhausted iterator should stay on 10, not %v.", it.Key())
+	}
+	if !it.Previous() || it.Key() != 8 {
+		t.Errorf("Previous should go to 8, not %v.", it.Key())
+	}
+
+	it = FilterIter(s.Iterator(), even)
+	if !it.Seek(7) || it.Key() != 8 {
+		t.Errorf("Seek(7) should go to 8, not %v.", it.Key())
+	}
+	if !it.Previous() || it.Key() != 6 {
+		t.Errorf("Previous should go to 6, not %v.", it.Key())
+	}
+
+	it = FilterIter(s.Iterator(), func(key, value interface{}) bool {
+		return key.(int) >= 5
+	})
+	if !it.Seek(3) || it.Key() != 5 {
+		t.Errorf("Seek(3) should go to 5, not %v.", it.Key())
+	}
+	if it.Previous() || it.Key() != 5 {
+		t.Errorf("Previous should fail without moving, got %v.", it.Key())
+	}
+}
+
+func TestMapIter(t *testing.T) {
+	s := NewIntMap()
+	for i := 0; i < 5; i++ {
+		s.Set(i, i)
+	}
+	it := MapIter(FilterIter(s.Iterator(), func(key, value interface{}) bool {
+		return key.(int) > 1
+	}), func(key, value interface{}) interface{} {
+		return value.(int) * 10
+	})
+	var values []int
+	for it.Next() {
+		values = append(values, it.Value().(int))
+	}
+	if !equalInts(values, []int{20, 30, 40}) {
+		t.Errorf("Expected mapped values, got %v.", values)
+	}
+}
@@ -0,0 +1,123 @@
+package skiplist
+
+import (
+	"sync"
+	"time"
+)
+
+// CompactionPolicy tells a CompactionScheduler when to compact a
+// TieredMap, on top of the compactions triggered by its hot list
+// reaching hotLimit entries. Zero fields disable the corresponding
+// trigger.
+type CompactionPolicy struct {
+	// TombstoneRatio triggers a compaction when the hot list holds at
+	// least TombstoneRatio tombstones per live key.
+	TombstoneRatio float64
+	// IdleTime triggers a compaction when the map has not been written
+	// to for IdleTime, so that cleanup happens during quiet periods.
+	IdleTime time.Duration
+	// CheckInterval is how often the policy is evaluated (IdleTime/4,
+	// or one second, if zero).
+	CheckInterval time.Duration
+}
+
+// CompactionStats describes the compactions of a TieredMap.
+type CompactionStats struct {
+	// Compactions is the total number of compactions, BySize,
+	// ByTombstones and ByIdle the number of those triggered by the
+	// size of the hot list and by each trigger of the scheduler.
+	Compactions, BySize, ByTombstones, ByIdle uint64
+	// Tombstones is the number of deleted keys currently shadowed by a
+	// tombstone in the hot list.
+	Tombstones int
+	// LastDuration is how long the last compaction took.
+	LastDuration time.Duration
+}
+
+// CompactionStats returns statistics about the compactions of t.
+func (t *TieredMap) CompactionStats() CompactionStats {
+	stats := t.stats
+	stats.Tombstones = t.tombstones
+	return stats
+}
+
+// A CompactionScheduler compacts a TieredMap in the background
+// according to a CompactionPolicy.
+type CompactionScheduler struct {
+	t      *TieredMap
+	mu     sync.Locker
+	policy CompactionPolicy
+	stop   chan struct{}
+	done   chan struct{}
+	// lastWrites is the write count of t at the last check, and
+	// lastWrite when it last changed.
+	lastWrites uint64
+	lastWrite  time.Time
+}
+
+// StartCompactionScheduler starts compacting t in the background
+// according to policy. As a TieredMap is not safe for concurrent use,
+// mu must be the lock guarding all other accesses to t (including
+// iterations); the scheduler holds it while checking the policy and
+// compacting.
+func (t *TieredMap) StartCompactionScheduler(mu sync.Locker, policy CompactionPolicy) *CompactionScheduler {
+	if policy.CheckInterval <= 0 {
+		policy.CheckInterval = time.Second
+		if policy.IdleTime > 0 {
+			policy.CheckInterval = policy.IdleTime / 4
+		}
+	}
+	c := &CompactionScheduler{
+		t:         t,
+		mu:        mu,
+		policy:    policy,
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+		lastWrite: time.Now(),
+	}
+	go c.run()
+	return c
+}
+
+// Stop stops the scheduler, waiting for a running compaction to
+// finish.
+func (c *CompactionScheduler) Stop() {
+	close(c.stop)
+	<-c.done
+}
+
+func (c *CompactionScheduler) run() {
+	defer close(c.done)
+	ticker := time.NewTicker(c.policy.CheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.stop:
+			return
+		case now := <-ticker.C:
+			c.mu.Lock()
+			c.check(now)
+			c.mu.Unlock()
+		}
+	}
+}
+
+// check compacts the map if the policy says so at time now.
+func (c *CompactionScheduler) check(now time.Time) {
+	t := c.t
+	if t.writes != c.lastWrites {
+		c.lastWrites, c.lastWrite = t.writes, now
+	}
+	if t.hot.Len() == 0 {
+		return
+	}
+	switch {
+	case c.policy.TombstoneRatio > 0 && float64(t.tombstones) >= c.policy.TombstoneRatio*float64(t.Len()):
+		t.stats.ByTombstones++
+	case c.policy.IdleTime > 0 && now.Sub(c.lastWrite) >= c.policy.IdleTime:
+		t.stats.ByIdle++
+	default:
+		return
+	}
+	t.Compact()
+}
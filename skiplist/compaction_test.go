@@ -0,0 +1,66 @@
+package skiplist
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCompactionPolicy(t *testing.T) {
+	tm := NewTieredMap(intLessThan, 1000)
+	for i := 0; i < 100; i++ {
+		tm.Set(i, i)
+	}
+	tm.Compact()
+	for i := 0; i < 30; i++ {
+		tm.Delete(i)
+	}
+	tm.Set(0, 0)
+	if stats := tm.CompactionStats(); stats.Tombstones != 29 || stats.Compactions != 1 {
+		t.Fatalf("Wrong stats: %+v.", stats)
+	}
+
+	c := &CompactionScheduler{t: tm, policy: CompactionPolicy{TombstoneRatio: 0.5, IdleTime: time.Minute}}
+	now := time.Now()
+	c.check(now)
+	if stats := tm.CompactionStats(); stats.Compactions != 1 {
+		t.Errorf("Nothing should be compacted yet: %+v.", stats)
+	}
+	for i := 30; i < 50; i++ {
+		tm.Delete(i)
+	}
+	c.check(now)
+	if stats := tm.CompactionStats(); stats.ByTombstones != 1 || stats.Tombstones != 0 {
+		t.Errorf("Expected a compaction for tombstones: %+v.", stats)
+	}
+
+	tm.Set(1000, 1000)
+	c.check(now.Add(time.Second))
+	c.check(now.Add(30 * time.Second))
+	if stats := tm.CompactionStats(); stats.ByIdle != 0 {
+		t.Errorf("The map is not idle yet: %+v.", stats)
+	}
+	c.check(now.Add(time.Minute + time.Second))
+	if stats := tm.CompactionStats(); stats.ByIdle != 1 || tm.hot.Len() != 0 {
+		t.Errorf("Expected a compaction for idleness: %+v.", stats)
+	}
+	if tm.Len() != 52 {
+		t.Errorf("Compactions should not lose keys, got %d.", tm.Len())
+	}
+}
+
+func TestCompactionScheduler(t *testing.T) {
+	var mu sync.Mutex
+	tm := NewTieredMap(intLessThan, 1000)
+	c := tm.StartCompactionScheduler(&mu, CompactionPolicy{IdleTime: 20 * time.Millisecond})
+	mu.Lock()
+	tm.Set(1, 1)
+	mu.Unlock()
+	time.Sleep(100 * time.Millisecond)
+	c.Stop()
+	mu.Lock()
+	defer mu.Unlock()
+	if stats := tm.CompactionStats(); stats.ByIdle != 1 {
+		t.Errorf("Expected the scheduler to compact the idle map: %+v.", stats)
+	}
+}
@@ -0,0 +1,30 @@
+package skiplist
+
+import "cmp"
+
+// LessOf returns a comparison function for keys of type T, suitable for
+// NewCustomMap, NewCustomSet and WithComparator. It panics on keys of
+// any other type.
+func LessOf[T cmp.Ordered]() func(l, r interface{}) bool {
+	return func(l, r interface{}) bool {
+		return cmp.Less(l.(T), r.(T))
+	}
+}
+
+// LessFunc adapts a typed less function, like the ones used with the
+// sort and slices packages, to a comparison function for keys of type
+// T.
+func LessFunc[T any](less func(a, b T) bool) func(l, r interface{}) bool {
+	return func(l, r interface{}) bool {
+		return less(l.(T), r.(T))
+	}
+}
+
+// CompareFunc adapts a three-way comparison function returning a
+// negative number, zero or a positive number, like cmp.Compare or
+// strings.Compare, to a comparison function for keys of type T.
+func CompareFunc[T any](compare func(a, b T) int) func(l, r interface{}) bool {
+	return func(l, r interface{}) bool {
+		return compare(l.(T), r.(T)) < 0
+	}
+}
@@ -0,0 +1,39 @@
+package skiplist
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestComparatorAdapters(t *testing.T) {
+	floats := NewCustomMap(LessOf[float64]())
+	for _, f := range []float64{2.5, math.Inf(-1), 1, math.NaN()} {
+		floats.Set(f, f)
+	}
+	if k, _, _ := floats.Min(); !math.IsNaN(k.(float64)) {
+		t.Errorf("LessOf should order NaN first, like cmp.Less; got %v.", k)
+	}
+	if k, _, _ := floats.Max(); k != 2.5 {
+		t.Errorf("Expected 2.5 to be the largest key, got %v.", k)
+	}
+
+	byLength := NewCustomSet(LessFunc(func(a, b string) bool {
+		return len(a) < len(b)
+	}))
+	byLength.Add("ccc")
+	byLength.Add("a")
+	byLength.Add("bb")
+	byLength.Add("dd")
+	if byLength.Len() != 3 || !byLength.Contains("xx") {
+		t.Errorf("LessFunc should deem strings of equal length equal.")
+	}
+
+	folded := NewCustomMap(CompareFunc(func(a, b string) int {
+		return strings.Compare(strings.ToLower(a), strings.ToLower(b))
+	}))
+	folded.Set("Foo", 1)
+	if v, ok := folded.Get("FOO"); !ok || v != 1 {
+		t.Errorf("CompareFunc should find FOO, got %v, %v.", v, ok)
+	}
+}
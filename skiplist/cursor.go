@@ -0,0 +1,48 @@
+package skiplist
+
+// A Cursor looks up keys in a SkipList, remembering where the previous
+// lookup ended. Looking up a key slightly greater than the previous one
+// then takes time logarithmic in the distance between them rather than
+// in the length of the list, which suits nearly sorted workloads like
+// log replay. Looking up a smaller key falls back to a full search.
+//
+// A Cursor must not be used across modifications of its list; call
+// Reset after modifying the list.
+type Cursor struct {
+	list *SkipList
+	// finger holds where the previous lookup ended.
+	finger finger
+}
+
+// Cursor returns a new Cursor positioned before the first element of
+// s.
+func (s *SkipList) Cursor() *Cursor {
+	return &Cursor{list: s}
+}
+
+// Reset moves c back before the first element of its list.
+func (c *Cursor) Reset() {
+	c.finger.preds, c.finger.ranks = c.finger.preds[:0], c.finger.ranks[:0]
+}
+
+// search returns the first node whose key is greater or equal to key,
+// or nil if there is none.
+func (c *Cursor) search(key interface{}) *node {
+	return c.finger.search(c.list, key)
+}
+
+// Get is like SkipList.Get.
+func (c *Cursor) Get(key interface{}) (value interface{}, ok bool) {
+	if candidate := c.search(key); candidate != nil && c.list.equal(candidate.key, key) {
+		return c.list.decode(candidate.value), true
+	}
+	return nil, false
+}
+
+// GetGE is like SkipList.GetGreaterOrEqual.
+func (c *Cursor) GetGE(min interface{}) (actualKey, value interface{}, ok bool) {
+	if candidate := c.search(min); candidate != nil {
+		return candidate.key, c.list.decode(candidate.value), true
+	}
+	return nil, nil, false
+}
@@ -0,0 +1,46 @@
+package skiplist
+
+import "testing"
+
+func TestCursor(t *testing.T) {
+	s := NewIntMap()
+	for i := 0; i < 1000; i++ {
+		s.Set(i*2, i)
+	}
+	c := s.Cursor()
+	for i := 0; i < 2000; i++ {
+		v, ok := c.Get(i)
+		if ok != (i%2 == 0) || (ok && v != i/2) {
+			t.Fatalf("Get(%d) returned %v, %v.", i, v, ok)
+		}
+	}
+
+	// Going backwards and jumping around must still work.
+	for _, k := range []int{1500, 3, 3, 1998, 0, 777, 1999} {
+		key, v, ok := c.GetGE(k)
+		expected := k + k%2
+		if k == 1999 {
+			if ok {
+				t.Errorf("GetGE(1999) should not find anything, got %v.", key)
+			}
+			continue
+		}
+		if !ok || key != expected || v != expected/2 {
+			t.Errorf("GetGE(%d) returned %v, %v, %v.", k, key, v, ok)
+		}
+	}
+
+	for i := 0; i < 2000; i += 4 {
+		s.Delete(i)
+	}
+	c.Reset()
+	for i := 0; i < 2000; i += 2 {
+		if _, ok := c.Get(i); ok != (i%4 == 2) {
+			t.Fatalf("Get(%d) after deletions returned %v.", i, ok)
+		}
+	}
+
+	if _, ok := NewIntMap().Cursor().Get(1); ok {
+		t.Errorf("Get on an empty list should fail.")
+	}
+}
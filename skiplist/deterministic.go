@@ -0,0 +1,270 @@
+package skiplist
+
+// dslInfinity is the key of the last node of every level of a
+// DeterministicSkipList, greater than all the other keys.
+type dslInfinity struct{}
+
+// dslNode is a node of a DeterministicSkipList. The nodes of a level
+// are linked by right, and down points to the first node of the range
+// of the level below that ends with the node having the same key.
+type dslNode struct {
+	key, value  interface{}
+	right, down *dslNode
+}
+
+// A DeterministicSkipList is an ordered map implemented as a 1-2-3
+// skip list, after Munro, Papadakis and Sedgewick: every range of
+// nodes between two consecutive nodes of the level above holds 2 to 4
+// nodes, so Get, Set and Delete take O(log n) time in the worst case
+// rather than in expectation, and its shape depends only on the
+// sequence of operations. It is a separate, simpler structure than
+// SkipList, meant for users who cannot tolerate probabilistic tail
+// latencies: it supports neither ranks nor options.
+type DeterministicSkipList struct {
+	lessThan func(l, r interface{}) bool
+	// header is the only node of the top level. bottom ends the down
+	// links of the lowest level, and tail the right links of all
+	// levels.
+	header, bottom, tail *dslNode
+	length               int
+}
+
+// NewDeterministicSkipList returns a new DeterministicSkipList that
+// will use lessThan as the comparison function.
+func NewDeterministicSkipList(lessThan func(l, r interface{}) bool) *DeterministicSkipList {
+	s := &DeterministicSkipList{lessThan: lessThan, bottom: &dslNode{}}
+	s.tail = &dslNode{key: dslInfinity{}}
+	s.tail.right = s.tail
+	s.header = &dslNode{key: dslInfinity{}, right: s.tail, down: s.bottom}
+	return s
+}
+
+// less compares keys, dslInfinity being greater than any other key.
+func (s *DeterministicSkipList) less(l, r interface{}) bool {
+	if _, inf := l.(dslInfinity); inf {
+		return false
+	}
+	if _, inf := r.(dslInfinity); inf {
+		return true
+	}
+	return s.lessThan(l, r)
+}
+
+// same returns true if l and r are equal keys.
+func (s *DeterministicSkipList) same(l, r interface{}) bool {
+	return !s.less(l, r) && !s.less(r, l)
+}
+
+// count returns the number of nodes in the range below n.
+func (s *DeterministicSkipList) count(n *dslNode) int {
+	c := 1
+	for x := n.down; !s.same(x.key, n.key); x = x.right {
+		c++
+	}
+	return c
+}
+
+// Len returns the number of elements in s.
+func (s *DeterministicSkipList) Len() int {
+	return s.length
+}
+
+// Height returns the number of levels of s, which grows as O(log n)
+// for n elements.
+func (s *DeterministicSkipList) Height() int {
+	h := 0
+	for n := s.header; n != s.bottom; n = n.down {
+		h++
+	}
+	return h
+}
+
+// search returns the first node of the lowest level whose key is
+// greater or equal to key.
+func (s *DeterministicSkipList) search(key interface{}) *dslNode {
+	current := s.header
+	for {
+		for s.less(current.key, key) {
+			current = current.right
+		}
+		if current.down == s.bottom {
+			return current
+		}
+		current = current.down
+	}
+}
+
+// Get returns the value associated with key in s. The second return
+// value is true when the key is present.
+func (s *DeterministicSkipList) Get(key interface{}) (value interface{}, ok bool) {
+	n := s.search(key)
+	if _, inf := n.key.(dslInfinity); inf || s.less(key, n.key) {
+		return nil, false
+	}
+	return n.value, true
+}
+
+// GetGreaterOrEqual finds the element whose key is greater than or
+// equal to min, and returns its key and value. ok is false if there is
+// no such element.
+func (s *DeterministicSkipList) GetGreaterOrEqual(min interface{}) (actualKey, value interface{}, ok bool) {
+	n := s.search(min)
+	if _, inf := n.key.(dslInfinity); inf {
+		return nil, nil, false
+	}
+	return n.key, n.value, true
+}
+
+// Set sets the value of key to value, adding key to s if needed. It
+// returns true if key was added. Ranges that are full are split on the
+// way down, so that the insertion cannot overflow them.
+func (s *DeterministicSkipList) Set(key, value interface{}) bool {
+	if key == nil {
+		panic(ErrNilKey)
+	}
+	current := s.header
+	var prev *dslNode
+	for current.down != s.bottom {
+		if s.count(current) == 4 {
+			// Raise the second node of the range, splitting it in two
+			// ranges of 2 nodes.
+			second := current.down.right
+			current.right = &dslNode{key: current.key, right: current.right, down: second.right}
+			current.key = second.key
+			if s.less(current.key, key) {
+				current = current.right
+			}
+		}
+		prev, current = nil, current.down
+		for s.less(current.key, key) {
+			prev, current = current, current.right
+		}
+	}
+	s.grow()
+
+	if _, inf := current.key.(dslInfinity); !inf && !s.less(key, current.key) {
+		current.value = value
+		return false
+	}
+	if prev != nil {
+		prev.right = &dslNode{key: key, value: value, right: current, down: s.bottom}
+	} else {
+		// current may be the first node of a range, which the level
+		// above points to: insert after it, and swap their contents.
+		current.right = &dslNode{key: current.key, value: current.value, right: current.right, down: s.bottom}
+		current.key, current.value = key, value
+	}
+	s.length++
+	s.grow()
+	return true
+}
+
+// grow adds a level above the top level of s if it was split.
+func (s *DeterministicSkipList) grow() {
+	if s.header.right != s.tail {
+		s.header = &dslNode{key: dslInfinity{}, right: s.tail, down: s.header}
+	}
+}
+
+// Delete removes key from s and returns its value. ok is false if key
+// was not present. Ranges with the minimum of 2 nodes are widened on
+// the way down, by borrowing a node from a neighbor or merging with
+// it, so that the deletion cannot underflow them.
+func (s *DeterministicSkipList) Delete(key interface{}) (value interface{}, ok bool) {
+	var parent, prev *dslNode
+	current := s.header
+	// stale holds the nodes above the lowest level with key, whose key
+	// must be replaced if it is deleted.
+	var stale []*dslNode
+	for current.down != s.bottom {
+		if parent != nil {
+			current = s.widen(parent, prev, current)
+		}
+		if s.same(current.key, key) {
+			stale = append(stale, current)
+		}
+		parent, prev, current = current, nil, current.down
+		for s.less(current.key, key) {
+			prev, current = current, current.right
+		}
+	}
+
+	if _, inf := current.key.(dslInfinity); inf || s.less(key, current.key) {
+		s.shrink()
+		return nil, false
+	}
+	value = current.value
+	if prev != nil {
+		prev.right = current.right
+		for _, n := range stale {
+			n.key = prev.key
+		}
+	} else {
+		// current is the first node of a range, which is not its last
+		// one: replace it with its successor.
+		next := current.right
+		current.key, current.value, current.right = next.key, next.value, next.right
+	}
+	s.length--
+	s.shrink()
+	return value, true
+}
+
+// widen makes the range below current, a node in the range below
+// parent preceded by prev, hold at least 3 nodes, and returns the node
+// whose range now holds the key being deleted.
+func (s *DeterministicSkipList) widen(parent, prev, current *dslNode) *dslNode {
+	if s.count(current) > 2 {
+		return current
+	}
+	if !s.same(current.key, parent.key) {
+		next := current.right
+		if s.count(next) > 2 {
+			// Borrow the first node of the range of next.
+			current.key = next.down.key
+			next.down = next.down.right
+		} else {
+			// Merge with next.
+			current.key, current.right = next.key, next.right
+		}
+		return current
+	}
+	// current ends the range of parent, which has another node before
+	// it.
+	if s.count(prev) > 2 {
+		// Borrow the last node of the range of prev.
+		n := prev.down
+		for !s.same(n.right.key, prev.key) {
+			n = n.right
+		}
+		prev.key, current.down = n.key, n.right
+		return current
+	}
+	// Merge with prev.
+	prev.key, prev.right = current.key, current.right
+	return prev
+}
+
+// shrink removes the top levels of s holding a single node.
+func (s *DeterministicSkipList) shrink() {
+	for s.header.down != s.bottom && s.header.down.right == s.tail {
+		s.header = s.header.down
+	}
+}
+
+// Range calls fn for the elements of s in order, until fn returns
+// false.
+func (s *DeterministicSkipList) Range(fn func(key, value interface{}) bool) {
+	n := s.header
+	for n.down != s.bottom {
+		n = n.down
+	}
+	for ; n != s.tail; n = n.right {
+		if _, inf := n.key.(dslInfinity); inf {
+			return
+		}
+		if !fn(n.key, n.value) {
+			return
+		}
+	}
+}
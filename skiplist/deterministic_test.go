@@ -0,0 +1,113 @@
+package skiplist
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// checkDeterministic verifies that every range of s holds 2 to 4 nodes
+// and ends with the key of the node above it, and that the lowest level
+// is sorted.
+func checkDeterministic(t *testing.T, s *DeterministicSkipList) {
+	t.Helper()
+	for upper := s.header; upper.down != s.bottom; upper = upper.down {
+		for n := upper; n != s.tail; n = n.right {
+			if c := s.count(n); c < 2 || c > 4 {
+				t.Fatalf("Range below %v holds %d nodes.", n.key, c)
+			}
+		}
+	}
+	count := 0
+	var last interface{}
+	s.Range(func(key, value interface{}) bool {
+		if last != nil && !s.less(last, key) {
+			t.Fatalf("Keys %v and %v are out of order.", last, key)
+		}
+		last = key
+		count++
+		return true
+	})
+	if count != s.Len() {
+		t.Fatalf("Expected %d elements, got %d.", s.Len(), count)
+	}
+}
+
+func TestDeterministicSkipList(t *testing.T) {
+	s := NewDeterministicSkipList(func(l, r interface{}) bool {
+		return l.(int) < r.(int)
+	})
+	r := rand.New(rand.NewSource(1))
+	model := make(map[int]int)
+	for i := 0; i < 5000; i++ {
+		k := r.Intn(500)
+		if r.Intn(3) == 0 {
+			v, ok := s.Delete(k)
+			mv, mok := model[k]
+			if ok != mok || (ok && v != mv) {
+				t.Fatalf("Delete(%d) returned %v, %v, expected %v, %v.", k, v, ok, mv, mok)
+			}
+			delete(model, k)
+		} else {
+			_, present := model[k]
+			if added := s.Set(k, i); added == present {
+				t.Fatalf("Set(%d) returned %v.", k, added)
+			}
+			model[k] = i
+		}
+		checkDeterministic(t, s)
+	}
+	for k := 0; k < 500; k++ {
+		v, ok := s.Get(k)
+		if mv, mok := model[k]; ok != mok || (ok && v != mv) {
+			t.Errorf("Get(%d) returned %v, %v, expected %v, %v.", k, v, ok, mv, mok)
+		}
+	}
+	if k, _, ok := s.GetGreaterOrEqual(1000); ok {
+		t.Errorf("Expected no key greater than 1000, got %v.", k)
+	}
+
+	for k := range model {
+		s.Delete(k)
+	}
+	checkDeterministic(t, s)
+	if s.Len() != 0 || s.Height() != 1 {
+		t.Errorf("Expected an empty list of height 1, got %d elements and height %d.", s.Len(), s.Height())
+	}
+
+	// Sorted insertions are the worst case of many balanced
+	// structures.
+	for i := 0; i < 1<<14; i++ {
+		s.Set(i, i)
+	}
+	checkDeterministic(t, s)
+	if max := int(math.Log2(float64(s.Len()+1))) + 2; s.Height() > max {
+		t.Errorf("Height %d exceeds %d.", s.Height(), max)
+	}
+	if k, v, ok := s.GetGreaterOrEqual(-1); !ok || k != 0 || v != 0 {
+		t.Errorf("GetGreaterOrEqual(-1) returned %v, %v, %v.", k, v, ok)
+	}
+}
+
+func TestDeterministicSkipListShape(t *testing.T) {
+	build := func() *DeterministicSkipList {
+		s := NewDeterministicSkipList(func(l, r interface{}) bool {
+			return l.(int) < r.(int)
+		})
+		for i := 0; i < 1000; i++ {
+			s.Set(i*7919%1000, i)
+			if i%3 == 0 {
+				s.Delete(i * 31 % 1000)
+			}
+		}
+		return s
+	}
+	a, b := build(), build()
+	for x, y := a.header, b.header; x != a.bottom; x, y = x.down, y.down {
+		for n, m := x, y; n != a.tail; n, m = n.right, m.right {
+			if m == b.tail || n.key != m.key {
+				t.Fatalf("Lists built alike should have the same shape.")
+			}
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package skiplist
+
+// finger holds, for every level, the last node before the key of the
+// previous search and its rank. It is used by WithFingerSearch and by
+// Cursor.
+type finger struct {
+	preds []*node
+	ranks []uint32
+}
+
+// WithFingerSearch makes Get, Set and Delete start searching from where
+// the previous one of them ended, like a Cursor, instead of from the
+// header. Accessing a key slightly greater than the previous one then
+// takes time logarithmic in the distance between them rather than in
+// the length of the list, which speeds up clustered accesses like
+// time-ordered insertions. Accessing a smaller key falls back to a full
+// search. The lists derived from s, like its snapshots, do not keep
+// the option.
+//
+// Get then updates the list, so it must not be called concurrently
+// with any other method. ConcurrentSkipList serializes its readers
+// accordingly.
+func WithFingerSearch() Option {
+	return func(s *SkipList) {
+		s.finger = &finger{}
+	}
+}
+
+// fingerSearch moves the finger of s to the last nodes before key, and
+// returns the node following them.
+func (s *SkipList) fingerSearch(key interface{}) *node {
+	return s.finger.search(s, key)
+}
+
+// search moves f to the last nodes of s before key, and returns the node
+// following them. It climbs from where the previous search ended until
+// the key falls within the next pointer of a level, then descends from
+// there, unless key is not greater than the previous one, or f is not
+// as high as s, in which case it starts over from the header.
+func (f *finger) search(s *SkipList, key interface{}) *node {
+	top := s.level()
+	if len(f.preds) != top+1 || (f.preds[0] != s.header && !s.lessThan(f.preds[0].key, key)) {
+		f.preds, f.ranks = f.preds[:0], f.ranks[:0]
+		for i := 0; i <= top; i++ {
+			f.preds = append(f.preds, s.header)
+			f.ranks = append(f.ranks, 0)
+		}
+	}
+
+	// The levels above h keep their nodes.
+	h := 0
+	for h < top && f.preds[h].levels[h].forward != nil && s.lessThan(f.preds[h].levels[h].forward.key, key) {
+		h++
+	}
+	current, rank := f.preds[h], f.ranks[h]
+	for i := h; i >= 0; i-- {
+		// The previous search may have gone further at lower levels.
+		if p := f.preds[i]; p != s.header && (current == s.header || s.lessThan(current.key, p.key)) {
+			current, rank = p, f.ranks[i]
+		}
+		for current.levels[i].forward != nil && s.lessThan(current.levels[i].forward.key, key) {
+			rank += current.levels[i].span
+			current = current.levels[i].forward
+		}
+		f.preds[i], f.ranks[i] = current, rank
+	}
+	return current.next()
+}
+
+// saveFinger moves the finger of s to update and rank, the last nodes
+// before a key at every level and their ranks, if s has one.
+func (s *SkipList) saveFinger(update []*node, rank []uint32) {
+	if s.finger != nil {
+		s.finger.preds = append(s.finger.preds[:0], update[:s.level()+1]...)
+		s.finger.ranks = append(s.finger.ranks[:0], rank[:s.level()+1]...)
+	}
+}
+
+// dropFinger resets the finger of s, if it has one, after s was
+// modified in a way that may have unlinked its nodes or changed their
+// ranks.
+func (s *SkipList) dropFinger() {
+	if s.finger != nil {
+		s.finger.preds, s.finger.ranks = s.finger.preds[:0], s.finger.ranks[:0]
+	}
+}
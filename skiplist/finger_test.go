@@ -0,0 +1,81 @@
+package skiplist
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestFingerSearch(t *testing.T) {
+	s := NewIntMap(WithFingerSearch(), WithShadowCheck(nil), WithAggregate(&sumMonoid))
+	want := map[int]int{}
+	r := rand.New(rand.NewSource(1))
+	base := 0
+	for i := 0; i < 5000; i++ {
+		// Clustered accesses, moving forward with occasional jumps back.
+		if r.Intn(100) == 0 {
+			base = r.Intn(1000)
+		}
+		base += r.Intn(3)
+		key := base % 1000
+		switch r.Intn(4) {
+		case 0, 1:
+			s.Set(key, i)
+			want[key] = i
+		case 2:
+			_, rank, ok := s.DeleteWithRank(key)
+			if _, present := want[key]; ok != present {
+				t.Fatalf("Delete(%d) returned %v.", key, ok)
+			}
+			if ok && rank != uint32(countLess(want, key)+1) {
+				t.Fatalf("Delete(%d) returned rank %d.", key, rank)
+			}
+			delete(want, key)
+		case 3:
+			v, ok := s.Get(key)
+			if w, present := want[key]; ok != present || ok && v != w {
+				t.Fatalf("Get(%d) returned %v, %v.", key, v, ok)
+			}
+		}
+		if i%1000 == 999 {
+			s.DeleteRange(base, base+50)
+			for k := range want {
+				if k >= base && k < base+50 {
+					delete(want, k)
+				}
+			}
+		}
+	}
+	if err := s.CheckShadow(); err != nil {
+		t.Fatalf("Finger search: %v.", err)
+	}
+	if s.Len() != len(want) {
+		t.Errorf("Expected %d elements, got %d.", len(want), s.Len())
+	}
+	for k := range want {
+		if rank := s.Rank(k); rank != uint32(countLess(want, k)+1) {
+			t.Errorf("Wrong rank %d for %d.", rank, k)
+		}
+	}
+
+	s.Clear()
+	for i := 10; i > 0; i-- {
+		s.Set(i, i)
+	}
+	if v, ok := s.Get(5); !ok || v != 5 || s.Len() != 10 {
+		t.Errorf("Finger search should be reset by Clear.")
+	}
+	if err := s.CheckShadow(); err != nil {
+		t.Errorf("Finger search after Clear: %v.", err)
+	}
+}
+
+// countLess returns the number of keys of m less than key.
+func countLess(m map[int]int, key int) int {
+	n := 0
+	for k := range m {
+		if k < key {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,48 @@
+package skiplist
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxFormattedKey is the length beyond which String truncates keys.
+const maxFormattedKey = 32
+
+// formatKey formats v with %v, truncated to maxFormattedKey bytes.
+func formatKey(v interface{}) string {
+	f := fmt.Sprintf("%v", v)
+	if len(f) > maxFormattedKey {
+		f = strings.ToValidUTF8(f[:maxFormattedKey], "") + "..."
+	}
+	return f
+}
+
+// String summarizes s as its length, level and first and last keys,
+// so that printing it with %v neither dumps all its elements nor just
+// pointers.
+func (s *SkipList) String() string {
+	first, _, ok := s.Min()
+	if !ok {
+		return fmt.Sprintf("SkipList{len: 0, level: %d}", s.level())
+	}
+	last, _, _ := s.Max()
+	return fmt.Sprintf("SkipList{len: %d, level: %d, first: %s, last: %s}", s.length, s.level(), formatKey(first), formatKey(last))
+}
+
+// String summarizes s like SkipList.String.
+func (s *Set) String() string {
+	return "Set" + strings.TrimPrefix(s.skiplist.String(), "SkipList")
+}
+
+// String summarizes z as its cardinality and its first and last
+// members, with their scores.
+func (z *ZSet) String() string {
+	first, firstKey, ok := z.sl.Min()
+	if !ok {
+		return "ZSet{card: 0}"
+	}
+	last, lastKey, _ := z.sl.Max()
+	return fmt.Sprintf("ZSet{card: %d, first: %s (%s), last: %s (%s)}", z.Card(),
+		formatKey(firstKey), formatKey(first.(*zsetScore).score),
+		formatKey(lastKey), formatKey(last.(*zsetScore).score))
+}
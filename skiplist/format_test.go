@@ -0,0 +1,38 @@
+package skiplist
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestString(t *testing.T) {
+	s := NewIntMap()
+	if got := fmt.Sprintf("%v", s); got != "SkipList{len: 0, level: 0}" {
+		t.Errorf("Wrong empty summary: %s.", got)
+	}
+	for i := 0; i < 1000; i++ {
+		s.Set(i, i)
+	}
+	if got, expected := fmt.Sprintf("%v", s), fmt.Sprintf("SkipList{len: 1000, level: %d, first: 0, last: 999}", s.level()); got != expected {
+		t.Errorf("Expected %s, got %s.", expected, got)
+	}
+
+	set := NewStringSet(WithMaxLevel(0))
+	set.Add(strings.Repeat("a", 100))
+	if got, expected := set.String(), "Set{len: 1, level: 0, first: "+strings.Repeat("a", 32)+"..., last: "+strings.Repeat("a", 32)+"...}"; got != expected {
+		t.Errorf("Expected %s, got %s.", expected, got)
+	}
+
+	zs := NewCustomZSet(func(l, r interface{}) bool {
+		return l.(float64) < r.(float64)
+	})
+	if got := zs.String(); got != "ZSet{card: 0}" {
+		t.Errorf("Wrong empty summary: %s.", got)
+	}
+	zs.Add("foo", 1.5)
+	zs.Add("bar", 3.0)
+	if got := fmt.Sprint(zs); got != "ZSet{card: 2, first: foo (1.5), last: bar (3)}" {
+		t.Errorf("Wrong summary: %s.", got)
+	}
+}
@@ -0,0 +1,83 @@
+package skiplist
+
+import "sync"
+
+// An Interner deduplicates string keys, so that equal keys set in one
+// or several lists (or set again after being deleted) share the same
+// backing storage. Keys of other types are left alone. It is safe for
+// concurrent use, so it can be shared by lists used from different
+// goroutines.
+//
+// Interned strings are kept until the Interner is discarded or Reset,
+// even if no list holds them anymore.
+type Interner struct {
+	mu      sync.Mutex
+	strings map[string]string
+	stats   InternStats
+}
+
+// InternStats describes the keys seen by an Interner.
+type InternStats struct {
+	// Strings is the number of distinct strings interned.
+	Strings int
+	// Hits is the number of keys replaced by an interned string, and
+	// BytesSaved the total length of those keys.
+	Hits       uint64
+	BytesSaved uint64
+}
+
+// NewInterner returns a new, empty Interner.
+func NewInterner() *Interner {
+	return &Interner{strings: make(map[string]string)}
+}
+
+// Intern returns the interned string equal to key if key is a string,
+// interning it first if needed, and key itself otherwise.
+func (in *Interner) Intern(key interface{}) interface{} {
+	str, ok := key.(string)
+	if !ok {
+		return key
+	}
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	if interned, ok := in.strings[str]; ok {
+		in.stats.Hits++
+		in.stats.BytesSaved += uint64(len(str))
+		return interned
+	}
+	in.strings[str] = str
+	in.stats.Strings++
+	return str
+}
+
+// Stats returns statistics about the keys interned so far.
+func (in *Interner) Stats() InternStats {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	return in.stats
+}
+
+// Reset forgets the interned strings and the statistics.
+func (in *Interner) Reset() {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	in.strings = make(map[string]string)
+	in.stats = InternStats{}
+}
+
+// WithKeyInterner makes the list intern the keys of new elements with
+// in, whether they are added by Set (and its variants) or in bulk.
+// Setting an existing key keeps the key already in the list.
+func WithKeyInterner(in *Interner) Option {
+	return func(s *SkipList) {
+		s.interner = in
+	}
+}
+
+// intern returns key interned by the interner of s, if any.
+func (s *SkipList) intern(key interface{}) interface{} {
+	if s.interner == nil {
+		return key
+	}
+	return s.interner.Intern(key)
+}
@@ -0,0 +1,42 @@
+package skiplist
+
+import (
+	"strings"
+	"testing"
+	"unsafe"
+)
+
+func TestInterner(t *testing.T) {
+	in := NewInterner()
+	a, b := NewStringMap(WithKeyInterner(in)), NewStringMap(WithKeyInterner(in))
+	key := func(i int) string {
+		// Build the key anew every time.
+		return strings.Repeat("k", 20) + string(rune('a'+i))
+	}
+	for i := 0; i < 10; i++ {
+		a.Set(key(i), i)
+		a.Set(key(i), -i)
+	}
+	b.FillBySortedSlice([][2]interface{}{{key(0), 0}, {key(1), 1}, {key(20), 20}})
+
+	stats := in.Stats()
+	if stats.Strings != 11 || stats.Hits != 2 || stats.BytesSaved != 42 {
+		t.Errorf("Wrong stats: %+v.", stats)
+	}
+	ka, _, _ := a.Min()
+	kb, _, _ := b.Min()
+	if unsafe.StringData(ka.(string)) != unsafe.StringData(kb.(string)) {
+		t.Errorf("Equal keys of both lists should share their storage.")
+	}
+	if v, _ := a.Get(key(3)); v != -3 {
+		t.Errorf("Expected -3, got %v.", v)
+	}
+	if in.Intern(42) != 42 {
+		t.Errorf("Keys other than strings should not be interned.")
+	}
+
+	in.Reset()
+	if stats := in.Stats(); stats.Strings != 0 || stats.Hits != 0 {
+		t.Errorf("Reset should clear the stats: %+v.", stats)
+	}
+}
@@ -0,0 +1,65 @@
+package skiplist
+
+import (
+	"sort"
+	"sync"
+)
+
+// FromBTree returns a new SkipList ordered by lessThan holding the
+// items of an ordered container, like a BTree from
+// github.com/google/btree. ascend must call iterator with every item
+// in ascending order, stopping if it returns false, and split must
+// return the key and value of an item. As items arrive sorted, the list
+// is bulk filled in O(n) time. For a btree.BTree the adapter looks
+// like:
+//
+//	s := skiplist.FromBTree(func(iterator func(item interface{}) bool) {
+//		tree.Ascend(func(i btree.Item) bool { return iterator(i) })
+//	}, func(item interface{}) (key, value interface{}) {
+//		e := item.(entry)
+//		return e.key, e.value
+//	}, lessThan)
+func FromBTree(ascend func(iterator func(item interface{}) bool), split func(item interface{}) (key, value interface{}), lessThan func(l, r interface{}) bool, opts ...Option) *SkipList {
+	var elements [][2]interface{}
+	ascend(func(item interface{}) bool {
+		key, value := split(item)
+		elements = append(elements, [2]interface{}{key, value})
+		return true
+	})
+	s := NewCustomMap(lessThan, opts...)
+	s.FillBySortedSlice(elements)
+	return s
+}
+
+// ToBTree calls insert with every key and value of s, in order. For a
+// btree.BTree insert would call tree.ReplaceOrInsert. Inserting sorted
+// items is the cheapest way to populate most ordered containers.
+func (s *SkipList) ToBTree(insert func(key, value interface{})) {
+	for n := s.header.next(); n != nil; n = n.next() {
+		insert(n.key, s.decode(n.value))
+	}
+}
+
+// FromSyncMap returns a new SkipList ordered by lessThan holding a
+// snapshot of the entries of m. Entries are sorted once and the list
+// is bulk filled, which is cheaper than setting them one by one.
+func FromSyncMap(m *sync.Map, lessThan func(l, r interface{}) bool, opts ...Option) *SkipList {
+	var elements [][2]interface{}
+	m.Range(func(key, value interface{}) bool {
+		elements = append(elements, [2]interface{}{key, value})
+		return true
+	})
+	sort.Slice(elements, func(i, j int) bool {
+		return lessThan(elements[i][0], elements[j][0])
+	})
+	s := NewCustomMap(lessThan, opts...)
+	s.FillBySortedSlice(elements)
+	return s
+}
+
+// ToSyncMap stores every key and value of s in m.
+func (s *SkipList) ToSyncMap(m *sync.Map) {
+	for n := s.header.next(); n != nil; n = n.next() {
+		m.Store(n.key, s.decode(n.value))
+	}
+}
@@ -0,0 +1,70 @@
+package skiplist
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func intLessThan(l, r interface{}) bool {
+	return l.(int) < r.(int)
+}
+
+func TestSyncMapInterop(t *testing.T) {
+	var m sync.Map
+	for i := 0; i < 100; i++ {
+		m.Store(i*7%100, i)
+	}
+	s := FromSyncMap(&m, intLessThan)
+	if s.Len() != 100 {
+		t.Fatalf("Expected 100 elements, got %d.", s.Len())
+	}
+	for i := 0; i < 100; i++ {
+		v, _ := m.Load(i)
+		s.check(t, i, v.(int))
+	}
+
+	var out sync.Map
+	s.ToSyncMap(&out)
+	seen := 0
+	out.Range(func(key, value interface{}) bool {
+		if v, _ := m.Load(key); v != value {
+			t.Errorf("Wrong value for key %v: %v.", key, value)
+		}
+		seen++
+		return true
+	})
+	if seen != 100 {
+		t.Errorf("Expected 100 entries in the sync.Map, got %d.", seen)
+	}
+}
+
+func TestBTreeInterop(t *testing.T) {
+	// The BTree adapter stands in for a btree.BTree.
+	tree := NewBTree()
+	for i := 0; i < 50; i++ {
+		tree.ReplaceOrInsert(btreePair{i * 2, strconv.Itoa(i)})
+	}
+	ascend := func(iterator func(item interface{}) bool) {
+		tree.Ascend(func(i Item) bool { return iterator(i) })
+	}
+	split := func(item interface{}) (key, value interface{}) {
+		e := item.(btreePair)
+		return e.key, e.value
+	}
+
+	s := FromBTree(ascend, split, intLessThan)
+	for i := 0; i < 50; i++ {
+		if value, _ := s.Get(i * 2); value != strconv.Itoa(i) {
+			t.Errorf("For key %v wanted value %v, got %v.", i*2, i, value)
+		}
+	}
+
+	back := NewBTree()
+	s.ToBTree(func(key, value interface{}) {
+		back.ReplaceOrInsert(btreePair{key.(int), value.(string)})
+	})
+	if back.Len() != 50 || back.Min() != (btreePair{0, "0"}) || back.Max() != (btreePair{98, "49"}) {
+		t.Errorf("ToBTree should insert all entries, got %d.", back.Len())
+	}
+}
@@ -0,0 +1,228 @@
+package skiplist
+
+import (
+	"math/rand"
+	"sync/atomic"
+)
+
+// lfLink is the pointer from a node of a LockFreeSkipList to its
+// successor at some level, together with the mark that logically
+// deletes the node. Links are immutable, so that the successor and the
+// mark can be swapped together with a single compare-and-swap.
+type lfLink struct {
+	node   *lfNode
+	marked bool
+}
+
+type lfNode struct {
+	key, value interface{}
+	next       []atomic.Pointer[lfLink]
+}
+
+// A LockFreeSkipList is a concurrent skip list for workloads with many
+// goroutines writing at once, like memtables, following the lock-free
+// design of Fraser and of Herlihy and Shavit. Insert, Delete, Contains
+// and Get are linearizable and never block; Contains and Get do not
+// even retry. It is a separate, simpler structure than SkipList: it
+// supports neither ranks nor options, and the value of a key cannot be
+// changed while it is present. For the full SkipList API with locking,
+// see SkipList.Synced.
+type LockFreeSkipList struct {
+	lessThan func(l, r interface{}) bool
+	head     *lfNode
+	// height is the highest level any node has ever had.
+	height atomic.Int32
+	length atomic.Int64
+}
+
+// NewLockFreeSkipList returns a new LockFreeSkipList that will use
+// lessThan as the comparison function.
+func NewLockFreeSkipList(lessThan func(l, r interface{}) bool) *LockFreeSkipList {
+	head := &lfNode{next: make([]atomic.Pointer[lfLink], DefaultMaxLevel+1)}
+	for i := range head.next {
+		head.next[i].Store(&lfLink{})
+	}
+	return &LockFreeSkipList{lessThan: lessThan, head: head}
+}
+
+// Len returns the number of keys in s.
+func (s *LockFreeSkipList) Len() int {
+	return int(s.length.Load())
+}
+
+// randomLevel returns a new random level, raising the height of s if
+// needed.
+func (s *LockFreeSkipList) randomLevel() int {
+	lvl := 0
+	for lvl < DefaultMaxLevel && rand.Float64() < p {
+		lvl++
+	}
+	for {
+		height := s.height.Load()
+		if int32(lvl) <= height || s.height.CompareAndSwap(height, int32(lvl)) {
+			return lvl
+		}
+	}
+}
+
+// find fills preds and succs with the last node whose key is less than
+// key and its successor at every level up to the height of s,
+// unlinking the marked nodes it meets on the way. It returns true if
+// key is present.
+func (s *LockFreeSkipList) find(key interface{}, preds, succs []*lfNode) bool {
+retry:
+	pred := s.head
+	var curr *lfNode
+	for level := int(s.height.Load()); level >= 0; level-- {
+		curr = pred.next[level].Load().node
+		for curr != nil {
+			succ := curr.next[level].Load()
+			for succ.marked {
+				// curr is deleted: help unlinking it.
+				link := pred.next[level].Load()
+				if link.marked || link.node != curr {
+					goto retry
+				}
+				if !pred.next[level].CompareAndSwap(link, &lfLink{node: succ.node}) {
+					goto retry
+				}
+				if curr = succ.node; curr == nil {
+					break
+				}
+				succ = curr.next[level].Load()
+			}
+			if curr == nil || !s.lessThan(curr.key, key) {
+				break
+			}
+			pred, curr = curr, succ.node
+		}
+		preds[level], succs[level] = pred, curr
+	}
+	return curr != nil && !s.lessThan(key, curr.key)
+}
+
+// Insert adds key with value to s. It returns false, leaving s
+// unchanged, if key is already present.
+func (s *LockFreeSkipList) Insert(key, value interface{}) bool {
+	if key == nil {
+		panic(ErrNilKey)
+	}
+	top := s.randomLevel()
+	preds := make([]*lfNode, DefaultMaxLevel+1)
+	succs := make([]*lfNode, DefaultMaxLevel+1)
+	for {
+		if s.find(key, preds, succs) {
+			return false
+		}
+		n := &lfNode{key: key, value: value, next: make([]atomic.Pointer[lfLink], top+1)}
+		for i := range n.next {
+			n.next[i].Store(&lfLink{node: succs[i]})
+		}
+		// Linking the bottom level makes n part of s.
+		link := preds[0].next[0].Load()
+		if link.marked || link.node != succs[0] || !preds[0].next[0].CompareAndSwap(link, &lfLink{node: n}) {
+			continue
+		}
+		s.length.Add(1)
+		for i := 1; i <= top; i++ {
+			for {
+				own := n.next[i].Load()
+				if own.marked {
+					// n is already being deleted.
+					return true
+				}
+				if own.node != succs[i] && !n.next[i].CompareAndSwap(own, &lfLink{node: succs[i]}) {
+					continue
+				}
+				link := preds[i].next[i].Load()
+				if !link.marked && link.node == succs[i] && preds[i].next[i].CompareAndSwap(link, &lfLink{node: n}) {
+					break
+				}
+				s.find(key, preds, succs)
+			}
+		}
+		return true
+	}
+}
+
+// Delete removes key from s. It returns false if key was not present
+// (or was concurrently deleted by another goroutine).
+func (s *LockFreeSkipList) Delete(key interface{}) bool {
+	preds := make([]*lfNode, DefaultMaxLevel+1)
+	succs := make([]*lfNode, DefaultMaxLevel+1)
+	if !s.find(key, preds, succs) {
+		return false
+	}
+	victim := succs[0]
+	for i := len(victim.next) - 1; i >= 1; i-- {
+		for link := victim.next[i].Load(); !link.marked; link = victim.next[i].Load() {
+			victim.next[i].CompareAndSwap(link, &lfLink{node: link.node, marked: true})
+		}
+	}
+	for {
+		link := victim.next[0].Load()
+		if link.marked {
+			return false
+		}
+		if victim.next[0].CompareAndSwap(link, &lfLink{node: link.node, marked: true}) {
+			s.length.Add(-1)
+			// Unlink the victim.
+			s.find(key, preds, succs)
+			return true
+		}
+	}
+}
+
+// search returns the node with key, or nil, without modifying s.
+func (s *LockFreeSkipList) search(key interface{}) *lfNode {
+	pred := s.head
+	var curr *lfNode
+	for level := int(s.height.Load()); level >= 0; level-- {
+		curr = pred.next[level].Load().node
+		for curr != nil {
+			succ := curr.next[level].Load()
+			for succ.marked {
+				if curr = succ.node; curr == nil {
+					break
+				}
+				succ = curr.next[level].Load()
+			}
+			if curr == nil || !s.lessThan(curr.key, key) {
+				break
+			}
+			pred, curr = curr, succ.node
+		}
+	}
+	if curr != nil && !s.lessThan(key, curr.key) {
+		return curr
+	}
+	return nil
+}
+
+// Contains returns true if key is present in s.
+func (s *LockFreeSkipList) Contains(key interface{}) bool {
+	return s.search(key) != nil
+}
+
+// Get returns the value associated with key in s. The second return
+// value is true when the key is present.
+func (s *LockFreeSkipList) Get(key interface{}) (value interface{}, ok bool) {
+	if n := s.search(key); n != nil {
+		return n.value, true
+	}
+	return nil, false
+}
+
+// Range calls fn for the elements of s in order, until fn returns
+// false. The iteration is weakly consistent: it sees every element
+// present throughout the call, and may or may not see elements
+// inserted or deleted concurrently.
+func (s *LockFreeSkipList) Range(fn func(key, value interface{}) bool) {
+	for n := s.head.next[0].Load().node; n != nil; {
+		link := n.next[0].Load()
+		if !link.marked && !fn(n.key, n.value) {
+			return
+		}
+		n = link.node
+	}
+}
@@ -0,0 +1,74 @@
+package skiplist
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestLockFreeSkipList(t *testing.T) {
+	s := NewLockFreeSkipList(intLessThan)
+	if !s.Insert(2, "two") || !s.Insert(1, "one") || s.Insert(2, "again") {
+		t.Errorf("Insert should only succeed for absent keys.")
+	}
+	if v, ok := s.Get(2); !ok || v != "two" || s.Contains(3) || s.Len() != 2 {
+		t.Errorf("Wrong contents: %v, %v.", v, ok)
+	}
+	if !s.Delete(1) || s.Delete(1) || s.Contains(1) || s.Len() != 1 {
+		t.Errorf("Delete should only succeed for present keys.")
+	}
+}
+
+func TestLockFreeSkipListConcurrent(t *testing.T) {
+	s := NewLockFreeSkipList(intLessThan)
+	const n, workers = 2000, 8
+	var wg sync.WaitGroup
+	var inserted, deleted [workers]int
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			// Every worker inserts all keys, racing with the others,
+			// then deletes the odd ones.
+			for i := 0; i < n; i++ {
+				if s.Insert(i, i) {
+					inserted[w]++
+				}
+			}
+			for i := 1; i < n; i += 2 {
+				if s.Delete(i) {
+					deleted[w]++
+				}
+				s.Contains(i)
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	var totalInserted, totalDeleted int
+	for w := 0; w < workers; w++ {
+		totalInserted += inserted[w]
+		totalDeleted += deleted[w]
+	}
+	if totalInserted-totalDeleted != n/2 {
+		t.Errorf("Got %d successful inserts and %d deletes, expected %d keys to remain.", totalInserted, totalDeleted, n/2)
+	}
+	if s.Len() != n/2 {
+		t.Errorf("Expected %d keys, got %d.", n/2, s.Len())
+	}
+	next := 0
+	s.Range(func(key, value interface{}) bool {
+		if key != next || value != next {
+			t.Fatalf("Wrong element %v, expected %d.", key, next)
+		}
+		next += 2
+		return true
+	})
+	if next != n {
+		t.Errorf("Range stopped at %d.", next)
+	}
+	for i := 0; i < n; i++ {
+		if s.Contains(i) != (i%2 == 0) {
+			t.Errorf("Unexpected presence of %d.", i)
+		}
+	}
+}
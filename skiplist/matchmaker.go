@@ -0,0 +1,84 @@
+package skiplist
+
+import "math"
+
+// A Matchmaker finds, for a member of a ZSet, the members whose scores
+// are within a distance of its own, like the players of a similar
+// rating to match against. The scores must be numeric.
+type Matchmaker struct {
+	z     *ZSet
+	delta float64
+}
+
+// NewMatchmaker returns a Matchmaker matching the members of z whose
+// scores differ by at most delta.
+func NewMatchmaker(z *ZSet, delta float64) *Matchmaker {
+	return &Matchmaker{z: z, delta: delta}
+}
+
+// Candidates returns the members whose scores are within the delta of
+// m from the score of member, nearest first, excluding member itself.
+// At most limit members are scanned, starting from the nearest ones,
+// so that a crowded band costs O(log(n) + limit) time; a limit of 0 or
+// less scans the whole band. It returns nil if member is not in the
+// ZSet.
+func (m *Matchmaker) Candidates(member interface{}, limit int) []interface{} {
+	zs, ok := m.z.key2Score[member]
+	if !ok {
+		return nil
+	}
+	n := m.z.sl.getLowerBound(m.z.sl.header, zs)
+	score := scoreFloat(zs.score)
+	// distance returns the distance of the score of x from score, or
+	// +Inf if x is outside of the band.
+	distance := func(x *node) float64 {
+		if x == nil || x == m.z.sl.header {
+			return math.Inf(1)
+		}
+		if d := math.Abs(scoreFloat(x.key.(*zsetScore).score) - score); d <= m.delta {
+			return d
+		}
+		return math.Inf(1)
+	}
+
+	var members []interface{}
+	// Walk away from member on both sides, taking the nearest of the
+	// two next members each time.
+	lower, upper := n.previous(), n.next()
+	dl, du := distance(lower), distance(upper)
+	for limit <= 0 || len(members) < limit {
+		if math.IsInf(dl, 1) && math.IsInf(du, 1) {
+			break
+		}
+		if dl <= du {
+			members = append(members, lower.value)
+			lower = lower.previous()
+			dl = distance(lower)
+		} else {
+			members = append(members, upper.value)
+			upper = upper.next()
+			du = distance(upper)
+		}
+	}
+	return members
+}
+
+// Sample returns up to k members picked at random, without
+// replacement, among the Candidates of member scanned within limit,
+// for matches that should not always pair the same members. It uses
+// the random source of the ZSet's skip list.
+func (m *Matchmaker) Sample(member interface{}, k, limit int) []interface{} {
+	if k <= 0 {
+		return nil
+	}
+	members := m.Candidates(member, limit)
+	if k > len(members) {
+		k = len(members)
+	}
+	// A partial Fisher-Yates shuffle.
+	for i := 0; i < k; i++ {
+		j := i + int(m.z.sl.random()*float64(len(members)-i))
+		members[i], members[j] = members[j], members[i]
+	}
+	return members[:k]
+}
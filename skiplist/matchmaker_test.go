@@ -0,0 +1,48 @@
+package skiplist
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMatchmaker(t *testing.T) {
+	z := NewCustomZSet(func(l, r interface{}) bool {
+		return l.(int) < r.(int)
+	})
+	for i, rating := range []int{1400, 1480, 1490, 1500, 1500, 1520, 1560, 1700} {
+		z.Add(i, rating)
+	}
+	m := NewMatchmaker(z, 50)
+	if got := m.Candidates(3, 0); !reflect.DeepEqual(got, []interface{}{4, 2, 1, 5}) {
+		t.Errorf("candidates perform wrong: %v", got)
+	}
+	if got := m.Candidates(3, 2); !reflect.DeepEqual(got, []interface{}{4, 2}) {
+		t.Errorf("candidates perform wrong with a limit: %v", got)
+	}
+	if got := m.Candidates(0, 0); len(got) != 0 {
+		t.Errorf("candidates perform wrong at the start: %v", got)
+	}
+	if got := m.Candidates(7, 0); len(got) != 0 {
+		t.Errorf("candidates perform wrong at the end: %v", got)
+	}
+	if got := m.Candidates(100, 0); got != nil {
+		t.Errorf("candidates perform wrong for a missing member: %v", got)
+	}
+
+	seen := map[interface{}]bool{}
+	for i := 0; i < 100; i++ {
+		sample := m.Sample(3, 2, 0)
+		if len(sample) != 2 || sample[0] == sample[1] {
+			t.Fatalf("sample perform wrong: %v", sample)
+		}
+		for _, member := range sample {
+			seen[member] = true
+		}
+	}
+	if !reflect.DeepEqual(seen, map[interface{}]bool{1: true, 2: true, 4: true, 5: true}) {
+		t.Errorf("sample perform wrong: %v", seen)
+	}
+	if got := m.Sample(3, 10, 0); len(got) != 4 {
+		t.Errorf("sample perform wrong with k above the candidates: %v", got)
+	}
+}
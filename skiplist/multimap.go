@@ -0,0 +1,169 @@
+package skiplist
+
+import "math"
+
+// A SkipMultiMap is an ordered map in which a key can have several
+// values, like events keyed by timestamp. Set always adds a new
+// element, and the elements of equal keys are kept in the order they
+// were added.
+type SkipMultiMap struct {
+	sl *SkipList
+	// counter orders the elements of equal keys; 0 and math.MaxUint64
+	// are reserved for the bounds of searches.
+	counter uint64
+}
+
+// multiMapKey is the key of an element of a SkipMultiMap in its
+// SkipList.
+type multiMapKey struct {
+	key     interface{}
+	counter uint64
+}
+
+// multiMapKeyLessThan returns the comparison function of multiMapKey
+// keys, ordered by key, then by counter.
+func multiMapKeyLessThan(lessThan func(l, r interface{}) bool) func(l, r interface{}) bool {
+	return func(l, r interface{}) bool {
+		lmk := l.(multiMapKey)
+		rmk := r.(multiMapKey)
+		if lessThan(lmk.key, rmk.key) {
+			return true
+		} else if lessThan(rmk.key, lmk.key) {
+			return false
+		} else {
+			return lmk.counter < rmk.counter
+		}
+	}
+}
+
+// NewMultiMap returns a new SkipMultiMap. Its keys must implement the
+// Ordered interface.
+func NewMultiMap(opts ...Option) *SkipMultiMap {
+	return NewCustomMultiMap(func(l, r interface{}) bool {
+		return l.(Ordered).LessThan(r.(Ordered))
+	}, opts...)
+}
+
+// NewCustomMultiMap returns a new SkipMultiMap that will use lessThan as
+// the comparison function of its keys. Options applying to keys, like
+// WithBloomFilter, are not supported.
+func NewCustomMultiMap(lessThan func(l, r interface{}) bool, opts ...Option) *SkipMultiMap {
+	return &SkipMultiMap{sl: NewCustomMap(multiMapKeyLessThan(lessThan), opts...)}
+}
+
+// NewIntMultiMap returns a new SkipMultiMap that accepts int keys.
+func NewIntMultiMap(opts ...Option) *SkipMultiMap {
+	return NewCustomMultiMap(func(l, r interface{}) bool {
+		return l.(int) < r.(int)
+	}, opts...)
+}
+
+// NewStringMultiMap returns a new SkipMultiMap that accepts string keys.
+func NewStringMultiMap(opts ...Option) *SkipMultiMap {
+	return NewCustomMultiMap(func(l, r interface{}) bool {
+		return l.(string) < r.(string)
+	}, opts...)
+}
+
+// Len returns the number of elements in m, counting every value of
+// every key.
+func (m *SkipMultiMap) Len() int {
+	return m.sl.Len()
+}
+
+// Clear removes all the elements of m.
+func (m *SkipMultiMap) Clear() {
+	m.sl.Clear()
+	m.counter = 0
+}
+
+// Set adds an element with key and value to m, after the elements
+// already there with an equal key.
+func (m *SkipMultiMap) Set(key, value interface{}) {
+	if key == nil {
+		panic(ErrNilKey)
+	}
+	m.counter++
+	m.sl.Set(multiMapKey{key, m.counter}, value)
+}
+
+// first returns the first node of key, or nil if it has none.
+func (m *SkipMultiMap) first(key interface{}) *node {
+	n := m.sl.getLowerBound(m.sl.header, multiMapKey{key, 0})
+	if n == nil || !m.sl.lessThan(n.key, multiMapKey{key, math.MaxUint64}) {
+		return nil
+	}
+	return n
+}
+
+// Get returns the values of key, in the order they were added, or nil
+// if key is not in m.
+func (m *SkipMultiMap) Get(key interface{}) (values []interface{}) {
+	last := multiMapKey{key, math.MaxUint64}
+	for n := m.first(key); n != nil && m.sl.lessThan(n.key, last); n = n.next() {
+		values = append(values, m.sl.decode(n.value))
+	}
+	return values
+}
+
+// Contains returns true if key has at least one value in m.
+func (m *SkipMultiMap) Contains(key interface{}) bool {
+	return m.first(key) != nil
+}
+
+// Count returns the number of values of key, in O(log n).
+func (m *SkipMultiMap) Count(key interface{}) int {
+	return int(m.sl.countLess(multiMapKey{key, math.MaxUint64}) - m.sl.countLess(multiMapKey{key, 0}))
+}
+
+// Delete removes the first element of m with key and value, comparing
+// values with ==, and returns true if there was one. Values of
+// uncomparable types, like slices and maps, are never equal; use
+// DeleteFunc to remove them.
+func (m *SkipMultiMap) Delete(key, value interface{}) bool {
+	return m.DeleteFunc(key, func(v interface{}) bool {
+		return valuesEqual(v, value)
+	})
+}
+
+// DeleteFunc removes the first element of m with key whose value
+// satisfies match, and returns true if there was one.
+func (m *SkipMultiMap) DeleteFunc(key interface{}, match func(value interface{}) bool) bool {
+	last := multiMapKey{key, math.MaxUint64}
+	for n := m.first(key); n != nil && m.sl.lessThan(n.key, last); n = n.next() {
+		if match(m.sl.decode(n.value)) {
+			m.sl.Delete(n.key)
+			return true
+		}
+	}
+	return false
+}
+
+// valuesEqual returns a == b, or false if comparing them panics because
+// they are of the same uncomparable type.
+func valuesEqual(a, b interface{}) (equal bool) {
+	defer func() {
+		recover()
+	}()
+	return a == b
+}
+
+// DeleteAll removes all the values of key and returns how many there
+// were.
+func (m *SkipMultiMap) DeleteAll(key interface{}) int {
+	return m.sl.DeleteRange(multiMapKey{key, 0}, multiMapKey{key, math.MaxUint64})
+}
+
+// All returns an iterator (an iter.Seq2, for use with range) over the
+// keys and values of m, in key order, the values of equal keys being
+// in the order they were added. m must not be modified during the
+// iteration.
+func (m *SkipMultiMap) All() func(yield func(key, value interface{}) bool) {
+	return func(yield func(key, value interface{}) bool) {
+		for n := m.sl.header.next(); n != nil; n = n.next() {
+			if !yield(n.key.(multiMapKey).key, m.sl.decode(n.value)) {
+				return
+			}
+		}
+	}
+}
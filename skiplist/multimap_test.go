@@ -0,0 +1,66 @@
+package skiplist
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMultiMap(t *testing.T) {
+	m := NewIntMultiMap(WithShadowCheck(nil))
+	for i := 0; i < 30; i++ {
+		m.Set(i%3, i)
+	}
+	m.Set(10, "x")
+	if m.Len() != 31 || m.Count(1) != 10 || m.Count(5) != 0 {
+		t.Errorf("Wrong counts: %d elements, %d for 1.", m.Len(), m.Count(1))
+	}
+	if values := m.Get(2); !reflect.DeepEqual(values, []interface{}{2, 5, 8, 11, 14, 17, 20, 23, 26, 29}) {
+		t.Errorf("Values should be in insertion order, got %v.", values)
+	}
+	if m.Get(5) != nil || m.Contains(5) || !m.Contains(10) {
+		t.Errorf("Unexpected values for missing key 5.")
+	}
+
+	if !m.Delete(1, 13) || m.Delete(1, 13) || m.Delete(1, 12) {
+		t.Errorf("Delete should remove existing (key, value) pairs only.")
+	}
+	m.Set(1, 4)
+	if !m.Delete(1, 4) {
+		t.Errorf("Delete(1, 4) should succeed.")
+	}
+	if values := m.Get(1); !reflect.DeepEqual(values, []interface{}{1, 7, 10, 16, 19, 22, 25, 28, 4}) {
+		t.Errorf("Delete should remove the first matching element, got %v.", values)
+	}
+	m.Set(3, []int{1})
+	m.Set(3, map[int]int{})
+	m.Set(3, []int{2})
+	if m.Delete(3, []int{2}) || m.Delete(3, map[int]int{}) || m.Count(3) != 3 {
+		t.Errorf("Delete should treat uncomparable values as unequal.")
+	}
+	if !m.DeleteFunc(3, func(value interface{}) bool {
+		v, ok := value.([]int)
+		return ok && v[0] == 2
+	}) || !reflect.DeepEqual(m.Get(3), []interface{}{[]int{1}, map[int]int{}}) {
+		t.Errorf("DeleteFunc should remove the first matching element, got %v.", m.Get(3))
+	}
+	m.DeleteAll(3)
+
+	if n := m.DeleteAll(0); n != 10 || m.Contains(0) || m.Len() != 20 {
+		t.Errorf("DeleteAll removed %d elements, %d left.", n, m.Len())
+	}
+	var keys []interface{}
+	for key := range m.All() {
+		keys = append(keys, key)
+	}
+	if len(keys) != 20 || keys[0] != 1 || keys[8] != 1 || keys[9] != 2 || keys[19] != 10 {
+		t.Errorf("Wrong iteration order: %v.", keys)
+	}
+	if err := m.sl.CheckShadow(); err != nil {
+		t.Errorf("MultiMap: %v.", err)
+	}
+
+	m.Clear()
+	if m.Len() != 0 || m.Count(2) != 0 {
+		t.Errorf("Clear should remove all elements.")
+	}
+}
@@ -0,0 +1,161 @@
+package skiplist
+
+import "math"
+
+// A MultiZSet is a set of members ranked under several independent
+// scores, or dimensions, like the kills, wins and playtime of players.
+// Each dimension is ordered like a ZSet, but the members are stored
+// once, so that adding or removing a member updates all the dimensions
+// together and they cannot drift apart as separate ZSets could.
+type MultiZSet struct {
+	key2Scores     map[interface{}][]*zsetScore
+	dims           []*SkipList
+	scoreLessThans []func(l, r interface{}) bool
+	pool           *zsetScorePool
+}
+
+// NewMultiZSet returns a new MultiZSet with a dimension for each of
+// scoreLessThans, which orders its scores. Dimensions are numbered from
+// 0, in the order of scoreLessThans.
+func NewMultiZSet(scoreLessThans ...func(l, r interface{}) bool) *MultiZSet {
+	m := &MultiZSet{
+		key2Scores:     make(map[interface{}][]*zsetScore),
+		scoreLessThans: scoreLessThans,
+		pool:           newzsetScorePool(128),
+	}
+	for _, scoreLessThan := range scoreLessThans {
+		m.dims = append(m.dims, NewCustomMap(zsetScoreLessThan(scoreLessThan)))
+	}
+	return m
+}
+
+// Dims returns the number of dimensions of m.
+func (m *MultiZSet) Dims() int {
+	return len(m.dims)
+}
+
+// Add sets the scores of key, one per dimension, adding key to m if it
+// is not a member yet. It panics if the number of scores is not the
+// number of dimensions.
+func (m *MultiZSet) Add(key interface{}, scores ...interface{}) {
+	if len(scores) != len(m.dims) {
+		panic("goskiplist: wrong number of scores for MultiZSet")
+	}
+	curZScores, ok := m.key2Scores[key]
+	if !ok {
+		curZScores = make([]*zsetScore, len(m.dims))
+		m.key2Scores[key] = curZScores
+	}
+	for dim, score := range scores {
+		m.setScore(dim, key, curZScores, score)
+	}
+}
+
+// setScore sets the score of key in dim, curZScores being its scores.
+func (m *MultiZSet) setScore(dim int, key interface{}, curZScores []*zsetScore, score interface{}) {
+	if curZScore := curZScores[dim]; curZScore != nil {
+		scoreLessThan := m.scoreLessThans[dim]
+		if !scoreLessThan(score, curZScore.score) && !scoreLessThan(curZScore.score, score) {
+			return
+		}
+		m.dims[dim].Delete(curZScore)
+		m.pool.Put(curZScore)
+	}
+	zScore := m.pool.Get(score)
+	curZScores[dim] = zScore
+	m.dims[dim].Set(zScore, key)
+}
+
+// Update sets the score of key in dimension dim only. It returns false
+// if key is not a member.
+func (m *MultiZSet) Update(dim int, key interface{}, score interface{}) bool {
+	curZScores, ok := m.key2Scores[key]
+	if !ok {
+		return false
+	}
+	m.setScore(dim, key, curZScores, score)
+	return true
+}
+
+// Remove removes key from every dimension. It returns false if key is
+// not a member.
+func (m *MultiZSet) Remove(key interface{}) bool {
+	curZScores, ok := m.key2Scores[key]
+	if !ok {
+		return false
+	}
+	for dim, curZScore := range curZScores {
+		m.dims[dim].Delete(curZScore)
+		m.pool.Put(curZScore)
+	}
+	delete(m.key2Scores, key)
+	return true
+}
+
+// Exists returns true if key is a member of m.
+func (m *MultiZSet) Exists(key interface{}) bool {
+	_, ok := m.key2Scores[key]
+	return ok
+}
+
+// Card returns the number of members of m.
+func (m *MultiZSet) Card() int {
+	return len(m.key2Scores)
+}
+
+// Score returns the score of key in dimension dim, or nil if key is
+// not a member.
+func (m *MultiZSet) Score(dim int, key interface{}) interface{} {
+	curZScores, ok := m.key2Scores[key]
+	if !ok {
+		return nil
+	}
+	return curZScores[dim].score
+}
+
+// RankIn returns the rank of key in dimension dim, starting from 1, or
+// 0 if key is not a member.
+func (m *MultiZSet) RankIn(dim int, key interface{}) uint32 {
+	curZScores, ok := m.key2Scores[key]
+	if !ok {
+		return 0
+	}
+	return m.dims[dim].Rank(curZScores[dim])
+}
+
+// RangeByRank is like ZSet.RangeByRank in dimension dim: it returns the
+// members ranked between rankFrom and rankTo, both included, with their
+// scores in dim.
+func (m *MultiZSet) RangeByRank(dim int, rankFrom uint32, rankTo uint32) [][2]interface{} {
+	sl := m.dims[dim]
+	if rankTo > uint32(sl.Len()) {
+		rankTo = uint32(sl.Len())
+	}
+	if rankFrom == 0 {
+		rankFrom = 1
+	}
+	if rankTo < rankFrom {
+		return nil
+	}
+
+	keys := make([][2]interface{}, 0, int(rankTo-rankFrom+1))
+	n := sl.nodeByRank(rankFrom)
+	for i := rankFrom; i <= rankTo; i++ {
+		keys = append(keys, [2]interface{}{n.value, n.key.(*zsetScore).score})
+		n = n.next()
+	}
+	return keys
+}
+
+// RangeByScore is like ZSet.RangeByScore in dimension dim: it returns
+// the members whose scores in dim are between scoreFrom and scoreTo,
+// both included.
+func (m *MultiZSet) RangeByScore(dim int, scoreFrom interface{}, scoreTo interface{}) []interface{} {
+	sl := m.dims[dim]
+	var keys []interface{}
+	to := &zsetScore{score: scoreTo, counter: math.MaxInt64}
+	for n := sl.getLowerBound(sl.header, &zsetScore{score: scoreFrom}); n != nil && !sl.lessThan(to, n.key); n = n.next() {
+		keys = append(keys, n.value)
+	}
+	return keys
+}
@@ -0,0 +1,54 @@
+package skiplist
+
+import "testing"
+
+func TestMultiZSet(t *testing.T) {
+	greater := func(l, r interface{}) bool {
+		return l.(int) > r.(int)
+	}
+	const (
+		kills = iota
+		wins
+	)
+	m := NewMultiZSet(greater, greater)
+	m.Add("foo", 10, 1)
+	m.Add("bar", 5, 3)
+	m.Add("baz", 7, 2)
+	if m.Card() != 3 || m.Dims() != 2 {
+		t.Fatalf("multi zset add perform wrong: %d members", m.Card())
+	}
+	if m.RankIn(kills, "foo") != 1 || m.RankIn(wins, "foo") != 3 || m.RankIn(kills, "qux") != 0 {
+		t.Errorf("multi zset rank in perform wrong")
+	}
+
+	if !m.Update(wins, "foo", 4) || m.Update(wins, "qux", 1) {
+		t.Errorf("multi zset update perform wrong")
+	}
+	if m.RankIn(wins, "foo") != 1 || m.RankIn(kills, "foo") != 1 || m.Score(wins, "foo") != 4 {
+		t.Errorf("multi zset update perform wrong: %v", m.RangeByRank(wins, 1, 3))
+	}
+	m.Add("bar", 20, 3)
+	if m.RankIn(kills, "bar") != 1 || m.RankIn(wins, "bar") != 2 {
+		t.Errorf("multi zset add perform wrong: %v", m.RangeByRank(kills, 1, 3))
+	}
+
+	ranked := m.RangeByRank(kills, 2, 10)
+	if len(ranked) != 2 || ranked[0] != [2]interface{}{"foo", 10} || ranked[1] != [2]interface{}{"baz", 7} {
+		t.Errorf("multi zset range by rank perform wrong: %v", ranked)
+	}
+	if keys := m.RangeByScore(wins, 3, 2); len(keys) != 2 || keys[0] != "bar" || keys[1] != "baz" {
+		t.Errorf("multi zset range by score perform wrong: %v", keys)
+	}
+
+	if !m.Remove("foo") || m.Remove("foo") || m.Exists("foo") {
+		t.Errorf("multi zset remove perform wrong")
+	}
+	for dim := 0; dim < m.Dims(); dim++ {
+		if m.dims[dim].Len() != 2 {
+			t.Errorf("multi zset remove perform wrong: %d elements in dimension %d", m.dims[dim].Len(), dim)
+		}
+	}
+	if m.Score(kills, "foo") != nil {
+		t.Errorf("multi zset score of a removed member should be nil")
+	}
+}
@@ -0,0 +1,207 @@
+package skiplist
+
+import (
+	"math"
+	"math/rand"
+)
+
+// An Option configures a SkipList (or the SkipList underlying a Set)
+// at construction.
+type Option func(*SkipList)
+
+// NewWithOptions returns a new SkipList configured by opts. Unless
+// WithComparator is given, its keys must implement the Ordered
+// interface.
+//
+// Unless WithMaxLevel is given, MaxLevel is DefaultMaxLevel, or
+// MaxLevelForSize of the size given to WithLevelGrowthHint.
+//
+// Setting all the parameters at construction is preferable to
+// modifying the MaxLevel field afterwards, which is not safe if the
+// list is already shared with other goroutines.
+func NewWithOptions(opts ...Option) *SkipList {
+	s := &SkipList{
+		lessThan: func(l, r interface{}) bool {
+			return l.(Ordered).LessThan(r.(Ordered))
+		},
+		MaxLevel: -1,
+		p:        p,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.MaxLevel < 0 {
+		s.MaxLevel = DefaultMaxLevel
+		if s.expectedSize > 0 {
+			s.MaxLevel = maxLevelForSize(s.expectedSize, s.p)
+		}
+	}
+	if s.profile != nil {
+		s.profile.wrap(s)
+	}
+	s.levelHint = maxInt(s.levelHint, levelForSize(s.expectedSize, s.p))
+	s.Clear()
+	return s
+}
+
+// WithComparator makes the list use lessThan as the comparison
+// function. lessThan should define a linear order on keys you intend
+// to use with the SkipList.
+func WithComparator(lessThan func(l, r interface{}) bool) Option {
+	return func(s *SkipList) {
+		s.lessThan = lessThan
+	}
+}
+
+// WithMaxLevel sets the MaxLevel of the list (DefaultMaxLevel if not
+// given).
+func WithMaxLevel(maxLevel int) Option {
+	return func(s *SkipList) {
+		s.MaxLevel = maxLevel
+	}
+}
+
+// WithP sets the fraction of nodes with level i pointers that also
+// have level i+1 pointers (1/4 if not given). It should be between 0
+// and 1; smaller values save space, larger ones make running times
+// less variable.
+func WithP(p float64) Option {
+	return func(s *SkipList) {
+		s.p = p
+	}
+}
+
+// WithRandSource makes the list draw the levels of its nodes from src
+// instead of the global source of math/rand. A seeded source makes the
+// shape of the list reproducible. The list does not synchronize access
+// to src, so it should not be shared with other goroutines.
+func WithRandSource(src rand.Source) Option {
+	return func(s *SkipList) {
+		s.rand = rand.New(src)
+	}
+}
+
+// WithNodeBlockSize makes the list allocate its nodes n at a time,
+// reducing the number of allocations when inserting many elements. The
+// memory of a block is only reclaimed once all its nodes have been
+// deleted, so this is best suited to lists which mostly grow.
+func WithNodeBlockSize(n int) Option {
+	return func(s *SkipList) {
+		s.nodeBlockSize = n
+	}
+}
+
+// WithInitialLevel preallocates room for n levels above the bottom one
+// in the header and in the scratch vectors used by Set and Delete, so
+// that a list growing up to that level does not have to reallocate
+// them.
+func WithInitialLevel(n int) Option {
+	return func(s *SkipList) {
+		s.levelHint = n
+	}
+}
+
+// WithLevelGrowthHint is like WithInitialLevel, deriving the number of
+// levels from the number of elements the list is expected to hold. It
+// also caps the level of the list to MaxLevelForSize(expectedSize),
+// unless WithMaxLevel is given.
+func WithLevelGrowthHint(expectedSize int) Option {
+	return func(s *SkipList) {
+		s.expectedSize = expectedSize
+	}
+}
+
+// WithAdaptiveMaxLevel makes the maximum level of the list follow its
+// length: new nodes are capped to MaxLevelForSize of the length the
+// list reaches, so that small lists stay shallow and large ones gain
+// levels as they grow past powers of 1/p, without tuning MaxLevel for
+// the largest expected size. MaxLevel still bounds the level, and
+// defaults to DefaultMaxLevel. RebuildOptimal lowers the level of lists
+// that shrank.
+func WithAdaptiveMaxLevel() Option {
+	return func(s *SkipList) {
+		s.adaptive = true
+	}
+}
+
+// MaxLevelForSize returns a MaxLevel suitable for a list holding up to
+// n elements with the default promotion probability: a couple of
+// levels above the expected one, so that searches stay logarithmic
+// without over-allocating small lists.
+func MaxLevelForSize(n int) int {
+	return maxLevelForSize(n, p)
+}
+
+func maxLevelForSize(n int, p float64) int {
+	return levelForSize(n, p) + 2
+}
+
+// levelForSize returns the level a skip list holding n elements is
+// expected to reach, that is log base 1/p of n.
+func levelForSize(n int, p float64) int {
+	if n <= 1 {
+		return 0
+	}
+	return int(math.Ceil(math.Log(float64(n)) / math.Log(1/p)))
+}
+
+// checkShapeOptions panics if s was configured with options other than
+// those shaping its levels (WithMaxLevel, WithP, WithRandSource,
+// WithNodeBlockSize, WithInitialLevel, WithLevelGrowthHint and
+// WithAdaptiveMaxLevel), for the containers that only support those.
+func (s *SkipList) checkShapeOptions(container string) {
+	if s.filter != nil || s.augment != nil || s.shadow != nil || s.quota != nil ||
+		s.profile != nil || s.codec != nil || s.interner != nil || s.finger != nil {
+		panic("goskiplist: " + container + " only supports the options shaping the levels of the list")
+	}
+}
+
+// shapeOptions returns the comparison function of s and the options
+// shaping its levels, the part of options that does not affect the
+// elements.
+func (s *SkipList) shapeOptions() []Option {
+	lessThan := s.lessThan
+	if s.profile != nil {
+		lessThan = s.profile.lessThan
+	}
+	opts := []Option{
+		WithComparator(lessThan),
+		WithMaxLevel(s.MaxLevel),
+		WithP(s.p),
+		WithNodeBlockSize(s.nodeBlockSize),
+		WithInitialLevel(s.levelHint),
+	}
+	if s.adaptive {
+		opts = append(opts, WithAdaptiveMaxLevel())
+	}
+	return opts
+}
+
+// options returns the options s was configured with, so that lists
+// derived from s (see CopyRange) behave the same. The random source is
+// not shared, as the lists may be used from different goroutines.
+func (s *SkipList) options() []Option {
+	opts := s.shapeOptions()
+	if s.interner != nil {
+		opts = append(opts, WithKeyInterner(s.interner))
+	}
+	if s.codec != nil {
+		opts = append(opts, WithValueCodec(s.codec.encode, s.codec.decode))
+	}
+	if s.filter != nil {
+		opts = append(opts, WithBloomFilter(s.filter.hash, s.filter.expectedSize, s.filter.rate))
+	}
+	if s.augment != nil {
+		opts = append(opts, WithAggregate(s.augment))
+	}
+	if s.shadow != nil {
+		opts = append(opts, WithShadowCheck(s.shadow.report))
+	}
+	if s.profile != nil {
+		opts = append(opts, WithAccessProfiling())
+	}
+	if s.quota != nil {
+		opts = append(opts, WithSizer(s.quota.sizer), WithByteLimit(s.quota.limit, s.quota.onEvict))
+	}
+	return opts
+}
@@ -0,0 +1,151 @@
+package skiplist
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestLevelForSize(t *testing.T) {
+	for _, c := range []struct{ size, level int }{
+		{0, 0}, {1, 0}, {4, 1}, {5, 2}, {16, 2}, {1 << 20, 10},
+	} {
+		if got := levelForSize(c.size, p); got != c.level {
+			t.Errorf("levelForSize(%d) should be %d, not %d.", c.size, c.level, got)
+		}
+	}
+}
+
+func TestMaxLevelForSize(t *testing.T) {
+	if l := MaxLevelForSize(1 << 20); l != 12 {
+		t.Errorf("MaxLevelForSize(1 << 20) should be 12, not %d.", l)
+	}
+	if s := NewIntMap(WithLevelGrowthHint(100)); s.MaxLevel != MaxLevelForSize(100) {
+		t.Errorf("Expected MaxLevel %d, got %d.", MaxLevelForSize(100), s.MaxLevel)
+	}
+	if s := NewIntMap(WithLevelGrowthHint(100), WithMaxLevel(20)); s.MaxLevel != 20 {
+		t.Errorf("WithMaxLevel should take precedence, got %d.", s.MaxLevel)
+	}
+	if s := NewIntMap(); s.MaxLevel != DefaultMaxLevel {
+		t.Errorf("Expected MaxLevel %d by default, got %d.", DefaultMaxLevel, s.MaxLevel)
+	}
+}
+
+func TestWithAdaptiveMaxLevel(t *testing.T) {
+	s := NewIntMap(WithAdaptiveMaxLevel(), WithRandSource(rand.NewSource(1)))
+	for i := 0; i < 1<<12; i++ {
+		s.Set(i, i)
+		if l := s.level(); l > MaxLevelForSize(s.Len()) {
+			t.Fatalf("Level %d exceeds %d for %d elements.", l, MaxLevelForSize(s.Len()), s.Len())
+		}
+	}
+	if l := s.level(); l < levelForSize(s.Len(), p)-2 {
+		t.Errorf("Level %d is too low for %d elements.", l, s.Len())
+	}
+
+	s.DeleteRange(16, 1<<12)
+	s.RebuildOptimal()
+	if l := s.level(); l > MaxLevelForSize(16) {
+		t.Errorf("RebuildOptimal should lower the level to %d, not %d.", MaxLevelForSize(16), l)
+	}
+	for i := 0; i < 16; i++ {
+		s.check(t, i, i)
+	}
+
+	f := NewIntMap(WithAdaptiveMaxLevel(), WithMaxLevel(3))
+	elements := make([][2]interface{}, 1<<12)
+	for i := range elements {
+		elements[i] = [2]interface{}{i, i}
+	}
+	f.FillBySortedSlice(elements)
+	if l := f.level(); l > 3 {
+		t.Errorf("MaxLevel should still bound the level, got %d.", l)
+	}
+}
+
+func TestWithInitialLevel(t *testing.T) {
+	s := NewIntMap(WithInitialLevel(8))
+	if c := cap(s.header.levels); c != 9 {
+		t.Errorf("Expected the header to have room for 9 levels, got %d.", c)
+	}
+	if l := s.level(); l != 0 {
+		t.Errorf("Preallocating levels should not change the level, got %d.", l)
+	}
+
+	for i := 0; i < 1000; i++ {
+		s.Set(i, i)
+	}
+	for i := 0; i < 1000; i += 2 {
+		s.Delete(i)
+	}
+	for i := 0; i < 1000; i++ {
+		if _, ok := s.Get(i); ok != (i%2 == 1) {
+			t.Errorf("Unexpected presence of key %d: %v.", i, ok)
+		}
+	}
+
+	for n := s.header.next(); n != nil; n = n.next() {
+		if len(n.levels) != cap(n.levels) {
+			t.Fatalf("Node %v has %d levels but capacity for %d.", n.key, len(n.levels), cap(n.levels))
+		}
+	}
+
+	s.Clear()
+	if c := cap(s.header.levels); c != 9 {
+		t.Errorf("Expected Clear to keep room for 9 levels, got %d.", c)
+	}
+
+	set := NewIntSet(WithLevelGrowthHint(1 << 20))
+	if c := cap(set.skiplist.header.levels); c != 11 {
+		t.Errorf("Expected the header to have room for 11 levels, got %d.", c)
+	}
+}
+
+func TestNewWithOptions(t *testing.T) {
+	s := NewWithOptions(
+		WithComparator(func(l, r interface{}) bool {
+			return l.(int) < r.(int)
+		}),
+		WithMaxLevel(4),
+		WithP(0.5),
+		WithNodeBlockSize(16),
+	)
+	if s.MaxLevel != 4 || s.p != 0.5 {
+		t.Errorf("Options were not applied: MaxLevel %d, p %v.", s.MaxLevel, s.p)
+	}
+	for i := 0; i < 100; i++ {
+		s.Set(i, i)
+	}
+	for i := 0; i < 100; i++ {
+		s.check(t, i, i)
+	}
+	if s.level() > 4 {
+		t.Errorf("Level %d exceeds MaxLevel 4.", s.level())
+	}
+
+	o := NewWithOptions()
+	o.Set(MyOrdered{1}, 1)
+	if v, _ := o.Get(MyOrdered{1}); v != 1 {
+		t.Errorf("Expected Ordered keys by default, got %v.", v)
+	}
+}
+
+func TestWithRandSource(t *testing.T) {
+	shape := func() []int {
+		s := NewIntMap(WithRandSource(rand.NewSource(42)))
+		for i := 0; i < 100; i++ {
+			s.Set(i, i)
+		}
+		var levels []int
+		for n := s.header.next(); n != nil; n = n.next() {
+			levels = append(levels, len(n.levels))
+		}
+		return levels
+	}
+
+	a, b := shape(), shape()
+	for i := range a {
+		if a[i] != b[i] {
+			t.Fatalf("Lists built from equally seeded sources differ at %d: %d and %d levels.", i, a[i], b[i])
+		}
+	}
+}
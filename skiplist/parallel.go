@@ -0,0 +1,153 @@
+package skiplist
+
+import (
+	"math/rand"
+	"runtime"
+	"sync"
+)
+
+// chunk is a piece of a list built by FillBySortedSliceParallel. first
+// and last hold the first and last nodes of the chunk at every level,
+// and firstPos and lastPos their positions in the whole list.
+type chunk struct {
+	first, last       []*node
+	firstPos, lastPos []int
+	unsorted          bool
+}
+
+// FillBySortedSliceParallel is like FillBySortedSlice, but builds the
+// list with up to workers goroutines (GOMAXPROCS if workers is not
+// positive). elements is split in contiguous chunks, which are built
+// concurrently and then linked together in a final pass over their
+// boundaries, cutting the time to load large datasets.
+func (s *SkipList) FillBySortedSliceParallel(elements [][2]interface{}, workers int) bool {
+	if s.Len() != 0 {
+		panic(ErrNotEmpty)
+	}
+	s.unshare()
+	s.dropFinger()
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if workers > len(elements) {
+		workers = len(elements)
+	}
+	if workers <= 1 {
+		return s.FillBySortedSlice(elements)
+	}
+
+	maxLevel := s.maxLevelFor(len(elements))
+	// The workers cannot share the visit counter of a profile either.
+	lessThan := s.lessThan
+	if s.profile != nil {
+		lessThan = s.profile.lessThan
+	}
+	chunks := make([]chunk, workers)
+	var wg sync.WaitGroup
+	for w := range chunks {
+		from, to := w*len(elements)/workers, (w+1)*len(elements)/workers
+		// The workers cannot share the source of s.
+		r := rand.New(rand.NewSource(int64(s.random() * (1 << 62))))
+		wg.Add(1)
+		go func(c *chunk, from, to int) {
+			defer wg.Done()
+			s.buildChunk(c, elements, from, to, maxLevel, r, lessThan)
+		}(&chunks[w], from, to)
+	}
+	wg.Wait()
+
+	for w := range chunks {
+		if chunks[w].unsorted || (w > 0 && !s.lessThan(chunks[w-1].last[0].key, chunks[w].first[0].key)) {
+			panic(ErrUnsortedInput)
+		}
+	}
+
+	top := 0
+	for w := range chunks {
+		for top < maxLevel && chunks[w].first[top+1] != nil {
+			top++
+		}
+	}
+	for s.level() < top {
+		s.header.levels = append(s.header.levels, level{})
+	}
+
+	// Link the chunks at every level, starting from the header.
+	tail := make([]*node, top+1)
+	tailPos := make([]int, top+1)
+	for i := range tail {
+		tail[i] = s.header
+	}
+	for w := range chunks {
+		c := &chunks[w]
+		if w > 0 {
+			c.first[0].backward = chunks[w-1].last[0]
+		}
+		for i := 0; i <= top && c.first[i] != nil; i++ {
+			tail[i].levels[i].forward = c.first[i]
+			tail[i].levels[i].span = uint32(c.firstPos[i] - tailPos[i])
+			tail[i], tailPos[i] = c.last[i], c.lastPos[i]
+		}
+	}
+	for i := range tail {
+		tail[i].levels[i].span = uint32(len(elements) - tailPos[i])
+	}
+	s.footer = chunks[len(chunks)-1].last[0]
+	s.length = len(elements)
+
+	for n := s.header.next(); n != nil; n = n.next() {
+		if s.filter != nil {
+			s.filter.add(n.key)
+		}
+		if s.quota != nil {
+			s.quota.account(n.key, n.value, 1)
+		}
+	}
+	if s.augment != nil {
+		s.rebuildAggregates()
+	}
+	if s.shadow != nil {
+		s.shadow.fill(s, elements)
+	}
+	if s.quota != nil {
+		s.enforceQuota()
+	}
+	return true
+}
+
+// buildChunk links the nodes for elements[from:to] into c, comparing
+// keys with lessThan.
+func (s *SkipList) buildChunk(c *chunk, elements [][2]interface{}, from, to, maxLevel int, r *rand.Rand, lessThan func(l, r interface{}) bool) {
+	c.first = make([]*node, maxLevel+1)
+	c.last = make([]*node, maxLevel+1)
+	c.firstPos = make([]int, maxLevel+1)
+	c.lastPos = make([]int, maxLevel+1)
+	nodes := make([]node, to-from)
+	for k := range nodes {
+		lvl := 0
+		for lvl < maxLevel && r.Float64() < s.p {
+			lvl++
+		}
+		n := &nodes[k]
+		n.setLevel(lvl)
+		n.key, n.value = s.intern(elements[from+k][0]), s.encode(elements[from+k][1])
+		if k > 0 {
+			n.backward = &nodes[k-1]
+			if !lessThan(n.backward.key, n.key) {
+				c.unsorted = true
+				return
+			}
+		}
+		// Positions start from 1, the header being at 0.
+		pos := from + k + 1
+		for i := 0; i <= lvl; i++ {
+			if c.last[i] == nil {
+				c.first[i], c.firstPos[i] = n, pos
+			} else {
+				c.last[i].levels[i].forward = n
+				c.last[i].levels[i].span = uint32(pos - c.lastPos[i])
+			}
+			c.last[i], c.lastPos[i] = n, pos
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package skiplist
+
+import "testing"
+
+func TestFillBySortedSliceParallel(t *testing.T) {
+	elements := make([][2]interface{}, 10000)
+	for i := range elements {
+		elements[i] = [2]interface{}{i * 2, i}
+	}
+	for _, workers := range []int{0, 1, 3, 16} {
+		s := NewIntMap(WithShadowCheck(nil), WithAggregate(&sumMonoid))
+		s.FillBySortedSliceParallel(elements, workers)
+		if err := s.CheckShadow(); err != nil {
+			t.Fatalf("Wrong list with %d workers: %v.", workers, err)
+		}
+		previous := s.header
+		for n := s.header.next(); n != nil; n = n.next() {
+			if n.backward != previous && !(previous == s.header && n.backward == nil) {
+				t.Fatalf("Wrong backward pointer at %v with %d workers.", n.key, workers)
+			}
+			previous = n
+		}
+		if k, _, _ := s.Max(); k != 19998 {
+			t.Errorf("Wrong footer with %d workers: %v.", workers, k)
+		}
+		if sum := s.Aggregate(); sum != 10000*9999/2 {
+			t.Errorf("Wrong aggregate with %d workers: %v.", workers, sum)
+		}
+		s.Set(5, 5)
+		s.Delete(10)
+		if s.Rank(5) != 4 || s.Rank(12) != 7 {
+			t.Errorf("Wrong ranks after updates with %d workers.", workers)
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Expected a panic for an unsorted slice.")
+		}
+	}()
+	elements[5000], elements[5001] = elements[5001], elements[5000]
+	NewIntMap().FillBySortedSliceParallel(elements, 4)
+}
+
+func TestFillBySortedSliceParallelProfiled(t *testing.T) {
+	elements := make([][2]interface{}, 10000)
+	for i := range elements {
+		elements[i] = [2]interface{}{i, i}
+	}
+	s := NewIntMap(WithAccessProfiling())
+	// Run with -race: the workers must not update the visit counter.
+	if !s.FillBySortedSliceParallel(elements, 4) || s.Len() != 10000 {
+		t.Fatalf("Expected a profiled list to be filled, got %d elements.", s.Len())
+	}
+	if v, ok := s.Get(1234); !ok || v != 1234 {
+		t.Errorf("Wrong value after a parallel fill: %v.", v)
+	}
+}
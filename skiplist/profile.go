@@ -0,0 +1,135 @@
+package skiplist
+
+// opKind identifies the operations profiled by WithAccessProfiling.
+type opKind int
+
+const (
+	opGet opKind = iota
+	opSet
+	opDelete
+	opRank
+	numOpKinds
+)
+
+// maxTrackedVisits is the largest number of visits the profile keeps
+// exact counts for; costlier operations are counted as that many.
+const maxTrackedVisits = 1024
+
+// visitHistogram counts operations by the number of nodes they
+// visited.
+type visitHistogram struct {
+	counts [maxTrackedVisits + 1]uint64
+	ops    uint64
+	sum    uint64
+	max    int
+}
+
+func (h *visitHistogram) add(visits int) {
+	h.ops++
+	h.sum += uint64(visits)
+	if visits > h.max {
+		h.max = visits
+	}
+	if visits > maxTrackedVisits {
+		visits = maxTrackedVisits
+	}
+	h.counts[visits]++
+}
+
+// percentile returns the smallest number of visits not exceeded by a
+// fraction q of the operations.
+func (h *visitHistogram) percentile(q float64) int {
+	target := uint64(q*float64(h.ops) + 0.5)
+	var seen uint64
+	for visits, count := range h.counts {
+		seen += count
+		if seen >= target && seen > 0 {
+			return visits
+		}
+	}
+	return h.max
+}
+
+func (h *visitHistogram) summary() VisitSummary {
+	if h.ops == 0 {
+		return VisitSummary{}
+	}
+	return VisitSummary{
+		Ops:  h.ops,
+		Mean: float64(h.sum) / float64(h.ops),
+		P50:  h.percentile(0.5),
+		P90:  h.percentile(0.9),
+		P99:  h.percentile(0.99),
+		Max:  h.max,
+	}
+}
+
+// profile counts the nodes visited by the operations of a list, as
+// the number of calls to its comparison function.
+type profile struct {
+	// lessThan is the comparison function of the list, before it was
+	// wrapped to count visits.
+	lessThan func(l, r interface{}) bool
+	visits   int
+	ops      [numOpKinds]visitHistogram
+}
+
+// WithAccessProfiling makes the list count the nodes visited by every
+// Get, Set, Delete and Rank, as the number of key comparisons they
+// make. Stats summarizes the counts, which helps checking that MaxLevel
+// and p suit the size of the data: a well-tuned list visits about
+// log(n)/p nodes per operation. Profiling adds a little overhead to
+// every comparison.
+func WithAccessProfiling() Option {
+	return func(s *SkipList) {
+		s.profile = &profile{}
+	}
+}
+
+// wrap makes the comparison function of s count visits.
+func (p *profile) wrap(s *SkipList) {
+	p.lessThan = s.lessThan
+	s.lessThan = func(l, r interface{}) bool {
+		p.visits++
+		return p.lessThan(l, r)
+	}
+}
+
+// track starts profiling an operation of kind op, returning the
+// function to call when it is done.
+func (p *profile) track(op opKind) func() {
+	start := p.visits
+	return func() {
+		p.ops[op].add(p.visits - start)
+	}
+}
+
+// VisitSummary describes the number of nodes visited by an operation.
+type VisitSummary struct {
+	// Ops is the number of operations profiled.
+	Ops uint64
+	// Mean is the average number of nodes visited, and P50, P90, P99
+	// and Max are percentiles of it.
+	Mean               float64
+	P50, P90, P99, Max int
+}
+
+// AccessStats summarizes the nodes visited by each kind of operation
+// (see WithAccessProfiling).
+type AccessStats struct {
+	Get, Set, Delete, Rank VisitSummary
+}
+
+// Stats returns statistics about the nodes visited by the operations
+// of s. ok is false if s was not created with WithAccessProfiling.
+func (s *SkipList) Stats() (stats AccessStats, ok bool) {
+	if s.profile == nil {
+		return AccessStats{}, false
+	}
+	return AccessStats{
+		Get:    s.profile.ops[opGet].summary(),
+		Set:    s.profile.ops[opSet].summary(),
+		Delete: s.profile.ops[opDelete].summary(),
+		Rank:   s.profile.ops[opRank].summary(),
+	}, true
+}
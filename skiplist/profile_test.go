@@ -0,0 +1,43 @@
+package skiplist
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestAccessProfiling(t *testing.T) {
+	if _, ok := NewIntMap().Stats(); ok {
+		t.Errorf("Stats should not be available without profiling.")
+	}
+
+	s := NewIntMap(WithAccessProfiling(), WithRandSource(rand.NewSource(1)))
+	for i := 0; i < 10000; i++ {
+		s.Set(i, i)
+	}
+	for i := 0; i < 1000; i++ {
+		s.Get(i * 7)
+		s.Rank(i * 3)
+	}
+	s.Delete(5)
+	stats, ok := s.Stats()
+	if !ok {
+		t.Fatalf("Stats should be available with profiling.")
+	}
+	if stats.Set.Ops != 10000 || stats.Get.Ops != 1000 || stats.Rank.Ops != 1000 || stats.Delete.Ops != 1 {
+		t.Errorf("Wrong operation counts: %+v.", stats)
+	}
+	g := stats.Get
+	if g.Mean < 5 || g.Mean > 100 || g.P50 > g.P90 || g.P90 > g.P99 || g.P99 > g.Max {
+		t.Errorf("Implausible summary for Get: %+v.", g)
+	}
+
+	// Lists derived from a profiled list are profiled separately.
+	c := s.CopyRange(0, 100)
+	c.Get(1)
+	if stats, _ := c.Stats(); stats.Get.Ops != 1 || stats.Set.Ops != 0 {
+		t.Errorf("Wrong stats for a copy: %+v.", stats)
+	}
+	if stats, _ := s.Stats(); stats.Get.Ops != 1000 {
+		t.Errorf("Copy should not count towards the original: %+v.", stats.Get)
+	}
+}
@@ -0,0 +1,72 @@
+package skiplist
+
+// sizeQuota keeps track of the number of bytes used by the elements of
+// a list, as estimated by a user-provided function.
+type sizeQuota struct {
+	sizer   func(key, value interface{}) int
+	total   int64
+	limit   int64
+	onEvict func(key, value interface{})
+}
+
+func (s *SkipList) sizeQuota() *sizeQuota {
+	if s.quota == nil {
+		s.quota = &sizeQuota{}
+	}
+	return s.quota
+}
+
+// WithSizer makes the list account for the size in bytes of its
+// elements, as returned by sizer, which is called once whenever an
+// element is inserted, updated or removed. The total is available
+// through TotalBytes.
+func WithSizer(sizer func(key, value interface{}) int) Option {
+	return func(s *SkipList) {
+		s.sizeQuota().sizer = sizer
+	}
+}
+
+// WithByteLimit caps the total size of the elements of the list, as
+// computed by the function given to WithSizer, to limit bytes. Whenever
+// a Set takes the list over the limit, the elements with the smallest
+// keys are deleted until it fits again, and passed to onEvict (if not
+// nil). With keys ordered by insertion time, this evicts the oldest
+// elements first. Note that an element bigger than limit is evicted
+// right after being set.
+func WithByteLimit(limit int64, onEvict func(key, value interface{})) Option {
+	return func(s *SkipList) {
+		q := s.sizeQuota()
+		q.limit = limit
+		q.onEvict = onEvict
+	}
+}
+
+// TotalBytes returns the total size of the elements of s, or 0 if s
+// was not created with WithSizer.
+func (s *SkipList) TotalBytes() int64 {
+	if s.quota == nil {
+		return 0
+	}
+	return s.quota.total
+}
+
+// account adds the size of key and value to the total, or removes it
+// if sign is negative.
+func (q *sizeQuota) account(key, value interface{}, sign int64) {
+	if q.sizer != nil {
+		q.total += sign * int64(q.sizer(key, value))
+	}
+}
+
+// enforceQuota evicts elements from the front of s until it fits in
+// its byte limit.
+func (s *SkipList) enforceQuota() {
+	q := s.quota
+	for q.limit > 0 && q.total > q.limit && s.length > 0 {
+		first := s.header.next()
+		s.Delete(first.key)
+		if q.onEvict != nil {
+			q.onEvict(first.key, s.decode(first.value))
+		}
+	}
+}
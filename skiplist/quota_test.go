@@ -0,0 +1,61 @@
+package skiplist
+
+import "testing"
+
+func TestByteLimit(t *testing.T) {
+	var evicted []interface{}
+	s := NewIntMap(
+		WithSizer(func(key, value interface{}) int {
+			return len(value.(string))
+		}),
+		WithByteLimit(10, func(key, value interface{}) {
+			evicted = append(evicted, key)
+		}),
+	)
+	s.Set(3, "abc")
+	s.Set(1, "abcd")
+	s.Set(2, "ab")
+	if s.TotalBytes() != 9 || len(evicted) != 0 {
+		t.Errorf("Wrong size accounting: %v bytes, evicted %v.", s.TotalBytes(), evicted)
+	}
+
+	s.Set(2, "abcde")
+	if s.TotalBytes() != 8 || len(evicted) != 1 || evicted[0] != 1 {
+		t.Errorf("Wrong eviction: %v bytes, evicted %v.", s.TotalBytes(), evicted)
+	}
+	if _, ok := s.Get(1); ok || s.Len() != 2 {
+		t.Errorf("Evicted element should have been deleted.")
+	}
+
+	s.Delete(3)
+	if s.TotalBytes() != 5 {
+		t.Errorf("Wrong size after delete: %v.", s.TotalBytes())
+	}
+
+	s.Set(4, "abcdefghijk")
+	if s.Len() != 0 || s.TotalBytes() != 0 || len(evicted) != 3 {
+		t.Errorf("Oversized element should have been evicted: %v.", evicted)
+	}
+
+	s.FillBySortedSlice([][2]interface{}{{1, "aaaa"}, {2, "bbbb"}, {3, "cccc"}})
+	if s.Len() != 2 || s.TotalBytes() != 8 {
+		t.Errorf("Wrong size after fill: %v bytes, %v elements.", s.TotalBytes(), s.Len())
+	}
+	s.Clear()
+	if s.TotalBytes() != 0 {
+		t.Errorf("Clear should reset the size.")
+	}
+}
+
+func TestSizerWithoutLimit(t *testing.T) {
+	s := NewIntMap(WithSizer(func(key, value interface{}) int { return 1 }))
+	for i := 0; i < 100; i++ {
+		s.Set(i, i)
+	}
+	if s.TotalBytes() != 100 || s.Len() != 100 {
+		t.Errorf("Wrong size accounting: %v.", s.TotalBytes())
+	}
+	if NewIntMap().TotalBytes() != 0 {
+		t.Errorf("TotalBytes should be 0 without a sizer.")
+	}
+}
@@ -0,0 +1,195 @@
+package skiplist
+
+// setOp is a set operation performed by a setOpIterator.
+type setOp int
+
+const (
+	setUnion setOp = iota
+	setIntersection
+	setDifference
+)
+
+// setOpIterator merges the elements of two lists on the fly, yielding
+// those selected by op. floorA and floorB are the last nodes of a and
+// b whose key is less or equal to the current one (the headers if
+// there are none).
+type setOpIterator struct {
+	a, b           *SkipList
+	op             setOp
+	floorA, floorB *node
+	key            interface{}
+	started        bool
+}
+
+// UnionIter returns an iterator over the elements present in s or in
+// other, in order. The elements are merged as the iterator advances,
+// without building an intermediate set. s and other must use the same
+// ordering, and must not be modified while the iterator is in use.
+func (s *Set) UnionIter(other *Set) Iterator {
+	return newSetOpIterator(s, other, setUnion)
+}
+
+// IntersectIter is like UnionIter for the elements present in both s
+// and other.
+func (s *Set) IntersectIter(other *Set) Iterator {
+	return newSetOpIterator(s, other, setIntersection)
+}
+
+// DifferenceIter is like UnionIter for the elements present in s but
+// not in other.
+func (s *Set) DifferenceIter(other *Set) Iterator {
+	return newSetOpIterator(s, other, setDifference)
+}
+
+func newSetOpIterator(a, b *Set, op setOp) *setOpIterator {
+	return &setOpIterator{
+		a:      &a.skiplist,
+		b:      &b.skiplist,
+		op:     op,
+		floorA: a.skiplist.header,
+		floorB: b.skiplist.header,
+	}
+}
+
+// selects returns true if an element present in a (inA) or b (inB)
+// belongs to the result of the operation.
+func (i *setOpIterator) selects(inA, inB bool) bool {
+	switch i.op {
+	case setIntersection:
+		return inA && inB
+	case setDifference:
+		return inA && !inB
+	}
+	return inA || inB
+}
+
+// exhausted returns true if no element can be selected once a (or b)
+// has no more elements in the direction of iteration.
+func (i *setOpIterator) exhausted(a, b *node) bool {
+	switch i.op {
+	case setIntersection:
+		return a == nil || b == nil
+	case setDifference:
+		return a == nil
+	}
+	return a == nil && b == nil
+}
+
+func (i *setOpIterator) Next() bool {
+	floorA, floorB := i.floorA, i.floorB
+	for {
+		nextA, nextB := floorA.next(), floorB.next()
+		if i.exhausted(nextA, nextB) {
+			return false
+		}
+		var key interface{}
+		if nextB == nil || (nextA != nil && !i.a.lessThan(nextB.key, nextA.key)) {
+			key = nextA.key
+		} else {
+			key = nextB.key
+		}
+		inA := nextA != nil && i.a.equal(nextA.key, key)
+		inB := nextB != nil && i.a.equal(nextB.key, key)
+		if inA {
+			floorA = nextA
+		}
+		if inB {
+			floorB = nextB
+		}
+		if i.selects(inA, inB) {
+			i.floorA, i.floorB, i.key, i.started = floorA, floorB, key, true
+			return true
+		}
+	}
+}
+
+// before returns the last node of s whose key is less than key, given
+// floor, the last one whose key is less or equal to it. It returns nil
+// if there is none.
+func before(s *SkipList, floor *node, key interface{}) *node {
+	if floor == s.header {
+		return nil
+	}
+	if s.equal(floor.key, key) {
+		return floor.backward
+	}
+	return floor
+}
+
+func (i *setOpIterator) Previous() bool {
+	if !i.started {
+		return false
+	}
+	floorA, floorB, key := i.floorA, i.floorB, i.key
+	for {
+		prevA, prevB := before(i.a, floorA, key), before(i.b, floorB, key)
+		if i.exhausted(prevA, prevB) {
+			return false
+		}
+		if prevB == nil || (prevA != nil && !i.a.lessThan(prevA.key, prevB.key)) {
+			key = prevA.key
+		} else {
+			key = prevB.key
+		}
+		inA := prevA != nil && i.a.equal(prevA.key, key)
+		inB := prevB != nil && i.a.equal(prevB.key, key)
+		floorA, floorB = prevA, prevB
+		if floorA == nil {
+			floorA = i.a.header
+		}
+		if floorB == nil {
+			floorB = i.b.header
+		}
+		if i.selects(inA, inB) {
+			i.floorA, i.floorB, i.key = floorA, floorB, key
+			return true
+		}
+	}
+}
+
+// lastLess returns the last node whose key is less than key, or the
+// header if there is none.
+func (s *SkipList) lastLess(key interface{}) *node {
+	current := s.header
+	for i := s.level(); i >= 0; i-- {
+		for current.levels[i].forward != nil && s.lessThan(current.levels[i].forward.key, key) {
+			current = current.levels[i].forward
+		}
+	}
+	return current
+}
+
+// Seek moves the iterator to the first element of the result greater
+// or equal to key. If there is none, it returns false and leaves the
+// iterator unchanged.
+func (i *setOpIterator) Seek(key interface{}) (ok bool) {
+	floorA, floorB := i.floorA, i.floorB
+	i.floorA, i.floorB = i.a.lastLess(key), i.b.lastLess(key)
+	if !i.Next() {
+		i.floorA, i.floorB = floorA, floorB
+		return false
+	}
+	return true
+}
+
+func (i *setOpIterator) Key() interface{} {
+	return i.key
+}
+
+// Value returns nil, as sets have no values.
+func (i *setOpIterator) Value() interface{} {
+	return nil
+}
+
+func (i *setOpIterator) Close() {
+	i.a, i.b = nil, nil
+	i.floorA, i.floorB = nil, nil
+	i.key = nil
+}
+
+// Checkpoint records the current element. The result of a set
+// operation cannot be resumed with ResumeIterator, but Seek to the
+// checkpointed element serves the same purpose.
+func (i *setOpIterator) Checkpoint() ([]byte, error) {
+	return (&checkpoint{Key: i.key}).encode()
+}
@@ -0,0 +1,85 @@
+package skiplist
+
+import "testing"
+
+func collectKeys(it Iterator) (keys []int) {
+	for it.Next() {
+		keys = append(keys, it.Key().(int))
+	}
+	return keys
+}
+
+func equalInts(l, r []int) bool {
+	if len(l) != len(r) {
+		return false
+	}
+	for i := range l {
+		if l[i] != r[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestSetOpIterators(t *testing.T) {
+	a, b := NewIntSet(), NewIntSet()
+	for i := 0; i < 20; i++ {
+		if i%2 == 0 {
+			a.Add(i)
+		}
+		if i%3 == 0 {
+			b.Add(i)
+		}
+	}
+
+	for _, c := range []struct {
+		name     string
+		it       Iterator
+		expected []int
+	}{
+		{"union", a.UnionIter(b), []int{0, 2, 3, 4, 6, 8, 9, 10, 12, 14, 15, 16, 18}},
+		{"intersection", a.IntersectIter(b), []int{0, 6, 12, 18}},
+		{"difference", a.DifferenceIter(b), []int{2, 4, 8, 10, 14, 16}},
+		{"reverse difference", b.DifferenceIter(a), []int{3, 9, 15}},
+		{"empty intersection", a.IntersectIter(NewIntSet()), nil},
+	} {
+		keys := collectKeys(c.it)
+		if !equalInts(keys, c.expected) {
+			t.Errorf("Wrong %s: %v, expected %v.", c.name, keys, c.expected)
+		}
+		var reversed []int
+		for c.it.Previous() {
+			reversed = append([]int{c.it.Key().(int)}, reversed...)
+		}
+		if len(c.expected) > 0 && !equalInts(append(reversed, c.expected[len(c.expected)-1]), c.expected) {
+			t.Errorf("Wrong %s backwards: %v.", c.name, reversed)
+		}
+	}
+}
+
+func TestSetOpIteratorSeek(t *testing.T) {
+	a, b := NewIntSet(), NewIntSet()
+	for i := 0; i < 100; i++ {
+		a.Add(i)
+		if i%10 == 0 {
+			b.Add(i)
+		}
+	}
+	it := a.IntersectIter(b)
+	if !it.Seek(31) || it.Key() != 40 {
+		t.Errorf("Seek should find 40, got %v.", it.Key())
+	}
+	if !it.Next() || it.Key() != 50 {
+		t.Errorf("Next after Seek should find 50, got %v.", it.Key())
+	}
+	if it.Seek(91) || it.Key() != 50 {
+		t.Errorf("Failed Seek should leave the iterator unchanged, got %v.", it.Key())
+	}
+	if !it.Previous() || it.Key() != 40 {
+		t.Errorf("Previous should find 40, got %v.", it.Key())
+	}
+	d := a.DifferenceIter(b)
+	if !d.Seek(30) || d.Key() != 31 {
+		t.Errorf("Seek should skip excluded elements, got %v.", d.Key())
+	}
+}
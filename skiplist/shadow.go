@@ -0,0 +1,152 @@
+package skiplist
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// shadowList is a deliberately naive sorted slice implementation of a
+// map, used to double check the results of a SkipList.
+type shadowList struct {
+	keys   []interface{}
+	values []interface{}
+	report func(err error)
+}
+
+// WithShadowCheck makes the list mirror all its mutations into a
+// simple sorted slice, and compare the results of Get, Rank, Set and
+// Delete with it. Any difference, which would indicate corrupted
+// spans or ordering, is passed to report (or makes the list panic if
+// report is nil). Mutations become O(n), so this is only meant for
+// tests and canary deployments; see also CheckShadow.
+func WithShadowCheck(report func(err error)) Option {
+	return func(s *SkipList) {
+		if report == nil {
+			report = func(err error) { panic(err) }
+		}
+		s.shadow = &shadowList{report: report}
+	}
+}
+
+// search returns the position of key in the shadow, or where it would
+// be inserted, and whether it is present.
+func (sh *shadowList) search(s *SkipList, key interface{}) (int, bool) {
+	i := sort.Search(len(sh.keys), func(i int) bool {
+		return !s.lessThan(sh.keys[i], key)
+	})
+	return i, i < len(sh.keys) && !s.lessThan(key, sh.keys[i])
+}
+
+func (sh *shadowList) set(s *SkipList, key, value interface{}) {
+	i, found := sh.search(s, key)
+	if !found {
+		sh.keys = append(sh.keys, nil)
+		sh.values = append(sh.values, nil)
+		copy(sh.keys[i+1:], sh.keys[i:])
+		copy(sh.values[i+1:], sh.values[i:])
+		sh.keys[i] = key
+	}
+	sh.values[i] = value
+	sh.checkLen(s)
+}
+
+func (sh *shadowList) delete(s *SkipList, key, value interface{}, ok bool) {
+	i, found := sh.search(s, key)
+	if found != ok {
+		sh.report(fmt.Errorf("goskiplist: shadow check: Delete(%v) reported presence %v, expected %v", key, ok, found))
+	}
+	if found {
+		sh.keys = append(sh.keys[:i], sh.keys[i+1:]...)
+		sh.values = append(sh.values[:i], sh.values[i+1:]...)
+	}
+	sh.checkLen(s)
+}
+
+// deleteKeys removes keys, which the list has already deleted.
+func (sh *shadowList) deleteKeys(s *SkipList, keys []interface{}) {
+	for _, key := range keys {
+		if i, found := sh.search(s, key); found {
+			sh.keys = append(sh.keys[:i], sh.keys[i+1:]...)
+			sh.values = append(sh.values[:i], sh.values[i+1:]...)
+		}
+	}
+	sh.checkLen(s)
+}
+
+// split moves the elements from position at onward to the shadow of t,
+// into which s was split.
+func (sh *shadowList) split(s, t *SkipList, at int) {
+	t.shadow.keys = append([]interface{}(nil), sh.keys[at:]...)
+	t.shadow.values = append([]interface{}(nil), sh.values[at:]...)
+	sh.keys, sh.values = sh.keys[:at:at], sh.values[:at:at]
+	sh.checkLen(s)
+	t.shadow.checkLen(t)
+}
+
+func (sh *shadowList) fill(s *SkipList, elements [][2]interface{}) {
+	sh.keys = make([]interface{}, len(elements))
+	sh.values = make([]interface{}, len(elements))
+	for i, elem := range elements {
+		sh.keys[i], sh.values[i] = elem[0], elem[1]
+	}
+	sh.checkLen(s)
+}
+
+// sameValue returns true if the values a and b are equal. Unlike ==, it
+// does not panic on slices, maps or funcs: the former are compared
+// deeply, and funcs by their code pointer.
+func sameValue(a, b interface{}) bool {
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	if va.Kind() == reflect.Func && vb.Kind() == reflect.Func {
+		return va.Type() == vb.Type() && va.Pointer() == vb.Pointer()
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func (sh *shadowList) checkLen(s *SkipList) {
+	if s.length != len(sh.keys) {
+		sh.report(fmt.Errorf("goskiplist: shadow check: length is %d, expected %d", s.length, len(sh.keys)))
+	}
+}
+
+func (sh *shadowList) checkGet(s *SkipList, key, value interface{}, ok bool) {
+	i, found := sh.search(s, key)
+	if found != ok || (found && !sameValue(value, sh.values[i])) {
+		sh.report(fmt.Errorf("goskiplist: shadow check: Get(%v) returned %v, %v", key, value, ok))
+	}
+}
+
+func (sh *shadowList) checkRank(s *SkipList, key interface{}, rank uint32) {
+	i, found := sh.search(s, key)
+	if want := uint32(i + 1); (found && rank != want) || (!found && rank != 0) {
+		sh.report(fmt.Errorf("goskiplist: shadow check: Rank(%v) returned %d", key, rank))
+	}
+}
+
+// CheckShadow compares the whole contents of s, including the rank of
+// every element, with its shadow copy. It returns nil if they agree or
+// s was not created with WithShadowCheck.
+func (s *SkipList) CheckShadow() error {
+	sh := s.shadow
+	if sh == nil {
+		return nil
+	}
+	if s.length != len(sh.keys) {
+		return fmt.Errorf("goskiplist: shadow check: length is %d, expected %d", s.length, len(sh.keys))
+	}
+	i := 0
+	for n := s.header.next(); n != nil; n = n.next() {
+		if i >= len(sh.keys) || !s.equal(n.key, sh.keys[i]) || !sameValue(s.decode(n.value), sh.values[i]) {
+			return fmt.Errorf("goskiplist: shadow check: element %d is %v: %v", i, n.key, s.decode(n.value))
+		}
+		if rank := s.rank(n.key); rank != uint32(i+1) {
+			return fmt.Errorf("goskiplist: shadow check: rank of %v is %d, expected %d", n.key, rank, i+1)
+		}
+		i++
+	}
+	if i != len(sh.keys) {
+		return fmt.Errorf("goskiplist: shadow check: %d elements linked, expected %d", i, len(sh.keys))
+	}
+	return nil
+}
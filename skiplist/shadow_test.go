@@ -0,0 +1,114 @@
+package skiplist
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestShadowCheck(t *testing.T) {
+	var errs []error
+	s := NewIntMap(WithShadowCheck(func(err error) {
+		errs = append(errs, err)
+	}))
+	for i := 0; i < 1000; i++ {
+		k := rand.Intn(100)
+		switch rand.Intn(4) {
+		case 0:
+			s.Delete(k)
+		case 1:
+			s.Get(k)
+			s.Rank(k)
+		default:
+			s.Set(k, i)
+		}
+	}
+	if err := s.CheckShadow(); err != nil || len(errs) != 0 {
+		t.Fatalf("Unexpected mismatches: %v, %v.", err, errs)
+	}
+
+	// Corrupt the spans behind the back of the list.
+	for i := range s.header.levels {
+		s.header.levels[i].span += 7
+	}
+	s.Rank(s.header.next().key)
+	if len(errs) != 1 {
+		t.Errorf("Expected the corrupted rank to be reported, got %v.", errs)
+	}
+	if err := s.CheckShadow(); err == nil {
+		t.Errorf("Expected CheckShadow to find the corrupted span.")
+	}
+
+	s.Clear()
+	s.FillBySortedSlice([][2]interface{}{{1, 1}, {2, 2}})
+	if err := s.CheckShadow(); err != nil {
+		t.Errorf("Unexpected mismatch after filling: %v.", err)
+	}
+}
+
+func TestShadowCheckMutators(t *testing.T) {
+	var errs []error
+	s := NewIntMap(WithShadowCheck(func(err error) {
+		errs = append(errs, err)
+	}), WithSizer(func(key, value interface{}) int {
+		return 1
+	}), WithByteLimit(50, nil))
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 2000; i++ {
+		k := r.Intn(100)
+		switch r.Intn(7) {
+		case 0:
+			s.SetReturning(k, i)
+		case 1:
+			s.GetOrSet(k, i)
+		case 2:
+			s.SetIfPresent(k, i)
+		case 3:
+			s.CompareAndSwap(k, i-1, i)
+		case 4:
+			s.UpdateFunc(k, func(old interface{}, exists bool) (interface{}, bool) {
+				return i, i%3 != 0
+			})
+		case 5:
+			s.SwapValues(k, r.Intn(100))
+		default:
+			s.Delete(k)
+		}
+		if s.Len() > 50 {
+			t.Fatalf("The byte limit was not enforced: %d elements.", s.Len())
+		}
+	}
+	if err := s.CheckShadow(); err != nil || len(errs) != 0 {
+		t.Errorf("Unexpected mismatches: %v, %v.", err, errs)
+	}
+}
+
+func TestShadowCheckUncomparableValues(t *testing.T) {
+	s := NewIntMap(WithShadowCheck(nil))
+	f := func() {}
+	s.Set(1, []byte("a"))
+	s.Set(2, map[string]int{"b": 2})
+	s.Set(3, f)
+	s.Get(1)
+	s.Get(2)
+	s.Get(3)
+	if err := s.CheckShadow(); err != nil {
+		t.Errorf("Unexpected mismatch: %v.", err)
+	}
+	s.header.levels[0].forward.value = []byte("b")
+	if err := s.CheckShadow(); err == nil {
+		t.Errorf("Expected a mismatch of []byte values.")
+	}
+}
+
+func TestShadowCheckPanics(t *testing.T) {
+	s := NewIntMap(WithShadowCheck(nil))
+	s.Set(1, 1)
+	s.header.levels[0].forward.value = 2
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Expected a mismatch to panic by default.")
+		}
+	}()
+	s.Get(1)
+}
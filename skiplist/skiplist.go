@@ -15,16 +15,18 @@
 package skiplist
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
 	"math/rand"
 )
 
-// TODO(ryszard):
-//   - A separately seeded source of randomness
-
 // p is the fraction of nodes with level i pointers that also have
 // level i+1 pointers. p equal to 1/4 is a good value from the point
 // of view of speed and space requirements. If variability of running
-// times is a concern, 1/2 is a better value for p.
+// times is a concern, 1/2 is a better value for p. It is the default
+// for lists not created with WithP.
 const p = 0.25
 
 const DefaultMaxLevel = 32
@@ -36,12 +38,32 @@ type level struct {
 	span    uint32
 }
 
+// inlineLevels is the number of levels stored in the nodes themselves.
+// With the default p, 15 nodes out of 16 have at most 2 levels. Every
+// node pays for them, which makes lists about 10% larger, but saves most
+// insertions an allocation (see BenchmarkBuild1024).
+const inlineLevels = 2
+
 type node struct {
+	// levels holds the level pointers of the node, from the lowest
+	// one. For nodes with at most inlineLevels levels, it points to
+	// inline, which saves them an allocation and keeps their pointers
+	// next to their keys in memory.
 	levels     []level
+	inline     [inlineLevels]level
 	backward   *node
 	key, value interface{}
 }
 
+// setLevel allocates the levels of n, up to level lvl.
+func (n *node) setLevel(lvl int) {
+	if lvl < inlineLevels {
+		n.levels = n.inline[: lvl+1 : lvl+1]
+	} else {
+		n.levels = make([]level, lvl+1)
+	}
+}
+
 // next returns the next node in the skip list containing n.
 func (n *node) next() *node {
 	if len(n.levels) == 0 {
@@ -94,6 +116,58 @@ type SkipList struct {
 	// standard linked list and will not have any of the nice
 	// properties of skip lists (probably not what you want).
 	MaxLevel int
+	// levelHint is the number of levels per-level slices are
+	// preallocated for (see WithInitialLevel), and expectedSize the
+	// size passed to WithLevelGrowthHint.
+	levelHint    int
+	expectedSize int
+	// p is the probability of promoting a node to the next level,
+	// and rand the source of randomness used for that (nil means the
+	// global source of math/rand).
+	p    float64
+	rand *rand.Rand
+	// nodeBlock holds preallocated nodes when nodeBlockSize is
+	// positive (see WithNodeBlockSize).
+	nodeBlock     []node
+	nodeBlockSize int
+	// scratchNodes and scratchRanks are reused by the methods modifying
+	// the list as their update and rank vectors (see scratch).
+	scratchNodes []*node
+	scratchRanks []uint32
+	// filter, if not nil, is a Bloom filter of the keys in the list
+	// (see WithBloomFilter).
+	filter *bloomFilter
+	// augment, if not nil, describes the aggregates maintained for
+	// each level pointer (see WithAggregate), and aggs holds them: for
+	// each node, the aggregate of the elements each of its level
+	// pointers skips over. They are kept aside so that the nodes of the
+	// other lists do not pay for them.
+	augment *Monoid
+	aggs    map[*node][]interface{}
+	// shadow, if not nil, mirrors the contents of the list to check
+	// its consistency (see WithShadowCheck).
+	shadow *shadowList
+	// quota, if not nil, accounts for the size of the elements (see
+	// WithSizer and WithByteLimit).
+	quota *sizeQuota
+	// profile, if not nil, counts the nodes visited by operations
+	// (see WithAccessProfiling).
+	profile *profile
+	// shared is true if the nodes of the list are shared with a
+	// Snapshot, and must be copied before being modified.
+	shared bool
+	// adaptive is true if the maximum level follows the length of the
+	// list (see WithAdaptiveMaxLevel).
+	adaptive bool
+	// interner, if not nil, interns the keys of new nodes (see
+	// WithKeyInterner).
+	interner *Interner
+	// codec, if not nil, transforms the values stored in the nodes (see
+	// WithValueCodec).
+	codec *valueCodec
+	// finger, if not nil, is where searches start (see
+	// WithFingerSearch).
+	finger *finger
 }
 
 // Len returns the length of s.
@@ -103,10 +177,24 @@ func (s *SkipList) Len() int {
 
 func (s *SkipList) Clear() {
 	s.header = &node{
-		levels: []level{level{}},
+		levels: make([]level, 1, s.levelHint+1),
 	}
 	s.footer = nil
 	s.length = 0
+	s.shared = false
+	s.dropFinger()
+	if s.filter != nil {
+		s.filter.reset(s.filter.expectedSize)
+	}
+	if s.augment != nil {
+		s.rebuildAggregates()
+	}
+	if s.shadow != nil {
+		s.shadow.keys, s.shadow.values = nil, nil
+	}
+	if s.quota != nil {
+		s.quota.total = 0
+	}
 }
 
 // Iterator is an interface that you can use to iterate through the
@@ -158,7 +246,7 @@ func (i *iter) Next() bool {
 
 	i.current = i.current.next()
 	i.key = i.current.key
-	i.value = i.current.value
+	i.value = i.list.decode(i.current.value)
 
 	return true
 }
@@ -170,7 +258,7 @@ func (i *iter) Previous() bool {
 
 	i.current = i.current.previous()
 	i.key = i.current.key
-	i.value = i.current.value
+	i.value = i.list.decode(i.current.value)
 
 	return true
 }
@@ -208,7 +296,7 @@ func (i *iter) Seek(key interface{}) (ok bool) {
 
 	i.current = current
 	i.key = current.key
-	i.value = current.value
+	i.value = list.decode(current.value)
 
 	return true
 }
@@ -220,6 +308,21 @@ func (i *iter) Close() {
 	i.list = nil
 }
 
+// Reset rewinds i to the position before the first element of its
+// list, so that the following call to Next yields the first element.
+func (i *iter) Reset() {
+	i.current = i.list.header
+	i.key = nil
+	i.value = nil
+}
+
+// ReusableIter is an Iterator that can be repositioned on any
+// SkipList without allocating. Use SkipList.IterInto to (re)attach it
+// to a list.
+type ReusableIter struct {
+	iter
+}
+
 type rangeIterator struct {
 	iter
 	upperLimit interface{}
@@ -239,7 +342,7 @@ func (i *rangeIterator) Next() bool {
 
 	i.current = i.current.next()
 	i.key = i.current.key
-	i.value = i.current.value
+	i.value = i.list.decode(i.current.value)
 	return true
 }
 
@@ -256,7 +359,7 @@ func (i *rangeIterator) Previous() bool {
 
 	i.current = i.current.previous()
 	i.key = i.current.key
-	i.value = i.current.value
+	i.value = i.list.decode(i.current.value)
 	return true
 }
 
@@ -270,18 +373,169 @@ func (i *rangeIterator) Seek(key interface{}) (ok bool) {
 	return i.iter.Seek(key)
 }
 
+// Reset rewinds i to the position before the first element within its
+// range.
+func (i *rangeIterator) Reset() {
+	start := i.list.getLowerBound(i.list.header, i.lowerLimit)
+	i.current = &node{
+		levels:   []level{level{start, 0}},
+		backward: start,
+	}
+	i.key = nil
+	i.value = nil
+}
+
 func (i *rangeIterator) Close() {
 	i.iter.Close()
 	i.upperLimit = nil
 	i.lowerLimit = nil
 }
 
+// An IterOption constrains the elements visited by an Iterator. Options
+// are applied when the iterator is constructed.
+type IterOption func(*boundedIterator)
+
+// WithLowerBound restricts the iterator to keys greater or equal than
+// key.
+func WithLowerBound(key interface{}) IterOption {
+	return func(i *boundedIterator) {
+		i.lowerBound = key
+	}
+}
+
+// WithUpperBoundExclusive restricts the iterator to keys less than key.
+func WithUpperBoundExclusive(key interface{}) IterOption {
+	return func(i *boundedIterator) {
+		i.upperBound = key
+	}
+}
+
+// WithPrefix restricts the iterator to []byte keys starting with
+// prefix. The skip list must order its keys bytewise (as bytes.Compare
+// does), so that all the keys sharing a prefix are adjacent.
+func WithPrefix(prefix []byte) IterOption {
+	return func(i *boundedIterator) {
+		i.prefix = prefix
+	}
+}
+
+type boundedIterator struct {
+	iter
+	lowerBound interface{}
+	upperBound interface{}
+	prefix     []byte
+}
+
+// contains returns true if key satisfies all the bounds of i.
+func (i *boundedIterator) contains(key interface{}) bool {
+	if i.lowerBound != nil && i.list.lessThan(key, i.lowerBound) {
+		return false
+	}
+	if i.upperBound != nil && !i.list.lessThan(key, i.upperBound) {
+		return false
+	}
+	if i.prefix != nil && !bytes.HasPrefix(key.([]byte), i.prefix) {
+		return false
+	}
+	return true
+}
+
+func (i *boundedIterator) Next() bool {
+	if !i.current.hasNext() || !i.contains(i.current.next().key) {
+		return false
+	}
+	return i.iter.Next()
+}
+
+func (i *boundedIterator) Previous() bool {
+	if !i.current.hasPrevious() || !i.contains(i.current.previous().key) {
+		return false
+	}
+	return i.iter.Previous()
+}
+
+func (i *boundedIterator) Seek(key interface{}) (ok bool) {
+	if !i.contains(key) {
+		return
+	}
+
+	saved := i.iter
+	if !i.iter.Seek(key) || !i.contains(i.key) {
+		i.iter = saved
+		return
+	}
+	return true
+}
+
+// Reset rewinds i to the position before the first element within its
+// bounds.
+func (i *boundedIterator) Reset() {
+	i.iter.Reset()
+	if i.lowerBound == nil && i.prefix == nil {
+		return
+	}
+
+	s := i.list
+	var from interface{} = i.prefix
+	if i.lowerBound != nil && (i.prefix == nil || s.lessThan(i.prefix, i.lowerBound)) {
+		from = i.lowerBound
+	}
+	first := s.getLowerBound(s.header, from)
+	if first == nil && s.footer != nil {
+		i.current = s.footer
+	} else if first != nil && first.backward != nil {
+		i.current = first.backward
+	}
+}
+
+func (i *boundedIterator) Close() {
+	i.iter.Close()
+	i.lowerBound = nil
+	i.upperBound = nil
+	i.prefix = nil
+}
+
 // Iterator returns an Iterator that will go through all elements s.
-func (s *SkipList) Iterator() Iterator {
-	return &iter{
-		current: s.header,
-		list:    s,
+// If any options are given, only the elements satisfying all of them
+// are visited.
+func (s *SkipList) Iterator(opts ...IterOption) Iterator {
+	if len(opts) == 0 {
+		return &iter{
+			current: s.header,
+			list:    s,
+		}
+	}
+
+	i := &boundedIterator{iter: iter{list: s}}
+	for _, opt := range opts {
+		opt(i)
+	}
+	i.Reset()
+	return i
+}
+
+// IterInto attaches it to s and rewinds it to the position before the
+// first element, just as if it had been returned by Iterator. Reusing a
+// single ReusableIter avoids allocating a new iterator per scan.
+func (s *SkipList) IterInto(it *ReusableIter) {
+	it.list = s
+	it.Reset()
+}
+
+// SeekInto attaches it to s and positions it at the first element whose
+// key is greater or equal to key. It returns false (leaving it rewound)
+// if there is no such element.
+func (s *SkipList) SeekInto(it *ReusableIter, key interface{}) bool {
+	s.IterInto(it)
+	current := s.getLowerBound(s.header, key)
+	if current == nil {
+		return false
 	}
+
+	it.current = current
+	it.key = current.key
+	it.value = s.decode(current.value)
+	return true
 }
 
 // Seek returns a bidirectional iterator starting with the first element whose
@@ -296,8 +550,37 @@ func (s *SkipList) Seek(key interface{}) Iterator {
 		current: current,
 		key:     current.key,
 		list:    s,
-		value:   current.value,
+		value:   s.decode(current.value),
+	}
+}
+
+// Min returns the smallest key of s and its value, in O(1). ok is
+// false if s is empty.
+func (s *SkipList) Min() (key, value interface{}, ok bool) {
+	first := s.header.next()
+	if first == nil {
+		return nil, nil, false
 	}
+	return first.key, s.decode(first.value), true
+}
+
+// Max returns the largest key of s and its value, in O(1). ok is false
+// if s is empty.
+func (s *SkipList) Max() (key, value interface{}, ok bool) {
+	if s.footer == nil {
+		return nil, nil, false
+	}
+	return s.footer.key, s.decode(s.footer.value), true
+}
+
+// First is the same as Min, named after the first element of s.
+func (s *SkipList) First() (key, value interface{}, ok bool) {
+	return s.Min()
+}
+
+// Last is the same as Max, named after the last element of s.
+func (s *SkipList) Last() (key, value interface{}, ok bool) {
+	return s.Max()
 }
 
 // SeekToFirst returns a bidirectional iterator starting from the first element
@@ -313,7 +596,7 @@ func (s *SkipList) SeekToFirst() Iterator {
 		current: current,
 		key:     current.key,
 		list:    s,
-		value:   current.value,
+		value:   s.decode(current.value),
 	}
 }
 
@@ -329,7 +612,7 @@ func (s *SkipList) SeekToLast() Iterator {
 		current: current,
 		key:     current.key,
 		list:    s,
-		value:   current.value,
+		value:   s.decode(current.value),
 	}
 }
 
@@ -337,18 +620,265 @@ func (s *SkipList) SeekToLast() Iterator {
 // elements of the skip list that are greater or equal than from, but
 // less than to.
 func (s *SkipList) Range(from, to interface{}) Iterator {
-	start := s.getLowerBound(s.header, from)
-	return &rangeIterator{
-		iter: iter{
-			current: &node{
-				levels:   []level{level{start, 0}},
-				backward: start,
-			},
-			list: s,
-		},
+	i := &rangeIterator{
+		iter:       iter{list: s},
 		upperLimit: to,
 		lowerLimit: from,
 	}
+	i.Reset()
+	return i
+}
+
+// Merge moves the elements of other, which must be ordered like s, into
+// s, leaving other empty. Keys in both lists take the value they have
+// in other. Rather than inserting the elements one by one, Merge
+// interleaves the nodes of both lists, keeping their levels, and
+// rebuilds the links and spans in a single O(n + m) pass.
+func (s *SkipList) Merge(other *SkipList) {
+	if other == s || other.length == 0 {
+		return
+	}
+	s.unshare()
+	other.unshare()
+
+	top := maxInt(s.level(), other.level())
+	header := &node{levels: make([]level, top+1, maxInt(top, s.levelHint)+1)}
+	// last and lastPos hold the last node linked at every level and its
+	// position.
+	last := make([]*node, top+1)
+	lastPos := make([]int, top+1)
+	for i := range last {
+		last[i] = header
+	}
+	var elements [][2]interface{}
+	var previous *node
+	pos := 0
+	link := func(n *node) {
+		pos++
+		n.backward = previous
+		previous = n
+		for i := range n.levels {
+			last[i].levels[i].forward = n
+			last[i].levels[i].span = uint32(pos - lastPos[i])
+			last[i], lastPos[i] = n, pos
+		}
+		if s.shadow != nil {
+			elements = append(elements, [2]interface{}{n.key, s.decode(n.value)})
+		}
+	}
+
+	a, b := s.header.next(), other.header.next()
+	for a != nil || b != nil {
+		switch {
+		case b == nil || (a != nil && s.lessThan(a.key, b.key)):
+			next := a.next()
+			link(a)
+			a = next
+		case a == nil || s.lessThan(b.key, a.key):
+			next := b.next()
+			if s.filter != nil {
+				s.filter.add(b.key)
+			}
+			if s.quota != nil {
+				s.quota.account(b.key, b.value, 1)
+			}
+			link(b)
+			b = next
+		default:
+			nextA, nextB := a.next(), b.next()
+			if s.quota != nil {
+				s.quota.account(a.key, a.value, -1)
+				s.quota.account(a.key, b.value, 1)
+			}
+			a.value = b.value
+			link(a)
+			a, b = nextA, nextB
+		}
+	}
+	for i := range last {
+		last[i].levels[i].forward = nil
+		last[i].levels[i].span = uint32(pos - lastPos[i])
+	}
+
+	s.header, s.footer, s.length = header, previous, pos
+	s.dropFinger()
+	other.Clear()
+	if s.augment != nil {
+		s.rebuildAggregates()
+	}
+	if s.shadow != nil {
+		s.shadow.fill(s, elements)
+	}
+	if s.quota != nil {
+		s.enforceQuota()
+	}
+}
+
+// Split moves the elements of s whose keys are greater or equal than
+// key to a new list, configured like s, which it returns. Only the
+// links crossing key are cut, so Split takes O(log n) time, plus O(k)
+// for the k moved elements if s has a Bloom filter, a byte limit or an
+// aggregate.
+func (s *SkipList) Split(key interface{}) *SkipList {
+	s.unshare()
+	t := NewWithOptions(s.options()...)
+
+	update, rank := s.scratch()
+	current := s.header
+	for i := s.level(); i >= 0; i-- {
+		if i == s.level() {
+			rank[i] = 0
+		} else {
+			rank[i] = rank[i+1]
+		}
+		for current.levels[i].forward != nil && s.lessThan(current.levels[i].forward.key, key) {
+			rank[i] += current.levels[i].span
+			current = current.levels[i].forward
+		}
+		update[i] = current
+	}
+	first := current.next()
+	if first == nil {
+		return t
+	}
+
+	before := rank[0]
+	moved := s.length - int(before)
+	for i := 0; i <= s.level(); i++ {
+		x := update[i]
+		if i > t.level() {
+			t.header.levels = append(t.header.levels, level{})
+		}
+		t.header.levels[i].forward = x.levels[i].forward
+		if x.levels[i].forward != nil {
+			t.header.levels[i].span = rank[i] + x.levels[i].span - before
+		} else {
+			t.header.levels[i].span = uint32(moved)
+		}
+		x.levels[i].forward = nil
+		x.levels[i].span = before - rank[i]
+	}
+	first.backward = nil
+	t.footer, t.length = s.footer, moved
+	s.footer, s.length = update[0], int(before)
+	s.dropFinger()
+	if s.footer == s.header {
+		s.footer = nil
+	}
+	for _, l := range []*SkipList{s, t} {
+		for l.level() > 0 && l.header.levels[l.level()].forward == nil {
+			l.header.levels = l.header.levels[:l.level()]
+		}
+	}
+
+	if s.filter != nil || s.quota != nil || s.augment != nil {
+		for n := first; n != nil; n = n.next() {
+			if s.augment != nil {
+				t.aggs[n] = s.aggs[n]
+				delete(s.aggs, n)
+			}
+			if s.filter != nil {
+				s.filter.stale++
+				t.filter.add(n.key)
+			}
+			if s.quota != nil {
+				s.quota.account(n.key, n.value, -1)
+				t.quota.account(n.key, n.value, 1)
+			}
+		}
+	}
+	if s.augment != nil {
+		s.fixAggregates(update, nil)
+		for i := 0; i <= t.level(); i++ {
+			t.recomputeAggregate(t.header, i)
+		}
+	}
+	if s.shadow != nil {
+		s.shadow.split(s, t, int(before))
+	}
+	return t
+}
+
+// Clone returns an independent copy of s, configured like s. The nodes
+// are copied structurally, keeping their levels and spans, in O(n)
+// without a single comparison. Keys and values are not deep copied.
+// Snapshot is cheaper when the copy is only read.
+func (s *SkipList) Clone() *SkipList {
+	t := NewWithOptions(s.options()...)
+	t.header, t.footer = s.copyNodes(t)
+	t.length = s.length
+	if t.filter != nil {
+		for n := t.header.next(); n != nil; n = n.next() {
+			t.filter.add(n.key)
+		}
+	}
+	if t.quota != nil {
+		t.quota.total = s.quota.total
+	}
+	if t.shadow != nil {
+		t.shadow.keys = append([]interface{}(nil), s.shadow.keys...)
+		t.shadow.values = append([]interface{}(nil), s.shadow.values...)
+	}
+	return t
+}
+
+// Equal reports whether s and other hold the same number of elements,
+// with equal keys and values in the same order. Keys are compared with
+// the comparison function of s, and values with valueEq, or == if
+// valueEq is nil. It stops at the first difference, in O(n) at worst.
+func (s *SkipList) Equal(other *SkipList, valueEq func(a, b interface{}) bool) bool {
+	if s.length != other.length {
+		return false
+	}
+	if valueEq == nil {
+		valueEq = func(a, b interface{}) bool { return a == b }
+	}
+	for l, r := s.header.next(), other.header.next(); l != nil; l, r = l.next(), r.next() {
+		if !s.equal(l.key, r.key) || !valueEq(s.decode(l.value), other.decode(r.value)) {
+			return false
+		}
+	}
+	return true
+}
+
+// CopyRange returns a new list holding the elements of s that are
+// greater or equal than from, but less than to. The new list is
+// configured like s and filled in bulk, in O(log n + k) for k copied
+// elements; keys and values are not deep copied.
+func (s *SkipList) CopyRange(from, to interface{}) *SkipList {
+	var elements [][2]interface{}
+	for n := s.getLowerBound(s.header, from); n != nil && s.lessThan(n.key, to); n = n.next() {
+		elements = append(elements, [2]interface{}{n.key, s.decode(n.value)})
+	}
+	c := NewWithOptions(s.options()...)
+	c.FillBySortedSlice(elements)
+	return c
+}
+
+// Chunks returns an iterator (an iter.Seq, for use with range) over
+// the elements of s in chunks of up to size elements, for batched
+// writes to databases or message queues. The same buffer is reused for
+// every chunk, so chunks must not be retained across iterations. s must
+// not be modified during the iteration.
+func (s *SkipList) Chunks(size int) func(yield func(chunk [][2]interface{}) bool) {
+	if size <= 0 {
+		panic("goskiplist: chunk size must be positive")
+	}
+	return func(yield func(chunk [][2]interface{}) bool) {
+		buf := make([][2]interface{}, 0, minInt(size, s.length))
+		for n := s.header.next(); n != nil; n = n.next() {
+			buf = append(buf, [2]interface{}{n.key, s.decode(n.value)})
+			if len(buf) == size {
+				if !yield(buf) {
+					return
+				}
+				buf = buf[:0]
+			}
+		}
+		if len(buf) > 0 {
+			yield(buf)
+		}
+	}
 }
 
 func (s *SkipList) level() int {
@@ -362,28 +892,125 @@ func maxInt(x, y int) int {
 	return y
 }
 
+func minInt(x, y int) int {
+	if x < y {
+		return x
+	}
+	return y
+}
+
+// equal returns true if neither of l and r is less than the other,
+// which is how keys are deemed equal by s. Keys are never compared with
+// ==, so that types with distinct representations of the same value
+// (like *big.Int) and types that do not support == (like []byte) work
+// as expected.
+func (s *SkipList) equal(l, r interface{}) bool {
+	return !s.lessThan(l, r) && !s.lessThan(r, l)
+}
+
 func (s *SkipList) effectiveMaxLevel() int {
-	return maxInt(s.level(), s.MaxLevel)
+	return s.maxLevelFor(s.length + 1)
+}
+
+// maxLevelFor returns the maximum level of new nodes once s holds size
+// elements: MaxLevel, lowered to suit size if the list is adaptive (see
+// WithAdaptiveMaxLevel), but never below the current level.
+func (s *SkipList) maxLevelFor(size int) int {
+	maxLevel := s.MaxLevel
+	if s.adaptive {
+		maxLevel = minInt(maxLevel, maxLevelForSize(size, s.p))
+	}
+	return maxInt(s.level(), maxLevel)
+}
+
+// levelCapacity returns the capacity to allocate for slices indexed by
+// level, like the update vectors used during insertion and deletion.
+func (s *SkipList) levelCapacity() int {
+	return maxInt(s.level(), s.levelHint) + 1
+}
+
+// scratch returns update and rank vectors for a search, of length
+// s.level()+1 and with room for the levels a new node may add. They are
+// the same buffers on every call, to save the methods modifying s two
+// allocations, so they must not be kept across calls, nor used by
+// methods that may run while another one is using them.
+func (s *SkipList) scratch() (update []*node, rank []uint32) {
+	if c := maxInt(s.levelCapacity(), s.effectiveMaxLevel()+1); cap(s.scratchNodes) < c {
+		s.scratchNodes = make([]*node, c)
+		s.scratchRanks = make([]uint32, c)
+	}
+	return s.scratchNodes[:s.level()+1], s.scratchRanks[:s.level()+1]
+}
+
+// random returns a pseudo-random number in [0.0,1.0) from the source of
+// randomness of s.
+func (s *SkipList) random() float64 {
+	if s.rand != nil {
+		return s.rand.Float64()
+	}
+	return rand.Float64()
 }
 
 // Returns a new random level.
-func (s SkipList) randomLevel() (n int) {
-	for n = 0; n < s.effectiveMaxLevel() && rand.Float64() < p; n++ {
+func (s *SkipList) randomLevel() (n int) {
+	return s.randomLevelUpTo(s.effectiveMaxLevel())
+}
+
+// randomLevelUpTo returns a new random level, at most maxLevel.
+func (s *SkipList) randomLevelUpTo(maxLevel int) (n int) {
+	for n = 0; n < maxLevel && s.random() < s.p; n++ {
 	}
 	return
 }
 
+// newNode returns a node for key and value with pointers for levels 0
+// to lvl.
+func (s *SkipList) newNode(lvl int, key, value interface{}) *node {
+	var n *node
+	if s.nodeBlockSize > 0 {
+		if len(s.nodeBlock) == 0 {
+			s.nodeBlock = make([]node, s.nodeBlockSize)
+		}
+		n = &s.nodeBlock[0]
+		s.nodeBlock = s.nodeBlock[1:]
+	} else {
+		n = new(node)
+	}
+	n.setLevel(lvl)
+	n.key = key
+	n.value = value
+	return n
+}
+
 // Get returns the value associated with key from s (nil if the key is
 // not present in s). The second return value is true when the key is
 // present.
 func (s *SkipList) Get(key interface{}) (value interface{}, ok bool) {
-	candidate := s.getLowerBound(s.header, key)
+	if s.shadow != nil {
+		defer func() { s.shadow.checkGet(s, key, value, ok) }()
+	}
+	if s.profile != nil {
+		defer s.profile.track(opGet)()
+	}
+	if s.filter != nil && !s.filter.check(key) {
+		return nil, false
+	}
+
+	var candidate *node
+	if s.finger != nil {
+		candidate = s.fingerSearch(key)
+	} else {
+		candidate = s.getLowerBound(s.header, key)
+	}
 
-	if candidate == nil || candidate.key != key {
+	if candidate == nil || !s.equal(candidate.key, key) {
+		if s.filter != nil {
+			s.filter.stats.FalsePositives++
+		}
 		return nil, false
 	}
 
-	return candidate.value, true
+	return s.decode(candidate.value), true
 }
 
 // GetGreaterOrEqual finds the node whose key is greater than or equal
@@ -393,12 +1020,43 @@ func (s *SkipList) GetGreaterOrEqual(min interface{}) (actualKey, value interfac
 	candidate := s.getLowerBound(s.header, min)
 
 	if candidate != nil {
-		return candidate.key, candidate.value, true
+		return candidate.key, s.decode(candidate.value), true
+	}
+	return nil, nil, false
+}
+
+// Higher finds the node with the smallest key strictly greater than
+// key, whether or not key is in s. It returns its key, its value, and
+// whether there is such a node.
+func (s *SkipList) Higher(key interface{}) (actualKey, value interface{}, ok bool) {
+	if candidate := s.lastLessOrEqual(key).next(); candidate != nil {
+		return candidate.key, s.decode(candidate.value), true
+	}
+	return nil, nil, false
+}
+
+// Lower finds the node with the largest key strictly less than key,
+// whether or not key is in s. It returns its key, its value, and
+// whether there is such a node.
+func (s *SkipList) Lower(key interface{}) (actualKey, value interface{}, ok bool) {
+	if candidate := s.lastLess(key); candidate != s.header {
+		return candidate.key, s.decode(candidate.value), true
 	}
 	return nil, nil, false
 }
 
 func (s *SkipList) Rank(key interface{}) uint32 {
+	if s.profile != nil {
+		defer s.profile.track(opRank)()
+	}
+	rank := s.rank(key)
+	if s.shadow != nil {
+		s.shadow.checkRank(s, key, rank)
+	}
+	return rank
+}
+
+func (s *SkipList) rank(key interface{}) uint32 {
 	current := s.header
 	var rank uint32
 	for i := s.level(); i >= 0; i-- {
@@ -406,14 +1064,83 @@ func (s *SkipList) Rank(key interface{}) uint32 {
 			rank += current.levels[i].span
 			current = current.levels[i].forward
 		}
-		if current.levels[i].forward != nil && current.levels[i].forward.key == key {
+		if current.levels[i].forward != nil && !s.lessThan(key, current.levels[i].forward.key) {
 			return rank + current.levels[i].span
 		}
 	}
 	return 0
 }
 
+// A RankedKey is a key present in two skip lists, together with its
+// rank in each of them.
+type RankedKey struct {
+	Key       interface{}
+	Rank      uint32
+	OtherRank uint32
+}
+
+// IntersectKeys returns the keys present both in s and in other, in
+// order, along with their ranks in both lists. It walks the two lists
+// side by side, so it takes O(s.Len() + other.Len()) time. Both lists
+// must use the same ordering.
+func (s *SkipList) IntersectKeys(other *SkipList) []RankedKey {
+	var common []RankedKey
+	l, r := s.header.next(), other.header.next()
+	var lRank, rRank uint32 = 1, 1
+	for l != nil && r != nil {
+		switch {
+		case s.lessThan(l.key, r.key):
+			l = l.next()
+			lRank++
+		case s.lessThan(r.key, l.key):
+			r = r.next()
+			rRank++
+		default:
+			common = append(common, RankedKey{Key: l.key, Rank: lRank, OtherRank: rRank})
+			l, r = l.next(), r.next()
+			lRank++
+			rRank++
+		}
+	}
+	return common
+}
+
+// countLess returns the number of elements of s whose keys are less
+// than key.
+func (s *SkipList) countLess(key interface{}) uint32 {
+	current := s.header
+	var rank uint32
+	for i := s.level(); i >= 0; i-- {
+		for current.levels[i].forward != nil && s.lessThan(current.levels[i].forward.key, key) {
+			rank += current.levels[i].span
+			current = current.levels[i].forward
+		}
+	}
+	return rank
+}
+
 func (s *SkipList) GetElemByRank(rank uint32) Iterator {
+	if rank == 0 {
+		return nil
+	}
+	current := s.nodeByRank(rank)
+	if current == nil {
+		return nil
+	}
+	return &iter{
+		current: current,
+		key:     current.key,
+		list:    s,
+		value:   s.decode(current.value),
+	}
+}
+
+// nodeByRank returns the node with the given rank, the header for rank
+// 0, or nil if rank is greater than the length of s.
+func (s *SkipList) nodeByRank(rank uint32) *node {
+	if rank == 0 {
+		return s.header
+	}
 	current := s.header
 	var traversed uint32
 	for i := s.level(); i >= 0; i-- {
@@ -422,17 +1149,15 @@ func (s *SkipList) GetElemByRank(rank uint32) Iterator {
 			current = current.levels[i].forward
 		}
 		if current.levels[i].forward != nil && traversed+current.levels[i].span == rank {
-			return &iter{
-				current: current.levels[i].forward,
-				key:     current.levels[i].forward.key,
-				list:    s,
-				value:   current.levels[i].forward.value,
-			}
+			return current.levels[i].forward
 		}
 	}
 	return nil
 }
 
+// getLowerBound returns the first node after current whose key is
+// greater or equal to key. It only uses lessThan, so it also works for
+// keys that cannot be compared with == (like []byte).
 func (s *SkipList) getLowerBound(current *node, key interface{}) *node {
 	depth := len(current.levels) - 1
 
@@ -440,13 +1165,14 @@ func (s *SkipList) getLowerBound(current *node, key interface{}) *node {
 		for current.levels[i].forward != nil && s.lessThan(current.levels[i].forward.key, key) {
 			current = current.levels[i].forward
 		}
-		if current.levels[i].forward != nil && current.levels[i].forward.key == key {
-			return current.levels[i].forward
-		}
 	}
 	return current.next()
 }
 
+// searchForInsert returns the node with key if there is one, and
+// otherwise fills update with the last node before key at every level,
+// and rank with their ranks. Either way, rank[0] ends up being the
+// number of nodes before key.
 func (s *SkipList) searchForInsert(key interface{}, update []*node, rank []uint32) *node {
 	current := s.header
 	for i := s.level(); i >= 0; i-- {
@@ -459,7 +1185,8 @@ func (s *SkipList) searchForInsert(key interface{}, update []*node, rank []uint3
 			rank[i] += current.levels[i].span
 			current = current.levels[i].forward
 		}
-		if current.levels[i].forward != nil && current.levels[i].forward.key == key {
+		if current.levels[i].forward != nil && !s.lessThan(key, current.levels[i].forward.key) {
+			rank[0] = rank[i] + current.levels[i].span - 1
 			return current.levels[i].forward
 		}
 		update[i] = current
@@ -470,18 +1197,171 @@ func (s *SkipList) searchForInsert(key interface{}, update []*node, rank []uint3
 // Sets set the value associated with key in s.
 func (s *SkipList) Set(key, value interface{}) {
 	if key == nil {
-		panic("goskiplist: nil keys are not supported")
+		panic(ErrNilKey)
+	}
+	if s.profile != nil {
+		defer s.profile.track(opSet)()
 	}
-	// s.level starts from 0, so we need to allocate one.
-	update := make([]*node, s.level()+1, s.effectiveMaxLevel()+1)
-	rank := make([]uint32, s.level()+1, s.effectiveMaxLevel()+1)
+	s.unshare()
+	update, rank := s.scratch()
+	var candidate *node
+	if s.finger != nil {
+		candidate = s.fingerSearch(key)
+		copy(update, s.finger.preds)
+		copy(rank, s.finger.ranks)
+	} else {
+		candidate = s.searchForInsert(key, update, rank)
+	}
+
+	if candidate != nil && s.equal(candidate.key, key) {
+		s.updateValue(candidate, value)
+		return
+	}
+	s.insert(key, value, update, rank)
+}
+
+// SetReturning is like Set, also returning the value key had before,
+// whether it was in s, and the rank of key, all found by the same
+// search.
+func (s *SkipList) SetReturning(key, value interface{}) (prev interface{}, existed bool, rank uint32) {
+	if key == nil {
+		panic(ErrNilKey)
+	}
+	if s.profile != nil {
+		defer s.profile.track(opSet)()
+	}
+	s.unshare()
+	update, ranks := s.scratch()
+	candidate := s.searchForInsert(key, update, ranks)
+	rank = ranks[0] + 1
+
+	if candidate != nil && s.equal(candidate.key, key) {
+		prev = s.decode(candidate.value)
+		s.updateValue(candidate, value)
+		return prev, true, rank
+	}
+	s.insert(key, value, update, ranks)
+	return nil, false, rank
+}
+
+// GetOrSet returns the value of key if it is in s, like Get, and
+// otherwise sets it to value, like Set, in a single search. loaded is
+// true if the value was already there.
+func (s *SkipList) GetOrSet(key, value interface{}) (actual interface{}, loaded bool) {
+	if key == nil {
+		panic(ErrNilKey)
+	}
+	if s.profile != nil {
+		defer s.profile.track(opSet)()
+	}
+	s.unshare()
+	update, rank := s.scratch()
 	candidate := s.searchForInsert(key, update, rank)
 
-	if candidate != nil && candidate.key == key {
-		candidate.value = value
+	if candidate != nil && s.equal(candidate.key, key) {
+		return s.decode(candidate.value), true
+	}
+	s.insert(key, value, update, rank)
+	return value, false
+}
+
+// SetIfAbsent sets the value of key only if key is not in s, like
+// the NX option of Redis. It returns true if it did.
+func (s *SkipList) SetIfAbsent(key, value interface{}) bool {
+	_, loaded := s.GetOrSet(key, value)
+	return !loaded
+}
+
+// SetIfPresent sets the value of key only if key is already in s, like
+// the XX option of Redis. It returns true if it did.
+func (s *SkipList) SetIfPresent(key, value interface{}) bool {
+	if key == nil {
+		panic(ErrNilKey)
+	}
+	if s.profile != nil {
+		defer s.profile.track(opSet)()
+	}
+	s.unshare()
+	candidate := s.getLowerBound(s.header, key)
+	if candidate == nil || !s.equal(candidate.key, key) {
+		return false
+	}
+	s.updateValue(candidate, value)
+	return true
+}
+
+// CompareAndSwap sets the value of key to new only if key is in s with
+// the value old, compared with ==, and returns true if it did. Values
+// that are not comparable make it panic.
+func (s *SkipList) CompareAndSwap(key, old, new interface{}) bool {
+	if key == nil {
+		panic(ErrNilKey)
+	}
+	if s.profile != nil {
+		defer s.profile.track(opSet)()
+	}
+	s.unshare()
+	candidate := s.getLowerBound(s.header, key)
+	if candidate == nil || !s.equal(candidate.key, key) || s.decode(candidate.value) != old {
+		return false
+	}
+	s.updateValue(candidate, new)
+	return true
+}
+
+// UpdateFunc finds key once and calls fn with its value and whether it
+// is in s. If fn returns keep, key is set to new, being inserted if it
+// was missing; otherwise key is deleted if it was present. This avoids
+// a second search in read-modify-write patterns, like counters. fn must
+// not modify s.
+func (s *SkipList) UpdateFunc(key interface{}, fn func(old interface{}, exists bool) (new interface{}, keep bool)) {
+	if key == nil {
+		panic(ErrNilKey)
+	}
+	if s.profile != nil {
+		defer s.profile.track(opSet)()
+	}
+	s.unshare()
+	update, rank := s.scratch()
+	current := s.header
+	for i := s.level(); i >= 0; i-- {
+		if i == s.level() {
+			rank[i] = 0
+		} else {
+			rank[i] = rank[i+1]
+		}
+		for current.levels[i].forward != nil && s.lessThan(current.levels[i].forward.key, key) {
+			rank[i] += current.levels[i].span
+			current = current.levels[i].forward
+		}
+		update[i] = current
+	}
+
+	var old interface{}
+	candidate := current.next()
+	exists := candidate != nil && s.equal(candidate.key, key)
+	if exists {
+		old = s.decode(candidate.value)
+	}
+	new, keep := fn(old, exists)
+	switch {
+	case !keep:
+		if exists {
+			s.deleteNode(candidate, update)
+		}
 		return
+	case exists:
+		s.updateValue(candidate, new)
+	default:
+		s.insert(key, new, update, rank)
 	}
+}
 
+// insert adds a node for key, which is not in s, given the last node
+// before it at every level and their ranks, as found by
+// searchForInsert. Like the other methods modifying the elements of s,
+// it updates the shadow of s and enforces its byte limit, if any.
+func (s *SkipList) insert(key, value interface{}, update []*node, rank []uint32) {
 	newLevel := s.randomLevel()
 
 	if currentLevel := s.level(); newLevel > currentLevel {
@@ -496,10 +1376,12 @@ func (s *SkipList) Set(key, value interface{}) {
 		}
 	}
 
-	newNode := &node{
-		levels: make([]level, newLevel+1, s.effectiveMaxLevel()+1),
-		key:    key,
-		value:  value,
+	newNode := s.newNode(newLevel, s.intern(key), s.encode(value))
+	if s.filter != nil {
+		s.filter.add(key)
+	}
+	if s.quota != nil {
+		s.quota.account(key, newNode.value, 1)
 	}
 
 	if previous := update[0]; previous.key != nil {
@@ -529,18 +1411,96 @@ func (s *SkipList) Set(key, value interface{}) {
 	if s.footer == nil || s.lessThan(s.footer.key, key) {
 		s.footer = newNode
 	}
+
+	if s.augment != nil {
+		s.fixAggregates(update, newNode)
+	}
+	// The nodes before newNode keep their ranks.
+	s.saveFinger(update, rank)
+	if s.shadow != nil {
+		s.shadow.set(s, key, value)
+	}
+	if s.quota != nil {
+		s.enforceQuota()
+	}
+}
+
+// updateValue replaces the value of n, and enforces the byte limit of
+// s, if any.
+func (s *SkipList) updateValue(n *node, value interface{}) {
+	s.setValue(n, value)
+	if s.quota != nil {
+		s.enforceQuota()
+	}
+}
+
+// setValue is like updateValue, without evicting any element.
+func (s *SkipList) setValue(n *node, value interface{}) {
+	if s.shadow != nil {
+		s.shadow.set(s, n.key, value)
+	}
+	value = s.encode(value)
+	if s.quota != nil {
+		s.quota.account(n.key, n.value, -1)
+		s.quota.account(n.key, value, 1)
+	}
+	n.value = value
+	if s.augment != nil {
+		update, _ := s.scratch()
+		s.searchForDelete(s.header, n.key, update)
+		s.fixAggregates(update, nil)
+	}
+}
+
+// SwapValues exchanges the values of the elements with keys k1 and k2,
+// finding both in a single search. It returns false, leaving s
+// unchanged, if either key is missing.
+func (s *SkipList) SwapValues(k1, k2 interface{}) bool {
+	s.unshare()
+	if s.lessThan(k2, k1) {
+		k1, k2 = k2, k1
+	}
+	n1 := s.getLowerBound(s.header, k1)
+	if n1 == nil || !s.equal(n1.key, k1) {
+		return false
+	}
+	// The search for the greater key resumes from the first one.
+	n2 := n1
+	if s.lessThan(k1, k2) {
+		n2 = s.getLowerBound(n1, k2)
+		if n2 == nil || !s.equal(n2.key, k2) {
+			return false
+		}
+	}
+	v1, v2 := s.decode(n1.value), s.decode(n2.value)
+	// Evicting elements between the two would unlink n2.
+	s.setValue(n1, v2)
+	s.setValue(n2, v1)
+	if s.quota != nil {
+		s.enforceQuota()
+	}
+	return true
 }
 
 func (s *SkipList) FillBySortedSlice(elements [][2]interface{}) bool {
+	maxLevel := s.maxLevelFor(s.length + len(elements))
+	return s.fill(elements, func(int) int { return s.randomLevelUpTo(maxLevel) })
+}
+
+// fill implements FillBySortedSlice, giving the element at position pos
+// the level levelOf(pos).
+func (s *SkipList) fill(elements [][2]interface{}, levelOf func(pos int) int) bool {
 	if s.Len() != 0 {
-		panic("goskiplist: can only fill empty skiplist")
+		panic(ErrNotEmpty)
 	}
+	s.unshare()
+	s.dropFinger()
 
-	update := make([]*node, s.level()+1, s.effectiveMaxLevel()+1)
+	update, _ := s.scratch()
 	update[0] = s.header
 
 	for pos, elem := range elements {
-		newLevel := s.randomLevel()
+		newLevel := levelOf(pos)
 
 		if currentLevel := s.level(); newLevel > currentLevel {
 			// there are no pointers for the higher levels in
@@ -553,16 +1513,18 @@ func (s *SkipList) FillBySortedSlice(elements [][2]interface{}) bool {
 			}
 		}
 
-		newNode := &node{
-			levels: make([]level, newLevel+1, s.effectiveMaxLevel()+1),
-			key:    elem[0],
-			value:  elem[1],
+		newNode := s.newNode(newLevel, s.intern(elem[0]), s.encode(elem[1]))
+		if s.filter != nil {
+			s.filter.add(newNode.key)
+		}
+		if s.quota != nil {
+			s.quota.account(newNode.key, newNode.value, 1)
 		}
 
 		if update[0] != s.header {
 			newNode.backward = update[0]
 			if !s.lessThan(update[0].key, newNode.key) {
-				panic("goskiplist: fill by unsorted slice")
+				panic(ErrUnsortedInput)
 			}
 		}
 
@@ -579,35 +1541,353 @@ func (s *SkipList) FillBySortedSlice(elements [][2]interface{}) bool {
 		s.footer = newNode
 		s.length++
 	}
+
+	if s.augment != nil {
+		s.rebuildAggregates()
+	}
+	if s.shadow != nil {
+		s.shadow.fill(s, elements)
+	}
+	if s.quota != nil {
+		s.enforceQuota()
+	}
 	return true
 }
 
+// RebuildOptimal rebuilds s with an ideal level assignment: every
+// 1/p-th node is promoted to level 1, every (1/p)^2-th to level 2, and
+// so on up to MaxLevel. Searches then take the same number of steps
+// for every key, which suits read-only phases following bulk loads.
+// Subsequent insertions draw random levels as usual. Adaptive lists
+// (see WithAdaptiveMaxLevel) also drop the levels their current size
+// does not need.
+//
+// The nodes are reallocated, so iterators on s must not be used
+// afterwards.
+func (s *SkipList) RebuildOptimal() {
+	elements := make([][2]interface{}, 0, s.length)
+	for n := s.header.next(); n != nil; n = n.next() {
+		elements = append(elements, [2]interface{}{n.key, s.decode(n.value)})
+	}
+	step := int(math.Round(1 / s.p))
+	if step < 2 {
+		step = 2
+	}
+	s.Clear()
+	maxLevel := s.maxLevelFor(len(elements))
+	s.fill(elements, func(pos int) (lvl int) {
+		for pos++; lvl < maxLevel && pos%step == 0; pos /= step {
+			lvl++
+		}
+		return lvl
+	})
+}
+
+// RepairSpans rebuilds the structure of s derived from its lowest
+// level: the links of the upper levels, from the level of each node,
+// their spans, the backward links, the footer and the length, in
+// O(n*levels) time. It is a recovery tool for long-lived processes in
+// which a bug corrupted s, as detected by CheckShadow or wrong ranks,
+// and returns the number of fields it had to correct, 0 meaning that s
+// was consistent. Elements missing from the lowest level cannot be
+// recovered.
+func (s *SkipList) RepairSpans() (repaired int) {
+	s.unshare()
+	s.dropFinger()
+	fix := func(field *uint32, want uint32) {
+		if *field != want {
+			*field = want
+			repaired++
+		}
+	}
+	last := make([]*node, s.level()+1)
+	lastPos := make([]int, s.level()+1)
+	for i := range last {
+		last[i] = s.header
+	}
+	var previous *node
+	pos := 0
+	for n := s.header.next(); n != nil; n = n.next() {
+		pos++
+		if n.backward != previous {
+			n.backward = previous
+			repaired++
+		}
+		previous = n
+		for i := 1; i < len(n.levels) && i < len(last); i++ {
+			if last[i].levels[i].forward != n {
+				last[i].levels[i].forward = n
+				repaired++
+			}
+			fix(&last[i].levels[i].span, uint32(pos-lastPos[i]))
+			last[i], lastPos[i] = n, pos
+		}
+		fix(&last[0].levels[0].span, 1)
+		last[0], lastPos[0] = n, pos
+	}
+	for i := range last {
+		if last[i].levels[i].forward != nil {
+			last[i].levels[i].forward = nil
+			repaired++
+		}
+		fix(&last[i].levels[i].span, uint32(pos-lastPos[i]))
+	}
+	if s.footer != previous {
+		s.footer = previous
+		repaired++
+	}
+	if s.length != pos {
+		s.length = pos
+		repaired++
+	}
+	if repaired > 0 && s.augment != nil {
+		s.rebuildAggregates()
+	}
+	return repaired
+}
+
 func (s *SkipList) searchForDelete(current *node, key interface{}, update []*node) *node {
+	candidate, _ := s.searchForDeleteRank(current, key, update)
+	return candidate
+}
+
+// searchForDeleteRank is like searchForDelete, also returning the
+// number of nodes between current and the candidate, which is its rank
+// if current is the header.
+func (s *SkipList) searchForDeleteRank(current *node, key interface{}, update []*node) (*node, uint32) {
 	depth := len(current.levels) - 1
 
+	var traversed uint32
 	for i := depth; i >= 0; i-- {
 		for current.levels[i].forward != nil && s.lessThan(current.levels[i].forward.key, key) {
+			traversed += current.levels[i].span
 			current = current.levels[i].forward
 		}
 		update[i] = current
 	}
-	return current.next()
+	return current.next(), traversed + 1
 }
 
 // Delete removes the node with the given key.
 //
 // It returns the old value and whether the node was present.
 func (s *SkipList) Delete(key interface{}) (value interface{}, ok bool) {
+	value, _, ok = s.DeleteWithRank(key)
+	return value, ok
+}
+
+// DeleteWithRank is like Delete, also returning the rank the element
+// had, found by the same search, instead of calling Rank beforehand.
+func (s *SkipList) DeleteWithRank(key interface{}) (value interface{}, rank uint32, ok bool) {
 	if key == nil {
-		panic("goskiplist: nil keys are not supported")
+		panic(ErrNilKey)
+	}
+	if s.profile != nil {
+		defer s.profile.track(opDelete)()
+	}
+	s.unshare()
+	update, ranks := s.scratch()
+	var candidate *node
+	if s.finger != nil {
+		candidate = s.fingerSearch(key)
+		copy(update, s.finger.preds)
+		copy(ranks, s.finger.ranks)
+		rank = ranks[0] + 1
+	} else {
+		candidate, rank = s.searchForDeleteRank(s.header, key, update)
 	}
-	update := make([]*node, s.level()+1, s.effectiveMaxLevel())
-	candidate := s.searchForDelete(s.header, key, update)
 
-	if candidate == nil || candidate.key != key {
-		return nil, false
+	if candidate == nil || !s.equal(candidate.key, key) {
+		if s.shadow != nil {
+			s.shadow.delete(s, key, nil, false)
+		}
+		return nil, 0, false
+	}
+
+	s.deleteNode(candidate, update)
+	if s.finger != nil {
+		// The nodes before candidate keep their ranks.
+		s.saveFinger(update, ranks)
+	}
+	return s.decode(candidate.value), rank, true
+}
+
+// DeleteByRank removes the element with the given rank, starting from
+// 1 as in Rank, found with a single search. It returns its key and
+// value, and false if rank is 0 or greater than the length of s.
+func (s *SkipList) DeleteByRank(rank uint32) (key, value interface{}, ok bool) {
+	if rank == 0 || rank > uint32(s.length) {
+		return nil, nil, false
+	}
+	if s.profile != nil {
+		defer s.profile.track(opDelete)()
+	}
+	s.unshare()
+	update, _ := s.scratch()
+	current := s.header
+	var traversed uint32
+	for i := s.level(); i >= 0; i-- {
+		for current.levels[i].forward != nil && traversed+current.levels[i].span < rank {
+			traversed += current.levels[i].span
+			current = current.levels[i].forward
+		}
+		update[i] = current
+	}
+
+	candidate := current.next()
+	s.deleteNode(candidate, update)
+	return candidate.key, s.decode(candidate.value), true
+}
+
+// DeleteRange removes the elements whose keys are greater or equal than
+// from, but less than to, unlinking them all in a single traversal. It
+// returns the number of elements removed.
+func (s *SkipList) DeleteRange(from, to interface{}) (removed int) {
+	s.unshare()
+	update, _ := s.scratch()
+	s.searchForDelete(s.header, from, update)
+
+	var last *node
+	for n := update[0].next(); n != nil && s.lessThan(n.key, to); n = n.next() {
+		last = n
+	}
+	if last == nil {
+		return 0
+	}
+	return s.unlinkRange(update, last)
+}
+
+// DeleteRangeByRank removes the elements whose ranks are between from
+// and to, both included, and returns them in order. Ranks start at 1,
+// as in Rank, and the ranks beyond the length of s are ignored.
+func (s *SkipList) DeleteRangeByRank(from, to uint32) [][2]interface{} {
+	if from == 0 {
+		from = 1
+	}
+	if to > uint32(s.length) {
+		to = uint32(s.length)
+	}
+	if from > to {
+		return nil
+	}
+	s.unshare()
+	update, _ := s.scratch()
+	current := s.header
+	var traversed uint32
+	for i := s.level(); i >= 0; i-- {
+		for current.levels[i].forward != nil && traversed+current.levels[i].span < from {
+			traversed += current.levels[i].span
+			current = current.levels[i].forward
+		}
+		update[i] = current
+	}
+
+	removed := make([][2]interface{}, 0, to-from+1)
+	last := current
+	for rank := from; rank <= to; rank++ {
+		last = last.next()
+		removed = append(removed, [2]interface{}{last.key, s.decode(last.value)})
 	}
+	s.unlinkRange(update, last)
+	return removed
+}
+
+// unlinkRange removes the nodes following update[0] up to last, which
+// must be one of them, given the last node before them at every level
+// in update. It returns the number of nodes removed.
+func (s *SkipList) unlinkRange(update []*node, last *node) (removed int) {
+	s.dropFinger()
+	var keys []interface{}
+	for n := update[0].next(); ; n = n.next() {
+		removed++
+		if s.filter != nil {
+			s.filter.stale++
+		}
+		if s.quota != nil {
+			s.quota.account(n.key, n.value, -1)
+		}
+		if s.augment != nil {
+			delete(s.aggs, n)
+		}
+		if s.shadow != nil {
+			keys = append(keys, n.key)
+		}
+		if n == last {
+			break
+		}
+	}
+
+	for i := 0; i <= s.level(); i++ {
+		x := update[i]
+		// The distance from x to the first node kept after it shrinks
+		// by the number of nodes removed.
+		span := x.levels[i].span
+		y := x.levels[i].forward
+		for y != nil && !s.lessThan(last.key, y.key) {
+			span += y.levels[i].span
+			y = y.levels[i].forward
+		}
+		x.levels[i].forward = y
+		x.levels[i].span = span - uint32(removed)
+	}
+
+	previous := update[0]
+	if previous == s.header {
+		previous = nil
+	}
+	if next := update[0].next(); next != nil {
+		next.backward = previous
+	} else {
+		s.footer = previous
+	}
+
+	for s.level() > 0 && s.header.levels[s.level()].forward == nil {
+		s.header.levels = s.header.levels[:s.level()]
+	}
+	s.length -= removed
+	if s.augment != nil {
+		s.fixAggregates(update, nil)
+	}
+	if s.shadow != nil {
+		s.shadow.deleteKeys(s, keys)
+	}
+	return removed
+}
+
+// deleteSorted deletes the elements with the given keys, which must be
+// sorted in ascending order, in a single traversal of s. It returns the
+// number of elements deleted.
+func (s *SkipList) deleteSorted(keys []interface{}) (deleted int) {
+	s.unshare()
+	update, _ := s.scratch()
+	for i := range update {
+		update[i] = s.header
+	}
+	for _, key := range keys {
+		// Deletions may have lowered the list; the nodes left in update
+		// still precede key, so the search resumes from them.
+		update = update[:s.level()+1]
+		current := update[len(update)-1]
+		for i := len(update) - 1; i >= 0; i-- {
+			for current.levels[i].forward != nil && s.lessThan(current.levels[i].forward.key, key) {
+				current = current.levels[i].forward
+			}
+			update[i] = current
+		}
+		candidate := current.next()
+		if candidate == nil || !s.equal(candidate.key, key) {
+			continue
+		}
+		s.deleteNode(candidate, update)
+		deleted++
+	}
+	return deleted
+}
 
+// deleteNode unlinks candidate, given update, the last nodes preceding
+// it at every level.
+func (s *SkipList) deleteNode(candidate *node, update []*node) {
+	s.dropFinger()
 	previous := candidate.backward
 	if s.footer == candidate {
 		s.footer = previous
@@ -631,27 +1911,194 @@ func (s *SkipList) Delete(key interface{}) (value interface{}, ok bool) {
 		s.header.levels = s.header.levels[:s.level()]
 	}
 	s.length--
+	if s.filter != nil {
+		s.filter.stale++
+	}
+	if s.quota != nil {
+		s.quota.account(candidate.key, candidate.value, -1)
+	}
+	if s.augment != nil {
+		delete(s.aggs, candidate)
+		s.fixAggregates(update, nil)
+	}
+	if s.shadow != nil {
+		s.shadow.delete(s, candidate.key, candidate.value, true)
+	}
+}
+
+// The errors of invalid arguments. The methods without a Try prefix
+// panic with them, and the Try* methods return them.
+var (
+	// ErrNilKey is the error of using a nil key, which is reserved for
+	// the header of lists.
+	ErrNilKey = errors.New("goskiplist: nil keys are not supported")
+	// ErrNotEmpty is the error of filling a list that is not empty.
+	ErrNotEmpty = errors.New("goskiplist: can only fill empty skiplist")
+	// ErrUnsortedInput is the error of filling a list with elements
+	// that are not sorted by key, or have duplicate keys.
+	ErrUnsortedInput = errors.New("goskiplist: fill by unsorted slice")
+)
+
+// A ComparatorError is returned by the Try* methods when the comparison
+// function panics, typically because of a failed type assertion on a
+// key of an unexpected type.
+type ComparatorError struct {
+	// Left and Right are the arguments lessThan was called with.
+	Left, Right interface{}
+	// Panic is the value the comparison function panicked with.
+	Panic interface{}
+}
+
+func (e *ComparatorError) Error() string {
+	return fmt.Sprintf("goskiplist: comparing %T with %T panicked: %v", e.Left, e.Right, e.Panic)
+}
+
+// safeLessThan calls lessThan, converting a panic into a
+// ComparatorError.
+func (s *SkipList) safeLessThan(l, r interface{}) (less bool, err error) {
+	defer func() {
+		if x := recover(); x != nil {
+			err = &ComparatorError{Left: l, Right: r, Panic: x}
+		}
+	}()
+	return s.lessThan(l, r), nil
+}
+
+// comparatorFault repeats the descent of a search for key using
+// safeLessThan, returning the error for the first comparison that
+// panics, or nil if none does. Searches are deterministic, so this
+// finds the comparison that made the original search panic.
+func (s *SkipList) comparatorFault(key interface{}) error {
+	current := s.header
+	for i := s.level(); i >= 0; i-- {
+		for current.levels[i].forward != nil {
+			less, err := s.safeLessThan(current.levels[i].forward.key, key)
+			if err != nil {
+				return err
+			}
+			if !less {
+				break
+			}
+			current = current.levels[i].forward
+		}
+	}
+	return nil
+}
 
-	return candidate.value, true
+// recoverComparator is deferred by the Try* methods. If the method
+// panicked because of the comparison function it stores the
+// corresponding ComparatorError in err, otherwise it propagates the
+// panic.
+func (s *SkipList) recoverComparator(key interface{}, err *error) {
+	x := recover()
+	if x == nil {
+		return
+	}
+	if *err = s.comparatorFault(key); *err == nil {
+		panic(x)
+	}
+}
+
+// TryGet is like Get, but returns a ComparatorError instead of
+// panicking if the comparison function panics on key.
+func (s *SkipList) TryGet(key interface{}) (value interface{}, ok bool, err error) {
+	defer s.recoverComparator(key, &err)
+	value, ok = s.Get(key)
+	return
+}
+
+// TrySet is like Set, but returns ErrNilKey instead of panicking if
+// key is nil, or a ComparatorError if the comparison function panics
+// on key.
+func (s *SkipList) TrySet(key, value interface{}) (err error) {
+	if key == nil {
+		return ErrNilKey
+	}
+	defer s.recoverComparator(key, &err)
+	s.Set(key, value)
+	return
+}
+
+// TryDelete is like Delete, but returns ErrNilKey instead of panicking
+// if key is nil, or a ComparatorError if the comparison function
+// panics on key.
+func (s *SkipList) TryDelete(key interface{}) (value interface{}, ok bool, err error) {
+	if key == nil {
+		return nil, false, ErrNilKey
+	}
+	defer s.recoverComparator(key, &err)
+	value, ok = s.Delete(key)
+	return
+}
+
+// An UnsortedInputError is returned by FillBySortedSliceChecked for
+// elements that are not sorted by key or have duplicate keys. It wraps
+// ErrUnsortedInput.
+type UnsortedInputError struct {
+	// Index is the position of the first element out of order, and
+	// Prev and Key the keys of the elements at Index-1 and Index.
+	Index     int
+	Prev, Key interface{}
+	// Duplicate is true if Prev and Key are equal.
+	Duplicate bool
+}
+
+func (e *UnsortedInputError) Error() string {
+	if e.Duplicate {
+		return fmt.Sprintf("goskiplist: fill by unsorted slice: duplicate key %v at index %d", e.Key, e.Index)
+	}
+	return fmt.Sprintf("goskiplist: fill by unsorted slice: key %v at index %d is less than %v", e.Key, e.Index, e.Prev)
+}
+
+func (e *UnsortedInputError) Unwrap() error {
+	return ErrUnsortedInput
+}
+
+// FillBySortedSliceChecked is like FillBySortedSlice, but checks all
+// the elements before inserting any, and returns an error instead of
+// panicking: ErrNotEmpty if s is not empty, ErrNilKey if elements
+// holds a nil key, an UnsortedInputError if it is not sorted, or a
+// ComparatorError if the comparison function panics. s is left
+// unchanged on errors.
+func (s *SkipList) FillBySortedSliceChecked(elements [][2]interface{}) error {
+	if s.Len() != 0 {
+		return ErrNotEmpty
+	}
+	for i, elem := range elements {
+		if elem[0] == nil {
+			return ErrNilKey
+		}
+		if i == 0 {
+			continue
+		}
+		prev := elements[i-1][0]
+		less, err := s.safeLessThan(prev, elem[0])
+		if err != nil {
+			return err
+		}
+		if !less {
+			greater, err := s.safeLessThan(elem[0], prev)
+			if err != nil {
+				return err
+			}
+			return &UnsortedInputError{Index: i, Prev: prev, Key: elem[0], Duplicate: !greater}
+		}
+	}
+	s.FillBySortedSlice(elements)
+	return nil
 }
 
 // NewCustomMap returns a new SkipList that will use lessThan as the
 // comparison function. lessThan should define a linear order on keys
 // you intend to use with the SkipList.
-func NewCustomMap(lessThan func(l, r interface{}) bool) *SkipList {
-	return &SkipList{
-		lessThan: lessThan,
-		header: &node{
-			levels: []level{level{}},
-		},
-		MaxLevel: DefaultMaxLevel,
-	}
+func NewCustomMap(lessThan func(l, r interface{}) bool, opts ...Option) *SkipList {
+	return NewWithOptions(append([]Option{WithComparator(lessThan)}, opts...)...)
 }
 
 // Ordered is an interface which can be linearly ordered by the
 // LessThan method, whereby this instance is deemed to be less than
-// other. Additionally, Ordered instances should behave properly when
-// compared using == and !=.
+// other. Two Ordered instances are considered equal when neither is
+// less than the other.
 type Ordered interface {
 	LessThan(other Ordered) bool
 }
@@ -659,26 +2106,26 @@ type Ordered interface {
 // New returns a new SkipList.
 //
 // Its keys must implement the Ordered interface.
-func New() *SkipList {
+func New(opts ...Option) *SkipList {
 	comparator := func(left, right interface{}) bool {
 		return left.(Ordered).LessThan(right.(Ordered))
 	}
-	return NewCustomMap(comparator)
+	return NewCustomMap(comparator, opts...)
 
 }
 
 // NewIntKey returns a SkipList that accepts int keys.
-func NewIntMap() *SkipList {
+func NewIntMap(opts ...Option) *SkipList {
 	return NewCustomMap(func(l, r interface{}) bool {
 		return l.(int) < r.(int)
-	})
+	}, opts...)
 }
 
 // NewStringMap returns a SkipList that accepts string keys.
-func NewStringMap() *SkipList {
+func NewStringMap(opts ...Option) *SkipList {
 	return NewCustomMap(func(l, r interface{}) bool {
 		return l.(string) < r.(string)
-	})
+	}, opts...)
 }
 
 // Set is an ordered set data structure.
@@ -698,38 +2145,32 @@ type Set struct {
 }
 
 // NewSet returns a new Set.
-func NewSet() *Set {
+func NewSet(opts ...Option) *Set {
 	comparator := func(left, right interface{}) bool {
 		return left.(Ordered).LessThan(right.(Ordered))
 	}
-	return NewCustomSet(comparator)
+	return NewCustomSet(comparator, opts...)
 }
 
 // NewCustomSet returns a new Set that will use lessThan as the
 // comparison function. lessThan should define a linear order on
 // elements you intend to use with the Set.
-func NewCustomSet(lessThan func(l, r interface{}) bool) *Set {
-	return &Set{skiplist: SkipList{
-		lessThan: lessThan,
-		header: &node{
-			levels: []level{level{}},
-		},
-		MaxLevel: DefaultMaxLevel,
-	}}
+func NewCustomSet(lessThan func(l, r interface{}) bool, opts ...Option) *Set {
+	return &Set{skiplist: *NewCustomMap(lessThan, opts...)}
 }
 
 // NewIntSet returns a new Set that accepts int elements.
-func NewIntSet() *Set {
+func NewIntSet(opts ...Option) *Set {
 	return NewCustomSet(func(l, r interface{}) bool {
 		return l.(int) < r.(int)
-	})
+	}, opts...)
 }
 
 // NewStringSet returns a new Set that accepts string elements.
-func NewStringSet() *Set {
+func NewStringSet(opts ...Option) *Set {
 	return NewCustomSet(func(l, r interface{}) bool {
 		return l.(string) < r.(string)
-	})
+	}, opts...)
 }
 
 // Add adds key to s.
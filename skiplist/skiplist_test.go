@@ -15,9 +15,13 @@
 package skiplist
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"math/big"
 	"math/rand"
 	"sort"
+	"strings"
 	"testing"
 )
 
@@ -354,6 +358,143 @@ func TestRangeIteration(t *testing.T) {
 	}
 }
 
+func TestBoundedIteration(t *testing.T) {
+	s := NewIntMap()
+	for i := 0; i < 20; i++ {
+		s.Set(i, i)
+	}
+
+	var seen []int
+	for i := s.Iterator(WithUpperBoundExclusive(5)); i.Next(); {
+		seen = append(seen, i.Key().(int))
+	}
+	if fmt.Sprint(seen) != "[0 1 2 3 4]" {
+		t.Errorf("Expected keys [0 1 2 3 4], got %v.", seen)
+	}
+
+	seen = nil
+	i := s.Iterator(WithLowerBound(15))
+	defer i.Close()
+	for i.Next() {
+		seen = append(seen, i.Key().(int))
+	}
+	if fmt.Sprint(seen) != "[15 16 17 18 19]" {
+		t.Errorf("Expected keys [15 16 17 18 19], got %v.", seen)
+	}
+
+	for i.Previous() {
+	}
+	if i.Key().(int) != 15 {
+		t.Errorf("Expected to stop at the lower bound 15, stopped at %v.", i.Key())
+	}
+
+	if i.Seek(14) {
+		t.Error("Allowed to seek below the lower bound.")
+	}
+	if !i.Seek(17) || i.Key().(int) != 17 {
+		t.Errorf("Expected to seek to 17, got %v.", i.Key())
+	}
+
+	if i := s.Iterator(WithLowerBound(100)); i.Next() || i.Previous() {
+		t.Errorf("Iterator past the end of the list should be empty, got %v.", i.Key())
+	}
+}
+
+func TestPrefixIteration(t *testing.T) {
+	s := NewCustomMap(func(l, r interface{}) bool {
+		return bytes.Compare(l.([]byte), r.([]byte)) < 0
+	})
+	keys := []string{"a", "ab", "abc", "abd", "ac", "b", "ba"}
+	elements := make([][2]interface{}, len(keys))
+	for i, k := range keys {
+		elements[i] = [2]interface{}{[]byte(k), i}
+	}
+	s.FillBySortedSlice(elements)
+
+	var seen []string
+	i := s.Iterator(WithPrefix([]byte("ab")))
+	defer i.Close()
+	for i.Next() {
+		seen = append(seen, string(i.Key().([]byte)))
+	}
+	if fmt.Sprint(seen) != "[ab abc abd]" {
+		t.Errorf("Expected keys [ab abc abd], got %v.", seen)
+	}
+
+	if i.Seek([]byte("ac")) {
+		t.Error("Allowed to seek outside of the prefix.")
+	}
+
+	seen = nil
+	for i := s.Iterator(WithPrefix([]byte("a")), WithUpperBoundExclusive([]byte("abd"))); i.Next(); {
+		seen = append(seen, string(i.Key().([]byte)))
+	}
+	if fmt.Sprint(seen) != "[a ab abc]" {
+		t.Errorf("Expected keys [a ab abc], got %v.", seen)
+	}
+
+	if i := s.Iterator(WithPrefix([]byte("c"))); i.Next() {
+		t.Errorf("Expected no keys with prefix c, got %s.", i.Key())
+	}
+}
+
+func TestReusableIter(t *testing.T) {
+	s := NewIntMap()
+	for i := 0; i < 10; i++ {
+		s.Set(i, i)
+	}
+
+	var it ReusableIter
+	s.IterInto(&it)
+	seen := 0
+	for it.Next() {
+		seen++
+	}
+	if seen != 10 {
+		t.Errorf("Expected to see 10 elements, saw %d.", seen)
+	}
+
+	it.Reset()
+	if !it.Next() || it.Key().(int) != 0 {
+		t.Errorf("Expected Reset to rewind to key 0, got %v.", it.Key())
+	}
+
+	if !s.SeekInto(&it, 5) || it.Key().(int) != 5 {
+		t.Errorf("Expected SeekInto to reach key 5, got %v.", it.Key())
+	}
+	if s.SeekInto(&it, 100) {
+		t.Errorf("Expected SeekInto past the end to fail, got %v.", it.Key())
+	}
+
+	other := NewIntMap()
+	other.Set(42, 42)
+	other.IterInto(&it)
+	if !it.Next() || it.Key().(int) != 42 || it.Next() {
+		t.Errorf("Expected iterator to be reattached to other list, got %v.", it.Key())
+	}
+
+	r := s.Range(3, 6).(*rangeIterator)
+	for r.Next() {
+	}
+	r.Reset()
+	if !r.Next() || r.Key().(int) != 3 {
+		t.Errorf("Expected Reset to rewind range iterator to key 3, got %v.", r.Key())
+	}
+
+	if allocs := testing.AllocsPerRun(100, func() {
+		s.IterInto(&it)
+		for it.Next() {
+		}
+	}); allocs != 0 {
+		t.Errorf("Expected reused iteration not to allocate, got %v allocations.", allocs)
+	}
+
+	empty := NewIntMap()
+	if i := empty.Iterator(WithLowerBound(1)); i.Next() {
+		t.Errorf("Expected no elements in an empty list, got %v.", i.Key())
+	}
+}
+
 func TestSomeMore(t *testing.T) {
 	s := NewIntMap()
 	insertions := [...]int{4, 1, 2, 9, 10, 7, 3}
@@ -442,6 +583,42 @@ func TestOrdered(t *testing.T) {
 	}
 }
 
+func TestComparatorEquality(t *testing.T) {
+	s := NewCustomMap(func(l, r interface{}) bool {
+		return l.(*big.Int).Cmp(r.(*big.Int)) < 0
+	})
+	for i := int64(0); i < 10; i++ {
+		s.Set(big.NewInt(i), i)
+	}
+	s.Set(big.NewInt(3), "three")
+
+	if s.Len() != 10 {
+		t.Errorf("Equal keys should not be inserted twice, got length %d.", s.Len())
+	}
+	if v, ok := s.Get(big.NewInt(3)); v != "three" || !ok {
+		t.Errorf("Get should find an equal key, got %v, %v.", v, ok)
+	}
+	if rank := s.Rank(big.NewInt(5)); rank != 6 {
+		t.Errorf("Rank of an equal key should be 6, not %d.", rank)
+	}
+	if v, ok := s.Delete(big.NewInt(5)); v != int64(5) || !ok {
+		t.Errorf("Delete should remove an equal key, got %v, %v.", v, ok)
+	}
+
+	b := NewCustomMap(func(l, r interface{}) bool {
+		return bytes.Compare(l.([]byte), r.([]byte)) < 0
+	})
+	b.Set([]byte("b"), 2)
+	b.Set([]byte("a"), 1)
+	b.Set([]byte("a"), 3)
+	if v, ok := b.Get([]byte("a")); v != 3 || !ok || b.Len() != 2 {
+		t.Errorf("Expected []byte keys to work, got %v, %v, length %d.", v, ok, b.Len())
+	}
+	if _, ok := b.Delete([]byte("b")); !ok || b.Rank([]byte("a")) != 1 {
+		t.Errorf("Expected []byte keys to be deleted and ranked.")
+	}
+}
+
 func TestNewStringMap(t *testing.T) {
 	s := NewStringMap()
 	s.Set("a", 1)
@@ -472,6 +649,110 @@ func TestSetNilKey(t *testing.T) {
 
 }
 
+func TestTryComparatorPanic(t *testing.T) {
+	s := NewIntMap()
+	for i := 0; i < 100; i++ {
+		if err := s.TrySet(i, i); err != nil {
+			t.Fatalf("TrySet(%d) returned unexpected error %v.", i, err)
+		}
+	}
+
+	err := s.TrySet("a", 0)
+	cerr, ok := err.(*ComparatorError)
+	if !ok {
+		t.Fatalf("Expected a *ComparatorError, got %v.", err)
+	}
+	if _, ok := cerr.Left.(int); !ok {
+		t.Errorf("Expected the left key to be an int, got %T.", cerr.Left)
+	}
+	if cerr.Right != "a" {
+		t.Errorf("Expected the right key to be \"a\", got %v.", cerr.Right)
+	}
+	if msg := cerr.Error(); !strings.Contains(msg, "int") || !strings.Contains(msg, "string") {
+		t.Errorf("Expected the error to mention the key types, got %q.", msg)
+	}
+
+	if _, _, err := s.TryGet(1.5); err == nil {
+		t.Errorf("Expected TryGet with a float key to fail.")
+	}
+	if _, _, err := s.TryDelete(1.5); err == nil {
+		t.Errorf("Expected TryDelete with a float key to fail.")
+	}
+	if v, ok, err := s.TryGet(7); v != 7 || !ok || err != nil {
+		t.Errorf("TryGet(7) should return 7, true, nil, not %v, %v, %v.", v, ok, err)
+	}
+	if v, ok, err := s.TryDelete(7); v != 7 || !ok || err != nil {
+		t.Errorf("TryDelete(7) should return 7, true, nil, not %v, %v, %v.", v, ok, err)
+	}
+	if s.Len() != 99 {
+		t.Errorf("Expected 99 elements, got %d.", s.Len())
+	}
+}
+
+func TestFillBySortedSliceChecked(t *testing.T) {
+	s := NewIntMap(WithShadowCheck(nil))
+	err := s.FillBySortedSliceChecked([][2]interface{}{{1, 1}, {3, 3}, {2, 2}, {4, 4}})
+	var uerr *UnsortedInputError
+	if !errors.As(err, &uerr) || uerr.Index != 2 || uerr.Prev != 3 || uerr.Key != 2 || uerr.Duplicate {
+		t.Errorf("Expected key 2 at index 2 to be reported, got %v.", err)
+	}
+	if !strings.Contains(err.Error(), "index 2") {
+		t.Errorf("The error should mention the index, got %q.", err)
+	}
+	err = s.FillBySortedSliceChecked([][2]interface{}{{1, 1}, {2, 2}, {2, 2}})
+	if !errors.As(err, &uerr) || uerr.Index != 2 || !uerr.Duplicate || !strings.Contains(err.Error(), "duplicate key 2") {
+		t.Errorf("Expected duplicate key 2 to be reported, got %v.", err)
+	}
+	if s.Len() != 0 || s.CheckShadow() != nil {
+		t.Errorf("Failed fills should leave the list unchanged, got %d elements.", s.Len())
+	}
+	if err := s.FillBySortedSliceChecked([][2]interface{}{{1, 1}, {2, 2}}); err != nil || s.Len() != 2 {
+		t.Errorf("FillBySortedSliceChecked failed with %v.", err)
+	}
+}
+
+func TestTryErrors(t *testing.T) {
+	s := NewIntMap()
+	if err := s.TrySet(nil, 1); err != ErrNilKey {
+		t.Errorf("TrySet(nil) should return ErrNilKey, got %v.", err)
+	}
+	if _, _, err := s.TryDelete(nil); err != ErrNilKey {
+		t.Errorf("TryDelete(nil) should return ErrNilKey, got %v.", err)
+	}
+
+	for _, test := range []struct {
+		elements [][2]interface{}
+		err      error
+	}{
+		{[][2]interface{}{{1, 1}, {nil, 2}}, ErrNilKey},
+		{[][2]interface{}{{1, 1}, {3, 3}, {2, 2}}, ErrUnsortedInput},
+		{[][2]interface{}{{1, 1}, {1, 1}}, ErrUnsortedInput},
+	} {
+		if err := s.FillBySortedSliceChecked(test.elements); !errors.Is(err, test.err) {
+			t.Errorf("FillBySortedSliceChecked(%v) should return %v, got %v.", test.elements, test.err, err)
+		}
+		if s.Len() != 0 {
+			t.Errorf("Failed fills should leave the list empty, got %d elements.", s.Len())
+		}
+	}
+	if _, ok := s.FillBySortedSliceChecked([][2]interface{}{{1, 1}, {"a", 2}}).(*ComparatorError); !ok {
+		t.Errorf("FillBySortedSliceChecked should return comparator panics as errors.")
+	}
+	if err := s.FillBySortedSliceChecked([][2]interface{}{{1, 1}, {2, 2}}); err != nil || s.Len() != 2 {
+		t.Errorf("FillBySortedSliceChecked failed with %v.", err)
+	}
+	if err := s.FillBySortedSliceChecked([][2]interface{}{{3, 3}}); err != ErrNotEmpty {
+		t.Errorf("Filling a list twice should return ErrNotEmpty, got %v.", err)
+	}
+
+	defer func() {
+		if x := recover(); x != ErrNilKey {
+			t.Errorf("Set(nil) should panic with ErrNilKey, got %v.", x)
+		}
+	}()
+	s.Set(nil, 1)
+}
+
 func TestSetMaxLevelInFlight(t *testing.T) {
 	s := NewIntMap()
 	s.MaxLevel = 2
@@ -837,6 +1118,665 @@ func TestRank(t *testing.T) {
 	}
 }
 
+func TestIntersectKeys(t *testing.T) {
+	a, b := NewIntMap(), NewIntMap()
+	for i := 0; i < 100; i++ {
+		a.Set(i*2, i)
+		b.Set(i*3, i)
+	}
+	common := a.IntersectKeys(b)
+	if len(common) != 34 {
+		t.Fatalf("Expected 34 common keys, got %d.", len(common))
+	}
+	for i, c := range common {
+		if c.Key != i*6 || c.Rank != a.Rank(c.Key) || c.OtherRank != b.Rank(c.Key) {
+			t.Errorf("Wrong common key %d: %+v.", i, c)
+		}
+	}
+	if common := a.IntersectKeys(NewIntMap()); len(common) != 0 {
+		t.Errorf("Expected no common keys with an empty list, got %v.", common)
+	}
+}
+
+func TestRebuildOptimal(t *testing.T) {
+	s := NewIntMap(WithMaxLevel(3))
+	for i := 0; i < 200; i++ {
+		s.Set(i, i)
+	}
+	s.RebuildOptimal()
+	if s.Len() != 200 || s.level() != 3 {
+		t.Fatalf("Wrong shape after rebuild: %d elements, level %d.", s.Len(), s.level())
+	}
+	i := 0
+	for n := s.header.next(); n != nil; n = n.next() {
+		expected := 0
+		for pos := i + 1; expected < 3 && pos%4 == 0; pos /= 4 {
+			expected++
+		}
+		if len(n.levels)-1 != expected {
+			t.Errorf("Node %v has level %d, expected %d.", n.key, len(n.levels)-1, expected)
+		}
+		i++
+	}
+	for i := 0; i < 200; i++ {
+		if v, ok := s.Get(i); !ok || v != i || s.Rank(i) != uint32(i+1) {
+			t.Errorf("Wrong element %d after rebuild.", i)
+		}
+	}
+	s.Set(1000, 1000)
+	s.Delete(0)
+	if s.Rank(1000) != 200 {
+		t.Errorf("Wrong rank after updating a rebuilt list: %d.", s.Rank(1000))
+	}
+}
+
+func TestSetReturning(t *testing.T) {
+	s := NewIntMap(WithShadowCheck(nil))
+	for i := 0; i < 200; i++ {
+		key := (i * 7919) % 200
+		prev, existed, rank := s.SetReturning(key, i)
+		if existed || prev != nil || rank != s.Rank(key) {
+			t.Errorf("SetReturning(%d) returned %v, %v, %d; expected rank %d.", key, prev, existed, rank, s.Rank(key))
+		}
+	}
+	for key := 0; key < 200; key += 3 {
+		old, _ := s.Get(key)
+		prev, existed, rank := s.SetReturning(key, -key)
+		if !existed || prev != old || rank != uint32(key+1) {
+			t.Errorf("SetReturning(%d) returned %v, %v, %d.", key, prev, existed, rank)
+		}
+		s.check(t, key, -key)
+	}
+	if err := s.CheckShadow(); err != nil {
+		t.Errorf("SetReturning: %v.", err)
+	}
+}
+
+func TestGetOrSet(t *testing.T) {
+	s := NewIntMap(WithShadowCheck(nil))
+	for i := 0; i < 100; i += 2 {
+		s.Set(i, i)
+	}
+	for i := 0; i < 100; i++ {
+		actual, loaded := s.GetOrSet(i, -i)
+		if loaded != (i%2 == 0) {
+			t.Errorf("GetOrSet(%d) should have loaded: %v.", i, i%2 == 0)
+		}
+		if (loaded && actual != i) || (!loaded && actual != -i) {
+			t.Errorf("GetOrSet(%d) returned %v.", i, actual)
+		}
+	}
+	for i := 0; i < 100; i++ {
+		if i%2 == 0 {
+			s.check(t, i, i)
+		} else {
+			s.check(t, i, -i)
+		}
+	}
+	if s.Len() != 100 {
+		t.Errorf("Expected 100 elements, got %d.", s.Len())
+	}
+	if err := s.CheckShadow(); err != nil {
+		t.Errorf("GetOrSet: %v.", err)
+	}
+}
+
+func TestSetIfAbsentOrPresent(t *testing.T) {
+	s := NewIntMap(WithShadowCheck(nil))
+	if s.SetIfPresent(1, 1) || s.Len() != 0 {
+		t.Errorf("SetIfPresent should not insert new keys.")
+	}
+	if !s.SetIfAbsent(1, 1) || s.SetIfAbsent(1, 2) {
+		t.Errorf("SetIfAbsent should only insert new keys.")
+	}
+	s.check(t, 1, 1)
+	if !s.SetIfPresent(1, 3) {
+		t.Errorf("SetIfPresent should update present keys.")
+	}
+	s.check(t, 1, 3)
+	if err := s.CheckShadow(); err != nil {
+		t.Errorf("SetIfAbsent and SetIfPresent: %v.", err)
+	}
+}
+
+func TestHigherLower(t *testing.T) {
+	s := NewIntMap()
+	for i := 0; i < 100; i += 10 {
+		s.Set(i, i)
+	}
+	for _, c := range []struct {
+		key           int
+		higher, lower interface{}
+	}{
+		{-1, 0, nil}, {0, 10, nil}, {5, 10, 0}, {50, 60, 40}, {90, nil, 80}, {100, nil, 90},
+	} {
+		if key, value, ok := s.Higher(c.key); key != c.higher || value != c.higher || ok != (c.higher != nil) {
+			t.Errorf("Higher(%d) should be %v, not %v.", c.key, c.higher, key)
+		}
+		if key, value, ok := s.Lower(c.key); key != c.lower || value != c.lower || ok != (c.lower != nil) {
+			t.Errorf("Lower(%d) should be %v, not %v.", c.key, c.lower, key)
+		}
+	}
+}
+
+func TestUpdateFunc(t *testing.T) {
+	s := NewIntMap(WithShadowCheck(nil), WithAggregate(&sumMonoid))
+	increment := func(old interface{}, exists bool) (interface{}, bool) {
+		if !exists {
+			return 1, true
+		}
+		return old.(int) + 1, true
+	}
+	for i := 0; i < 300; i++ {
+		s.UpdateFunc(i%100, increment)
+	}
+	for i := 0; i < 100; i++ {
+		s.check(t, i, 3)
+	}
+	if s.Len() != 100 || s.Aggregate() != 300 {
+		t.Errorf("Wrong length or aggregate: %d, %v.", s.Len(), s.Aggregate())
+	}
+
+	for i := 0; i < 120; i += 2 {
+		s.UpdateFunc(i, func(old interface{}, exists bool) (interface{}, bool) {
+			if exists != (i < 100) {
+				t.Errorf("UpdateFunc(%d) called with exists %v.", i, exists)
+			}
+			return nil, false
+		})
+	}
+	if s.Len() != 50 || s.Aggregate() != 150 {
+		t.Errorf("Wrong length or aggregate after deletions: %d, %v.", s.Len(), s.Aggregate())
+	}
+	if err := s.CheckShadow(); err != nil {
+		t.Errorf("UpdateFunc: %v.", err)
+	}
+}
+
+func TestDeleteWithRank(t *testing.T) {
+	s := NewIntMap(WithShadowCheck(nil))
+	for i := 0; i < 100; i++ {
+		s.Set(i*2, i)
+	}
+	for _, key := range []int{50, 0, 198, 51, 100} {
+		expected := s.Rank(key)
+		value, rank, ok := s.DeleteWithRank(key)
+		if ok != (expected != 0) || rank != expected || (ok && value != key/2) {
+			t.Errorf("DeleteWithRank(%d) returned %v, %d, %v; expected rank %d.", key, value, rank, ok, expected)
+		}
+	}
+	if err := s.CheckShadow(); err != nil {
+		t.Errorf("DeleteWithRank: %v.", err)
+	}
+}
+
+func TestDeleteByRank(t *testing.T) {
+	s := NewIntMap(WithShadowCheck(nil), WithAggregate(&sumMonoid))
+	for i := 0; i < 100; i++ {
+		s.Set(i, i*10)
+	}
+	for _, c := range []struct {
+		rank uint32
+		key  interface{}
+	}{
+		{1, 0}, {99, 99}, {50, 50}, {0, nil}, {98, nil},
+	} {
+		key, value, ok := s.DeleteByRank(c.rank)
+		if key != c.key || ok != (c.key != nil) || (ok && value != key.(int)*10) {
+			t.Errorf("DeleteByRank(%d) returned %v, %v, %v; expected key %v.", c.rank, key, value, ok, c.key)
+		}
+	}
+	if err := s.CheckShadow(); err != nil {
+		t.Errorf("DeleteByRank: %v.", err)
+	}
+	if s.Len() != 97 || s.Aggregate() != (99*100/2-99-50)*10 {
+		t.Errorf("Wrong length or aggregate after DeleteByRank: %d, %v.", s.Len(), s.Aggregate())
+	}
+}
+
+func TestDeleteRange(t *testing.T) {
+	for _, c := range []struct{ from, to, removed int }{
+		{10, 20, 10}, {0, 100, 100}, {-5, 3, 3}, {95, 200, 5}, {50, 50, 0}, {200, 300, 0},
+	} {
+		s := NewIntMap(WithShadowCheck(nil), WithAggregate(&sumMonoid))
+		expected := 0
+		for i := 0; i < 100; i++ {
+			s.Set(i, i)
+			if i < c.from || i >= c.to {
+				expected += i
+			}
+		}
+		if removed := s.DeleteRange(c.from, c.to); removed != c.removed {
+			t.Errorf("DeleteRange(%d, %d) removed %d elements, expected %d.", c.from, c.to, removed, c.removed)
+		}
+		if err := s.CheckShadow(); err != nil {
+			t.Errorf("DeleteRange(%d, %d): %v.", c.from, c.to, err)
+		}
+		if s.Aggregate() != expected {
+			t.Errorf("DeleteRange(%d, %d): aggregate is %v, expected %d.", c.from, c.to, s.Aggregate(), expected)
+		}
+		n := s.Len()
+		for i := s.SeekToLast(); i != nil && i.Previous(); {
+			n--
+		}
+		if s.Len() > 0 && n != 1 {
+			t.Errorf("DeleteRange(%d, %d): wrong backward links.", c.from, c.to)
+		}
+		s.Set(c.from, c.from)
+		if err := s.CheckShadow(); err != nil {
+			t.Errorf("DeleteRange(%d, %d), then Set: %v.", c.from, c.to, err)
+		}
+	}
+}
+
+func TestDeleteRangeByRank(t *testing.T) {
+	for _, c := range []struct{ from, to, first, removed int }{
+		{11, 20, 10, 10}, {1, 100, 0, 100}, {0, 3, 0, 3}, {96, 200, 95, 5}, {50, 49, 0, 0}, {101, 300, 0, 0},
+	} {
+		s := NewIntMap(WithShadowCheck(nil), WithAggregate(&sumMonoid))
+		for i := 0; i < 100; i++ {
+			s.Set(i, i)
+		}
+		removed := s.DeleteRangeByRank(uint32(c.from), uint32(c.to))
+		if len(removed) != c.removed {
+			t.Errorf("DeleteRangeByRank(%d, %d) removed %d elements, expected %d.", c.from, c.to, len(removed), c.removed)
+		}
+		expected := 99 * 100 / 2
+		for i, elem := range removed {
+			if elem[0] != c.first+i || elem[1] != c.first+i {
+				t.Errorf("DeleteRangeByRank(%d, %d) removed %v at %d.", c.from, c.to, elem, i)
+			}
+			expected -= elem[1].(int)
+		}
+		if err := s.CheckShadow(); err != nil {
+			t.Errorf("DeleteRangeByRank(%d, %d): %v.", c.from, c.to, err)
+		}
+		if s.Aggregate() != expected {
+			t.Errorf("DeleteRangeByRank(%d, %d): aggregate is %v, expected %d.", c.from, c.to, s.Aggregate(), expected)
+		}
+	}
+}
+
+func TestSwapValues(t *testing.T) {
+	s := NewIntMap(WithShadowCheck(nil), WithAggregate(&sumMonoid))
+	for i := 0; i < 100; i++ {
+		s.Set(i, i)
+	}
+	if !s.SwapValues(75, 3) {
+		t.Fatalf("SwapValues should succeed for present keys.")
+	}
+	s.check(t, 3, 75)
+	s.check(t, 75, 3)
+	if !s.SwapValues(42, 42) {
+		t.Errorf("Swapping a key with itself should succeed.")
+	}
+	s.check(t, 42, 42)
+	if s.SwapValues(5, 1000) || s.SwapValues(-1, 5) {
+		t.Errorf("SwapValues should fail for missing keys.")
+	}
+	s.check(t, 5, 5)
+	if sum := s.AggregateByRank(1, 10); sum != 117 {
+		t.Errorf("Aggregates should follow the swapped values, got %v.", sum)
+	}
+	if err := s.CheckShadow(); err != nil {
+		t.Errorf("Unexpected mismatch: %v.", err)
+	}
+}
+
+func TestChunks(t *testing.T) {
+	s := NewIntMap()
+	for i := 0; i < 10; i++ {
+		s.Set(i, i)
+	}
+	var sizes []int
+	next := 0
+	for chunk := range s.Chunks(4) {
+		sizes = append(sizes, len(chunk))
+		for _, elem := range chunk {
+			if elem[0] != next || elem[1] != next {
+				t.Errorf("Wrong element %v, expected %d.", elem, next)
+			}
+			next++
+		}
+	}
+	if len(sizes) != 3 || sizes[0] != 4 || sizes[2] != 2 {
+		t.Errorf("Wrong chunk sizes: %v.", sizes)
+	}
+
+	chunks := 0
+	for range s.Chunks(3) {
+		if chunks++; chunks == 2 {
+			break
+		}
+	}
+	if chunks != 2 {
+		t.Errorf("Breaking out of the loop should stop the chunks.")
+	}
+	for range NewIntMap().Chunks(3) {
+		t.Errorf("An empty list should have no chunks.")
+	}
+}
+
+func TestMinMax(t *testing.T) {
+	s := NewIntMap()
+	if _, _, ok := s.Min(); ok {
+		t.Errorf("Min of an empty list should not be ok.")
+	}
+	if _, _, ok := s.Max(); ok {
+		t.Errorf("Max of an empty list should not be ok.")
+	}
+	for _, k := range []int{5, 2, 9, 7} {
+		s.Set(k, k*10)
+	}
+	s.Delete(9)
+	if k, v, ok := s.Min(); !ok || k != 2 || v != 20 {
+		t.Errorf("Wrong Min: %v, %v, %v.", k, v, ok)
+	}
+	if k, v, ok := s.Max(); !ok || k != 7 || v != 70 {
+		t.Errorf("Wrong Max: %v, %v, %v.", k, v, ok)
+	}
+	if k, _, _ := s.First(); k != 2 {
+		t.Errorf("Wrong First: %v.", k)
+	}
+	if k, _, _ := s.Last(); k != 7 {
+		t.Errorf("Wrong Last: %v.", k)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	s := NewIntMap(WithShadowCheck(nil), WithAggregate(&sumMonoid))
+	other := NewIntMap(WithMaxLevel(20))
+	for i := 0; i < 300; i++ {
+		if i%3 != 0 {
+			s.Set(i, i)
+		}
+		if i%2 == 0 {
+			other.Set(i, -i)
+		}
+	}
+	expected := 0
+	for i := 0; i < 300; i++ {
+		if i%2 == 0 {
+			expected -= i
+		} else if i%3 != 0 {
+			expected += i
+		}
+	}
+
+	s.Merge(other)
+	if other.Len() != 0 {
+		t.Errorf("Merge should empty the other list, it has %d elements.", other.Len())
+	}
+	if err := s.CheckShadow(); err != nil {
+		t.Errorf("Merge: %v.", err)
+	}
+	if s.Aggregate() != expected {
+		t.Errorf("Merge: aggregate is %v, expected %d.", s.Aggregate(), expected)
+	}
+	n := 0
+	for i := 0; i < 300; i++ {
+		if i%2 == 0 {
+			s.check(t, i, -i)
+		} else if i%3 != 0 {
+			s.check(t, i, i)
+		} else {
+			continue
+		}
+		n++
+		if r := s.Rank(i); r != uint32(n) {
+			t.Errorf("Rank(%d) should be %d, not %d.", i, n, r)
+		}
+	}
+	for i, n := s.SeekToLast(), s.Len(); i != nil; n-- {
+		if !i.Previous() {
+			if n != 1 {
+				t.Errorf("Backward links skip %d elements.", n-1)
+			}
+			break
+		}
+	}
+
+	s.Set(1000, 1)
+	other.Set(1, 1)
+	s.Merge(other)
+	if err := s.CheckShadow(); err != nil {
+		t.Errorf("Merge into a merged list: %v.", err)
+	}
+}
+
+func TestSplit(t *testing.T) {
+	for _, key := range []int{-1, 0, 57, 150, 298, 300} {
+		s := NewIntMap(WithShadowCheck(nil), WithAggregate(&sumMonoid))
+		for i := 0; i < 300; i += 2 {
+			s.Set(i, i)
+		}
+		high := s.Split(key)
+		below := (key + 1) / 2
+		if key < 0 {
+			below = 0
+		}
+		if below > 150 {
+			below = 150
+		}
+		if s.Len() != below || high.Len() != 150-below {
+			t.Errorf("Split(%d) left %d and moved %d elements.", key, s.Len(), high.Len())
+		}
+		for _, l := range []*SkipList{s, high} {
+			if err := l.CheckShadow(); err != nil {
+				t.Errorf("Split(%d): %v.", key, err)
+			}
+			sum := 0
+			for i := l.Iterator(); i.Next(); {
+				sum += i.Key().(int)
+				if (l == s) != (i.Key().(int) < key) {
+					t.Errorf("Split(%d): %v is in the wrong list.", key, i.Key())
+				}
+			}
+			if l.Aggregate() != sum {
+				t.Errorf("Split(%d): aggregate is %v, expected %d.", key, l.Aggregate(), sum)
+			}
+		}
+		high.Set(key, key)
+		s.Set(key-1, key-1)
+		for _, l := range []*SkipList{s, high} {
+			if err := l.CheckShadow(); err != nil {
+				t.Errorf("Split(%d), then Set: %v.", key, err)
+			}
+		}
+	}
+}
+
+func TestClone(t *testing.T) {
+	s := NewIntMap(WithShadowCheck(nil), WithAggregate(&sumMonoid), WithBloomFilter(hashInt, 100, 0.01))
+	for i := 0; i < 100; i++ {
+		s.Set(i, i)
+	}
+	c := s.Clone()
+	for i := 0; i < 100; i += 2 {
+		c.Delete(i)
+	}
+	c.Set(1000, 1000)
+	if s.Len() != 100 || s.Aggregate() != 4950 {
+		t.Errorf("Modifying the clone changed the original: %d elements.", s.Len())
+	}
+	if c.Len() != 51 || c.Aggregate() != 2500+1000 {
+		t.Errorf("Wrong clone: %d elements, aggregate %v.", c.Len(), c.Aggregate())
+	}
+	for i := 0; i < 100; i++ {
+		s.check(t, i, i)
+		if _, ok := c.Get(i); ok != (i%2 == 1) {
+			t.Errorf("Unexpected presence of %d in the clone: %v.", i, ok)
+		}
+	}
+	for _, l := range []*SkipList{s, c} {
+		if err := l.CheckShadow(); err != nil {
+			t.Errorf("Clone: %v.", err)
+		}
+	}
+}
+
+func TestRepairSpans(t *testing.T) {
+	s := NewIntMap(WithShadowCheck(nil), WithAggregate(&sumMonoid))
+	for i := 0; i < 200; i++ {
+		s.Set(i, i)
+	}
+	if n := s.RepairSpans(); n != 0 {
+		t.Errorf("Expected a consistent list, got %d repairs.", n)
+	}
+
+	var high *node
+	for n := s.header.next(); n != nil; n = n.next() {
+		n.levels[0].span = 7
+		if len(n.levels) > 1 {
+			n.levels[len(n.levels)-1].span += 3
+			high = n
+		}
+		if n.key.(int)%10 == 0 {
+			n.backward = nil
+		}
+	}
+	high.levels[1].forward = nil
+	s.header.levels[0].span = 0
+	s.footer = s.header.next()
+	s.length = 3
+
+	if n := s.RepairSpans(); n == 0 {
+		t.Errorf("Expected RepairSpans to repair the list.")
+	}
+	if err := s.CheckShadow(); err != nil {
+		t.Errorf("RepairSpans: %v.", err)
+	}
+	if k, _, _ := s.Max(); k != 199 || s.Len() != 200 || s.Aggregate() != 19900 {
+		t.Errorf("Wrong list after RepairSpans: max %v, %d elements.", k, s.Len())
+	}
+	if it := s.SeekToLast(); !it.Previous() || it.Key() != 198 {
+		t.Errorf("Backward links should be repaired.")
+	}
+	if n := s.RepairSpans(); n != 0 {
+		t.Errorf("Expected a repaired list to be consistent, got %d repairs.", n)
+	}
+}
+
+func TestEqual(t *testing.T) {
+	s, o := NewIntMap(), NewIntMap()
+	for i := 0; i < 100; i++ {
+		s.Set(i, i)
+		o.Set(99-i, 99-i)
+	}
+	if !s.Equal(o, nil) || !o.Equal(s, nil) {
+		t.Errorf("Lists with the same elements should be equal.")
+	}
+	o.Set(50, -50)
+	if s.Equal(o, nil) {
+		t.Errorf("Lists with different values should not be equal.")
+	}
+	abs := func(a, b interface{}) bool {
+		return a.(int) == b.(int) || a.(int) == -b.(int)
+	}
+	if !s.Equal(o, abs) {
+		t.Errorf("Values should be compared with valueEq.")
+	}
+	o.Delete(50)
+	o.Set(100, 100)
+	if s.Equal(o, abs) {
+		t.Errorf("Lists with different keys should not be equal.")
+	}
+	o.Delete(100)
+	if s.Equal(o, abs) || !NewIntMap().Equal(NewIntMap(), nil) {
+		t.Errorf("Only lists of the same length can be equal.")
+	}
+}
+
+func TestCopyRange(t *testing.T) {
+	s := NewIntMap(WithMaxLevel(8))
+	for i := 0; i < 100; i++ {
+		s.Set(i*2, i)
+	}
+	c := s.CopyRange(25, 51)
+	if c.Len() != 13 || c.MaxLevel != 8 {
+		t.Fatalf("Expected 13 elements, got %d.", c.Len())
+	}
+	i := 13
+	for it := c.Iterator(); it.Next(); i++ {
+		if it.Key() != i*2 || it.Value() != i || c.Rank(it.Key()) != uint32(i-12) {
+			t.Errorf("Wrong element %v: %v.", it.Key(), it.Value())
+		}
+	}
+
+	// The copy is independent of the original.
+	c.Set(1000, 1000)
+	s.Delete(26)
+	if _, ok := s.Get(1000); ok || c.Len() != 14 {
+		t.Errorf("Copy and original should be independent.")
+	}
+	if c := s.CopyRange(300, 400); c.Len() != 0 {
+		t.Errorf("Expected an empty copy, got %d elements.", c.Len())
+	}
+}
+
+func TestInlineLevels(t *testing.T) {
+	s := NewIntMap(WithNodeBlockSize(16))
+	for i := 0; i < 1000; i++ {
+		s.Set(i, i)
+	}
+	snapshot := s.Snapshot()
+	s.Delete(0)
+	tall := 0
+	for n := s.header.next(); n != nil; n = n.next() {
+		if inline := &n.levels[0] == &n.inline[0]; inline != (len(n.levels) <= inlineLevels) {
+			t.Fatalf("Node %v with %d levels should store them inline: %v.", n.key, len(n.levels), !inline)
+		}
+		if len(n.levels) > inlineLevels {
+			tall++
+		}
+	}
+	if tall == 0 {
+		t.Errorf("Expected some nodes with more than %d levels.", inlineLevels)
+	}
+	if snapshot.Len() != 1000 || s.Len() != 999 {
+		t.Errorf("Wrong lengths after copying the nodes: %d, %d.", snapshot.Len(), s.Len())
+	}
+}
+
+func TestScratchAllocations(t *testing.T) {
+	s := NewIntMap()
+	for i := 0; i < 200; i++ {
+		s.Set(i, i)
+	}
+	allocs := testing.AllocsPerRun(100, func() {
+		s.Set(100, 1)
+		s.Delete(500)
+	})
+	if allocs != 0 {
+		t.Errorf("Expected Set and Delete to reuse their vectors, got %v allocations.", allocs)
+	}
+	keep := func(old interface{}, exists bool) (interface{}, bool) {
+		return old, exists
+	}
+	allocs = testing.AllocsPerRun(100, func() {
+		s.SetReturning(100, 2)
+		s.GetOrSet(100, 3)
+		s.UpdateFunc(100, keep)
+		s.UpdateFunc(500, keep)
+		s.DeleteRange(500, 600)
+		s.DeleteRangeByRank(300, 400)
+	})
+	if allocs != 0 {
+		t.Errorf("Expected the other mutators to reuse their vectors, got %v allocations.", allocs)
+	}
+	if _, _, ok := s.DeleteByRank(200); !ok || s.Len() != 199 {
+		t.Errorf("DeleteByRank(200) failed, %d elements left.", s.Len())
+	}
+	for i := 0; i < 199; i += 2 {
+		s.Delete(i)
+	}
+	for i := 1; i < 199; i += 2 {
+		if rank := s.Rank(i); rank != uint32(i/2+1) {
+			t.Errorf("Wrong rank %d for %d.", rank, i)
+		}
+	}
+}
+
 func BenchmarkLookup16(b *testing.B) {
 	LookupBenchmark(b, 16)
 }
@@ -861,6 +1801,20 @@ func BenchmarkSet65536(b *testing.B) {
 	SetBenchmark(b, 65536)
 }
 
+// BenchmarkBuild1024 builds lists of 1024 elements by insertion. The
+// allocations and bytes per operation show what storing the lowest
+// levels inline in the nodes saves, and what it costs in memory.
+func BenchmarkBuild1024(b *testing.B) {
+	keys := rand.Perm(1024)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		s := NewIntMap()
+		for _, key := range keys {
+			s.Set(key, nil)
+		}
+	}
+}
+
 func BenchmarkRandomSeek(b *testing.B) {
 	b.StopTimer()
 	values := []int{}
@@ -0,0 +1,136 @@
+// Package skiplisttest provides helpers for testing the serialization
+// of the containers of package skiplist.
+package skiplisttest
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/longzhiri/goskiplist/skiplist"
+)
+
+// A Codec serializes the elements of a container: the key-value pairs
+// of a SkipList in key order, the member-score pairs of a ZSet in rank
+// order (as returned by ZSet.Marshal), or the keys of a Set in order,
+// with nil values.
+type Codec interface {
+	Encode(elements [][2]interface{}) ([]byte, error)
+	Decode(data []byte) ([][2]interface{}, error)
+}
+
+// Gob is a Codec using encoding/gob. Types other than the basic ones
+// must be registered with gob.Register.
+var Gob Codec = gobCodec{}
+
+// JSON is a Codec using encoding/json. Numbers are decoded as float64.
+var JSON Codec = jsonCodec{}
+
+type gobCodec struct{}
+
+func (gobCodec) Encode(elements [][2]interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(elements)
+	return buf.Bytes(), err
+}
+
+func (gobCodec) Decode(data []byte) ([][2]interface{}, error) {
+	var elements [][2]interface{}
+	err := gob.NewDecoder(bytes.NewReader(data)).Decode(&elements)
+	return elements, err
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Encode(elements [][2]interface{}) ([]byte, error) {
+	return json.Marshal(elements)
+}
+
+func (jsonCodec) Decode(data []byte) ([][2]interface{}, error) {
+	var elements [][2]interface{}
+	err := json.Unmarshal(data, &elements)
+	return elements, err
+}
+
+// CheckRoundTrip encodes the elements of container, a *SkipList, *Set
+// or *ZSet, with codec, decodes them, and reports through t any element
+// that did not survive: decoded keys (or members) must find the same
+// element of container, at the same position, so that the order, and
+// for ZSets the order of members with equal scores, is preserved when
+// the container is rebuilt from them. Decoded values and scores must be
+// deeply equal to the original ones.
+func CheckRoundTrip(t testing.TB, container interface{}, codec Codec) {
+	t.Helper()
+
+	var elements [][2]interface{}
+	// find returns the position of the element of container with key,
+	// or -1.
+	var find func(key interface{}) int
+	switch c := container.(type) {
+	case *skiplist.SkipList:
+		for i := c.Iterator(); i.Next(); {
+			elements = append(elements, [2]interface{}{i.Key(), i.Value()})
+		}
+		find = func(key interface{}) int {
+			return int(c.Rank(key)) - 1
+		}
+	case *skiplist.ZSet:
+		elements = c.Marshal()
+		find = func(member interface{}) int {
+			if !c.Exists(member) {
+				return -1
+			}
+			return int(c.Rank(member)) - 1
+		}
+	case *skiplist.Set:
+		for i := c.Iterator(); i.Next(); {
+			elements = append(elements, [2]interface{}{i.Key(), nil})
+		}
+		find = func(key interface{}) int {
+			if !c.Contains(key) {
+				return -1
+			}
+			n := 0
+			for i := c.Range(elements[0][0], key); i.Next(); {
+				n++
+			}
+			return n
+		}
+	default:
+		t.Fatalf("skiplisttest: unsupported container %T", container)
+	}
+
+	data, err := codec.Encode(elements)
+	if err != nil {
+		t.Fatalf("skiplisttest: cannot encode %T: %v", container, err)
+	}
+	decoded, err := codec.Decode(data)
+	if err != nil {
+		t.Fatalf("skiplisttest: cannot decode %T: %v", container, err)
+	}
+	if len(decoded) != len(elements) {
+		t.Fatalf("skiplisttest: decoded %d elements, expected %d", len(decoded), len(elements))
+	}
+	for i, elem := range decoded {
+		pos, err := position(find, elem[0])
+		if err != nil {
+			t.Errorf("skiplisttest: cannot look up decoded key %#v (originally %#v): %v", elem[0], elements[i][0], err)
+		} else if pos != i {
+			t.Errorf("skiplisttest: decoded key %#v (originally %#v) is at position %d, expected %d", elem[0], elements[i][0], pos, i)
+		}
+		if !reflect.DeepEqual(elem[1], elements[i][1]) {
+			t.Errorf("skiplisttest: decoded %#v for key %#v, expected %#v", elem[1], elements[i][0], elements[i][1])
+		}
+	}
+}
+
+// position calls find, recovering from the panics of comparison
+// functions given keys of unexpected types.
+func position(find func(key interface{}) int, key interface{}) (pos int, err interface{}) {
+	defer func() {
+		err = recover()
+	}()
+	return find(key), nil
+}
@@ -0,0 +1,65 @@
+package skiplisttest
+
+import (
+	"runtime"
+	"sync"
+	"testing"
+
+	"github.com/longzhiri/goskiplist/skiplist"
+)
+
+// recorder records the failures of a check.
+type recorder struct {
+	testing.TB
+	failed bool
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.failed = true
+}
+
+func (r *recorder) Fatalf(format string, args ...interface{}) {
+	r.failed = true
+	runtime.Goexit()
+}
+
+func TestCheckRoundTrip(t *testing.T) {
+	list := skiplist.NewStringMap()
+	set := skiplist.NewIntSet()
+	zset := skiplist.NewCustomZSet(func(l, r interface{}) bool {
+		return l.(float64) < r.(float64)
+	})
+	for i, key := range []string{"b", "a", "d", "c"} {
+		list.Set(key, key+key)
+		set.Add(i * 10)
+		zset.Add(key, float64(i%2))
+	}
+
+	for _, c := range []struct {
+		container interface{}
+		codec     Codec
+		ok        bool
+	}{
+		{list, Gob, true},
+		{list, JSON, true},
+		{zset, Gob, true},
+		{zset, JSON, true},
+		{set, Gob, true},
+		// JSON decodes the int keys as float64.
+		{set, JSON, false},
+	} {
+		r := &recorder{TB: t}
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			CheckRoundTrip(r, c.container, c.codec)
+		}()
+		wg.Wait()
+		if r.failed == c.ok {
+			t.Errorf("CheckRoundTrip(%T, %T) should succeed: %v.", c.container, c.codec, c.ok)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package skiplist
+
+import "sync/atomic"
+
+// A Snapshot is a read-only, point-in-time view of a SkipList. It can
+// be read, including by several goroutines at once, while the list it
+// was taken from keeps being modified.
+type Snapshot struct {
+	list *SkipList
+}
+
+// Snapshot returns a view of the current contents of s, in O(1). The
+// nodes of s are shared with the snapshot until the next modification
+// of s, which copies them first (in O(n)), so that the snapshot is
+// never modified. Taking a snapshot is therefore cheap, but the first
+// write after it is not.
+func (s *SkipList) Snapshot() *Snapshot {
+	s.shared = true
+	lessThan := s.lessThan
+	if s.profile != nil {
+		lessThan = s.profile.lessThan
+	}
+	// The snapshot does not keep statistics, so that reading it does
+	// not write to memory shared with the list.
+	return &Snapshot{list: &SkipList{
+		lessThan: lessThan,
+		header:   s.header,
+		footer:   s.footer,
+		length:   s.length,
+		MaxLevel: s.MaxLevel,
+		p:        s.p,
+		augment:  s.augment,
+		aggs:     s.aggs,
+		codec:    s.codec,
+	}}
+}
+
+// An AtomicView publishes read-only snapshots of lists to reader
+// goroutines without locks: readers Load the current snapshot, while a
+// writer builds or updates a list and publishes it with SwapView. It is
+// returned by PublishReadOnly.
+type AtomicView struct {
+	current atomic.Pointer[Snapshot]
+}
+
+// PublishReadOnly returns an AtomicView initially publishing a snapshot
+// of s.
+func (s *SkipList) PublishReadOnly() *AtomicView {
+	v := new(AtomicView)
+	v.current.Store(s.Snapshot())
+	return v
+}
+
+// Load returns the current snapshot. Readers should load it once per
+// batch of reads, so that they see a consistent state.
+func (v *AtomicView) Load() *Snapshot {
+	return v.current.Load()
+}
+
+// SwapView atomically publishes a snapshot of newList and returns the
+// snapshot it replaces. Readers holding the previous snapshot can keep
+// using it. newList must only be used by the calling goroutine, like
+// any SkipList; it can keep being modified after the swap, as its
+// snapshot is copy-on-write.
+func (v *AtomicView) SwapView(newList *SkipList) *Snapshot {
+	return v.current.Swap(newList.Snapshot())
+}
+
+// unshare copies the nodes of s if they are shared with a snapshot, so
+// that s can be modified.
+func (s *SkipList) unshare() {
+	if !s.shared {
+		return
+	}
+	s.shared = false
+	s.header, s.footer = s.copyNodes(s)
+	s.dropFinger()
+}
+
+// copyNodes returns a copy of the header and the footer of s, and of
+// all the nodes in between, allocated by dst, with the same levels and
+// spans. It gives dst a copy of the aggregates of s, if any.
+func (s *SkipList) copyNodes(dst *SkipList) (header, footer *node) {
+	var aggs map[*node][]interface{}
+	if s.aggs != nil {
+		aggs = make(map[*node][]interface{}, len(s.aggs))
+	}
+	copyAggs := func(c, n *node) {
+		if aggs != nil {
+			aggs[c] = append([]interface{}(nil), s.aggs[n]...)
+		}
+	}
+	copyNode := func(n *node) *node {
+		c := dst.newNode(len(n.levels)-1, n.key, n.value)
+		copy(c.levels, n.levels)
+		copyAggs(c, n)
+		return c
+	}
+	header = &node{levels: make([]level, len(s.header.levels), maxInt(len(s.header.levels), dst.levelHint+1))}
+	copy(header.levels, s.header.levels)
+	copyAggs(header, s.header)
+
+	// last holds the last copied node at every level.
+	last := make([]*node, len(header.levels))
+	for i := range last {
+		last[i] = header
+	}
+	var previous *node
+	for n := s.header.next(); n != nil; n = n.next() {
+		c := copyNode(n)
+		c.backward = previous
+		for i := range c.levels {
+			last[i].levels[i].forward = c
+			last[i] = c
+		}
+		previous = c
+	}
+	for i := range last {
+		last[i].levels[i].forward = nil
+	}
+	if aggs != nil {
+		dst.aggs = aggs
+	}
+	return header, previous
+}
+
+// Len returns the number of elements in the snapshot.
+func (sn *Snapshot) Len() int {
+	return sn.list.Len()
+}
+
+// Get is like SkipList.Get.
+func (sn *Snapshot) Get(key interface{}) (value interface{}, ok bool) {
+	return sn.list.Get(key)
+}
+
+// GetGreaterOrEqual is like SkipList.GetGreaterOrEqual.
+func (sn *Snapshot) GetGreaterOrEqual(min interface{}) (actualKey, value interface{}, ok bool) {
+	return sn.list.GetGreaterOrEqual(min)
+}
+
+// Rank is like SkipList.Rank.
+func (sn *Snapshot) Rank(key interface{}) uint32 {
+	return sn.list.Rank(key)
+}
+
+// GetElemByRank is like SkipList.GetElemByRank.
+func (sn *Snapshot) GetElemByRank(rank uint32) Iterator {
+	return sn.list.GetElemByRank(rank)
+}
+
+// Iterator is like SkipList.Iterator.
+func (sn *Snapshot) Iterator(opts ...IterOption) Iterator {
+	return sn.list.Iterator(opts...)
+}
+
+// Range is like SkipList.Range.
+func (sn *Snapshot) Range(from, to interface{}) Iterator {
+	return sn.list.Range(from, to)
+}
@@ -0,0 +1,117 @@
+package skiplist
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestSnapshot(t *testing.T) {
+	s := NewIntMap(WithAggregate(&sumMonoid), WithAccessProfiling())
+	for i := 0; i < 100; i++ {
+		s.Set(i, i)
+	}
+	snap := s.Snapshot()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for j := 0; j < 10; j++ {
+			n := 0
+			for i := snap.Iterator(); i.Next(); n++ {
+				if i.Key() != n || i.Value() != n {
+					t.Errorf("Wrong element in snapshot: %v.", i.Key())
+				}
+			}
+			if n != 100 || snap.Rank(50) != 51 {
+				t.Errorf("Snapshot changed: %d elements.", n)
+			}
+		}
+	}()
+	for i := 0; i < 100; i += 2 {
+		s.Delete(i)
+	}
+	for i := 100; i < 150; i++ {
+		s.Set(i, -i)
+	}
+	s.Set(1, 1000)
+	wg.Wait()
+
+	if v, _ := snap.Get(1); v != 1 || snap.Len() != 100 {
+		t.Errorf("Snapshot should not see later writes: %v, %d.", v, snap.Len())
+	}
+	if v, _ := s.Get(1); v != 1000 || s.Len() != 100 || s.Rank(101) != 52 {
+		t.Errorf("Wrong list after writes: %v, %d.", v, s.Len())
+	}
+	if k, _, _ := snap.GetGreaterOrEqual(99); k != 99 || snap.GetElemByRank(100).Key() != 99 {
+		t.Errorf("Wrong last element in snapshot.")
+	}
+	if i := snap.Range(10, 20); !i.Next() || i.Key() != 10 {
+		t.Errorf("Wrong range over snapshot.")
+	}
+	total := 0
+	for i := s.Iterator(); i.Next(); {
+		total += i.Value().(int)
+	}
+	if s.Aggregate() != total {
+		t.Errorf("Aggregate is %v, expected %d.", s.Aggregate(), total)
+	}
+	if k, _, _ := s.Max(); k != 149 {
+		t.Errorf("Wrong footer after copy: %v.", k)
+	}
+	var previous interface{}
+	for i := s.SeekToLast(); i != nil; {
+		if previous != nil && i.Key().(int) >= previous.(int) {
+			t.Fatalf("Wrong backward order at %v.", i.Key())
+		}
+		previous = i.Key()
+		if !i.Previous() {
+			break
+		}
+	}
+}
+
+func TestAtomicView(t *testing.T) {
+	build := func(generation int) *SkipList {
+		s := NewIntMap()
+		for i := 0; i < 100; i++ {
+			s.Set(i, generation)
+		}
+		return s
+	}
+	s := build(0)
+	view := s.PublishReadOnly()
+
+	var wg sync.WaitGroup
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				snap := view.Load()
+				first, _ := snap.Get(0)
+				for i := snap.Iterator(); i.Next(); {
+					if i.Value() != first {
+						t.Errorf("Snapshot mixes generations %v and %v.", first, i.Value())
+						return
+					}
+				}
+			}
+		}()
+	}
+	for g := 1; g <= 20; g++ {
+		if g%2 == 0 {
+			// Update the published list in place.
+			for i := 0; i < 100; i++ {
+				s.Set(i, g)
+			}
+			view.SwapView(s)
+		} else if old := view.SwapView(build(g)); old.Len() != 100 {
+			t.Errorf("Wrong previous snapshot: %d elements.", old.Len())
+		}
+	}
+	wg.Wait()
+	if v, _ := view.Load().Get(50); v != 20 {
+		t.Errorf("Expected the last generation, got %v.", v)
+	}
+}
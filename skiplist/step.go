@@ -0,0 +1,118 @@
+package skiplist
+
+// A Stepper is an Iterator that can skip several elements at once. The
+// iterators of SkipList and Snapshot use the spans of the nodes to do
+// so in O(log n) time, which makes downsampling scans cheap; those of
+// TieredMap, of the set operations, FilterIter and MapIter implement
+// it too, by moving one element at a time:
+//
+//	if st, ok := it.(skiplist.Stepper); ok {
+//		for st.Step(10) {
+//			// every 10th element
+//		}
+//	}
+type Stepper interface {
+	Iterator
+	// Step advances the iterator by n elements, or rewinds it if n is
+	// negative, as n calls to Next (or Previous) would. It returns
+	// false, leaving the iterator unchanged, if there are fewer than
+	// |n| elements in that direction.
+	Step(n int) (ok bool)
+}
+
+// stepNode returns the node n positions after from (before it if n is
+// negative), from being a node or the header of s, or nil if there is
+// no such element. It uses the spans, so it takes O(log(s.Len())) time
+// whatever n is.
+func (s *SkipList) stepNode(from *node, n int) *node {
+	var pos int64
+	if from != s.header {
+		pos = int64(s.countLess(from.key)) + 1
+	}
+	pos += int64(n)
+	if pos < 1 || pos > int64(s.length) {
+		return nil
+	}
+	return s.nodeByRank(uint32(pos))
+}
+
+// stepTo moves i to target, unless it is nil or its key does not
+// satisfy contains, in which case it returns false.
+func (i *iter) stepTo(target *node, contains func(key interface{}) bool) bool {
+	if target == nil || (contains != nil && !contains(target.key)) {
+		return false
+	}
+	i.current = target
+	i.key = target.key
+	i.value = i.list.decode(target.value)
+	return true
+}
+
+func (i *iter) Step(n int) bool {
+	if n == 0 {
+		return true
+	}
+	return i.stepTo(i.list.stepNode(i.current, n), nil)
+}
+
+func (i *rangeIterator) Step(n int) bool {
+	if n == 0 {
+		return true
+	}
+	from := i.current
+	if from != i.list.header && from.key == nil {
+		// i was rewound by Reset to a placeholder before the first
+		// element of its range.
+		if n < 0 || from.next() == nil {
+			return false
+		}
+		from, n = from.next(), n-1
+	}
+	return i.stepTo(i.list.stepNode(from, n), func(key interface{}) bool {
+		return !i.list.lessThan(key, i.lowerLimit) && i.list.lessThan(key, i.upperLimit)
+	})
+}
+
+func (i *boundedIterator) Step(n int) bool {
+	if n == 0 {
+		return true
+	}
+	return i.stepTo(i.list.stepNode(i.current, n), i.contains)
+}
+
+// stepByMoves implements Step for the iterators that cannot skip
+// elements, by moving it n times, or moving it back if it could not.
+func stepByMoves(it Iterator, n int) bool {
+	step, undo := it.Next, it.Previous
+	if n < 0 {
+		n, step, undo = -n, it.Previous, it.Next
+	}
+	for moved := 0; moved < n; moved++ {
+		if !step() {
+			for ; moved > 0; moved-- {
+				undo()
+			}
+			return false
+		}
+	}
+	return true
+}
+
+func (i *filterIterator) Step(n int) bool {
+	return stepByMoves(i, n)
+}
+
+func (i *mapIterator) Step(n int) bool {
+	if st, ok := i.Iterator.(Stepper); ok {
+		return st.Step(n)
+	}
+	return stepByMoves(i.Iterator, n)
+}
+
+func (i *setOpIterator) Step(n int) bool {
+	return stepByMoves(i, n)
+}
+
+func (i *tieredIterator) Step(n int) bool {
+	return stepByMoves(i, n)
+}
@@ -0,0 +1,58 @@
+package skiplist
+
+import "testing"
+
+func TestStep(t *testing.T) {
+	s := NewIntMap()
+	for i := 0; i < 1000; i++ {
+		s.Set(i, -i)
+	}
+
+	it := s.Iterator().(Stepper)
+	var keys []int
+	for it.Step(100) {
+		keys = append(keys, it.Key().(int))
+	}
+	if len(keys) != 10 || keys[0] != 99 || keys[9] != 999 || it.Key() != 999 {
+		t.Errorf("Wrong keys stepped over: %v.", keys)
+	}
+	if !it.Step(-999) || it.Key() != 0 || it.Value() != 0 {
+		t.Errorf("Step should rewind to the first element, got %v.", it.Key())
+	}
+	if it.Step(-1) || it.Key() != 0 {
+		t.Errorf("Step should not go before the first element.")
+	}
+
+	r := s.Range(100, 200).(Stepper)
+	if !r.Step(5) || r.Key() != 104 || r.Value() != -104 {
+		t.Errorf("Wrong key after stepping into a range: %v.", r.Key())
+	}
+	if r.Step(100) || r.Key() != 104 {
+		t.Errorf("Step should not leave the range.")
+	}
+	if !r.Step(95) || r.Key() != 199 || r.Step(-100) {
+		t.Errorf("Wrong key at the end of a range: %v.", r.Key())
+	}
+
+	b := s.Iterator(WithLowerBound(500), WithUpperBoundExclusive(600)).(Stepper)
+	if !b.Step(1) || b.Key() != 500 || b.Step(100) || !b.Step(99) || b.Key() != 599 {
+		t.Errorf("Wrong key stepping within bounds: %v.", b.Key())
+	}
+
+	f := FilterIter(s.Iterator(), func(key, value interface{}) bool {
+		return key.(int)%2 == 0
+	}).(Stepper)
+	if !f.Step(3) || f.Key() != 4 || !f.Step(-2) || f.Key() != 0 || f.Step(-1) {
+		t.Errorf("Wrong key stepping a filtered iterator: %v.", f.Key())
+	}
+
+	m := MapIter(s.Iterator(), func(key, value interface{}) interface{} {
+		return key
+	}).(Stepper)
+	if !m.Step(10) || m.Value() != 9 {
+		t.Errorf("Wrong value stepping a mapped iterator: %v.", m.Value())
+	}
+	if _, ok := Iterator(opaqueIterator{s.Iterator()}).(Stepper); ok {
+		t.Errorf("Step should be optional.")
+	}
+}
@@ -0,0 +1,240 @@
+package skiplist
+
+import "sync"
+
+// A ConcurrentSkipList guards a SkipList with a sync.RWMutex, so that it
+// can be shared by goroutines: readers run concurrently, and writers
+// are serialized. It is returned by SkipList.Synced.
+type ConcurrentSkipList struct {
+	mu   sync.RWMutex
+	list *SkipList
+	// exclusiveReads is true if reads modify the list (to keep
+	// statistics or a search finger), and must therefore hold the write
+	// lock.
+	exclusiveReads bool
+
+	// rangeMu guards held, the key ranges locked by LockRange, and
+	// rangeCond signals their release.
+	rangeMu   sync.Mutex
+	rangeCond *sync.Cond
+	held      []*RangeLock
+}
+
+// Synced returns a ConcurrentSkipList guarding s. s must not be used
+// directly afterwards.
+func (s *SkipList) Synced() *ConcurrentSkipList {
+	return &ConcurrentSkipList{
+		list:           s,
+		exclusiveReads: s.filter != nil || s.profile != nil || s.shadow != nil || s.finger != nil,
+	}
+}
+
+func (c *ConcurrentSkipList) rlock() {
+	if c.exclusiveReads {
+		c.mu.Lock()
+	} else {
+		c.mu.RLock()
+	}
+}
+
+func (c *ConcurrentSkipList) runlock() {
+	if c.exclusiveReads {
+		c.mu.Unlock()
+	} else {
+		c.mu.RUnlock()
+	}
+}
+
+// Len is like SkipList.Len.
+func (c *ConcurrentSkipList) Len() int {
+	c.rlock()
+	defer c.runlock()
+	return c.list.Len()
+}
+
+// Get is like SkipList.Get.
+func (c *ConcurrentSkipList) Get(key interface{}) (value interface{}, ok bool) {
+	c.rlock()
+	defer c.runlock()
+	return c.list.Get(key)
+}
+
+// GetGreaterOrEqual is like SkipList.GetGreaterOrEqual.
+func (c *ConcurrentSkipList) GetGreaterOrEqual(min interface{}) (actualKey, value interface{}, ok bool) {
+	c.rlock()
+	defer c.runlock()
+	return c.list.GetGreaterOrEqual(min)
+}
+
+// Rank is like SkipList.Rank.
+func (c *ConcurrentSkipList) Rank(key interface{}) uint32 {
+	c.rlock()
+	defer c.runlock()
+	return c.list.Rank(key)
+}
+
+// Set is like SkipList.Set.
+func (c *ConcurrentSkipList) Set(key, value interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.list.Set(key, value)
+}
+
+// CompareAndSwap is like SkipList.CompareAndSwap. Together with Get, it
+// allows optimistic updates: read a value, compute the new one without
+// holding the lock, and retry if the value changed meanwhile.
+func (c *ConcurrentSkipList) CompareAndSwap(key, old, new interface{}) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.list.CompareAndSwap(key, old, new)
+}
+
+// Delete is like SkipList.Delete.
+func (c *ConcurrentSkipList) Delete(key interface{}) (value interface{}, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.list.Delete(key)
+}
+
+// Range calls fn for the elements of the list that are greater or equal
+// than from, but less than to, until fn returns false. The read lock is
+// held throughout, so fn must not modify the list.
+func (c *ConcurrentSkipList) Range(from, to interface{}, fn func(key, value interface{}) bool) {
+	c.rlock()
+	defer c.runlock()
+	for i := c.list.Range(from, to); i.Next(); {
+		if !fn(i.Key(), i.Value()) {
+			return
+		}
+	}
+}
+
+// Foreach is like Range for all the elements of the list.
+func (c *ConcurrentSkipList) Foreach(fn func(key, value interface{}) bool) {
+	c.rlock()
+	defer c.runlock()
+	for i := c.list.Iterator(); i.Next(); {
+		if !fn(i.Key(), i.Value()) {
+			return
+		}
+	}
+}
+
+// Read calls fn with the list while holding the read lock, for
+// operations not covered by the other methods. fn must not modify the
+// list, nor keep it (or iterators on it) after returning.
+func (c *ConcurrentSkipList) Read(fn func(s *SkipList)) {
+	c.rlock()
+	defer c.runlock()
+	fn(c.list)
+}
+
+// Write is like Read, holding the write lock, so that fn may modify the
+// list.
+func (c *ConcurrentSkipList) Write(fn func(s *SkipList)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	fn(c.list)
+}
+
+// A RangeLock is a lock on the keys of a ConcurrentSkipList in [From,
+// To), obtained with LockRange.
+type RangeLock struct {
+	c *ConcurrentSkipList
+	// From and To bound the locked keys; nil means unbounded.
+	From, To interface{}
+	write    bool
+}
+
+// LockRange locks the keys in [from, to) (nil meaning unbounded) for
+// writing, or only for reading if write is false, waiting for
+// conflicting locks to be released. Transactions that read or write
+// several keys under range locks proceed concurrently as long as their
+// ranges are disjoint (or both are read locks), instead of holding the
+// whole list for their duration. Each operation still holds the list's
+// own lock while it runs, as neighbouring ranges share nodes.
+func (c *ConcurrentSkipList) LockRange(from, to interface{}, write bool) *RangeLock {
+	l := &RangeLock{c: c, From: from, To: to, write: write}
+	c.rangeMu.Lock()
+	defer c.rangeMu.Unlock()
+	if c.rangeCond == nil {
+		c.rangeCond = sync.NewCond(&c.rangeMu)
+	}
+	for c.conflicts(l) {
+		c.rangeCond.Wait()
+	}
+	c.held = append(c.held, l)
+	return l
+}
+
+// conflicts returns true if l overlaps a held lock, one of them being a
+// write lock.
+func (c *ConcurrentSkipList) conflicts(l *RangeLock) bool {
+	for _, h := range c.held {
+		if (l.write || h.write) && l.overlaps(h) {
+			return true
+		}
+	}
+	return false
+}
+
+func (l *RangeLock) overlaps(o *RangeLock) bool {
+	lessThan := l.c.list.lessThan
+	// The ranges are disjoint if one ends before the other begins.
+	if l.To != nil && o.From != nil && !lessThan(o.From, l.To) {
+		return false
+	}
+	if o.To != nil && l.From != nil && !lessThan(l.From, o.To) {
+		return false
+	}
+	return true
+}
+
+func (l *RangeLock) contains(key interface{}) bool {
+	lessThan := l.c.list.lessThan
+	return (l.From == nil || !lessThan(key, l.From)) && (l.To == nil || lessThan(key, l.To))
+}
+
+func (l *RangeLock) mustContain(key interface{}, write bool) {
+	if !l.contains(key) {
+		panic("goskiplist: key outside of the locked range")
+	}
+	if write && !l.write {
+		panic("goskiplist: write under a read range lock")
+	}
+}
+
+// Get is like SkipList.Get for a key in the locked range.
+func (l *RangeLock) Get(key interface{}) (value interface{}, ok bool) {
+	l.mustContain(key, false)
+	return l.c.Get(key)
+}
+
+// Set is like SkipList.Set for a key in the range, which must be locked
+// for writing.
+func (l *RangeLock) Set(key, value interface{}) {
+	l.mustContain(key, true)
+	l.c.Set(key, value)
+}
+
+// Delete is like SkipList.Delete for a key in the range, which must be
+// locked for writing.
+func (l *RangeLock) Delete(key interface{}) (value interface{}, ok bool) {
+	l.mustContain(key, true)
+	return l.c.Delete(key)
+}
+
+// Unlock releases l.
+func (l *RangeLock) Unlock() {
+	c := l.c
+	c.rangeMu.Lock()
+	defer c.rangeMu.Unlock()
+	for i, h := range c.held {
+		if h == l {
+			c.held = append(c.held[:i], c.held[i+1:]...)
+			c.rangeCond.Broadcast()
+			return
+		}
+	}
+	panic("goskiplist: unlock of an unlocked range")
+}
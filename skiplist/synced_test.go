@@ -0,0 +1,132 @@
+package skiplist
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSynced(t *testing.T) {
+	for _, s := range []*SkipList{NewIntMap(), NewIntMap(WithAccessProfiling())} {
+		c := s.Synced()
+		var wg sync.WaitGroup
+		for w := 0; w < 4; w++ {
+			wg.Add(2)
+			go func(w int) {
+				defer wg.Done()
+				for i := w; i < 1000; i += 4 {
+					c.Set(i, i)
+				}
+			}(w)
+			go func() {
+				defer wg.Done()
+				for i := 0; i < 1000; i++ {
+					c.Get(i)
+					c.Rank(i)
+					c.Range(i, i+10, func(key, value interface{}) bool {
+						return key != value
+					})
+				}
+			}()
+		}
+		wg.Wait()
+
+		if c.Len() != 1000 || c.Rank(500) != 501 {
+			t.Errorf("Wrong contents after concurrent writes: %d elements.", c.Len())
+		}
+		c.Write(func(s *SkipList) {
+			for i := 0; i < 1000; i += 2 {
+				s.Delete(i)
+			}
+		})
+		n := 0
+		c.Foreach(func(key, value interface{}) bool {
+			n++
+			return true
+		})
+		if n != 500 {
+			t.Errorf("Expected 500 elements, got %d.", n)
+		}
+		c.Read(func(s *SkipList) {
+			if k, _, _ := s.Max(); k != 999 {
+				t.Errorf("Wrong maximum: %v.", k)
+			}
+		})
+	}
+}
+
+func TestSyncedCompareAndSwap(t *testing.T) {
+	c := NewIntMap().Synced()
+	c.Set(0, 0)
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				for {
+					old, _ := c.Get(0)
+					if c.CompareAndSwap(0, old, old.(int)+1) {
+						break
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if v, _ := c.Get(0); v != 800 {
+		t.Errorf("Expected 800 increments, got %v.", v)
+	}
+	if c.CompareAndSwap(1, nil, 1) {
+		t.Errorf("CompareAndSwap should fail for missing keys.")
+	}
+}
+
+func TestLockRange(t *testing.T) {
+	c := NewIntMap().Synced()
+	a := c.LockRange(0, 10, true)
+	b := c.LockRange(10, 20, true)
+	r1 := c.LockRange(nil, 0, false)
+	r2 := c.LockRange(nil, 0, false)
+
+	acquired := make(chan *RangeLock)
+	go func() {
+		acquired <- c.LockRange(5, 15, false)
+	}()
+	a.Set(1, 1)
+	b.Set(12, 12)
+	select {
+	case <-acquired:
+		t.Fatalf("Overlapping lock should wait.")
+	case <-time.After(20 * time.Millisecond):
+	}
+	a.Unlock()
+	b.Unlock()
+	l := <-acquired
+	if v, ok := l.Get(12); !ok || v != 12 {
+		t.Errorf("Wrong value under range lock: %v.", v)
+	}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("Writing under a read lock should panic.")
+			}
+		}()
+		l.Set(6, 6)
+	}()
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("Reading outside of the range should panic.")
+			}
+		}()
+		l.Get(20)
+	}()
+	l.Unlock()
+	r1.Unlock()
+	r2.Unlock()
+	if w := c.LockRange(nil, nil, true); w == nil || len(c.held) != 1 {
+		t.Errorf("Whole range should be lockable once everything is released.")
+	}
+}
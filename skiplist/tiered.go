@@ -0,0 +1,302 @@
+package skiplist
+
+import "time"
+
+type tombstoneValue struct{}
+
+// tombstone is the value a TieredMap stores in its hot list to shadow a
+// key deleted from its cold list.
+var tombstone = &tombstoneValue{}
+
+// A TieredMap is a map-like data structure for write-heavy workloads,
+// organized like a small log-structured merge tree. Writes and
+// deletions go to a small hot SkipList, which is merged into a large
+// cold SkipList with a single linear pass (and rebuilt with
+// FillBySortedSlice) whenever it holds hotLimit entries. Reads consult
+// the hot list first and the cold list second.
+//
+// To iterate over a tiered map (where t is a *TieredMap):
+//
+//	for i := t.Iterator(); i.Next(); {
+//		// do something with i.Key() and i.Value()
+//	}
+type TieredMap struct {
+	hot      *SkipList
+	cold     *SkipList
+	hotLimit int
+	// length is the number of keys in t, unless counted is false: Set
+	// does not look up the cold list to find out whether the keys new
+	// to the hot list are new to t, so that writes stay cheap, and Len
+	// counts them when needed.
+	length  int
+	counted bool
+	// writes counts the writes to t, and tombstones the tombstones in
+	// its hot list, for the compaction scheduler.
+	writes     uint64
+	tombstones int
+	stats      CompactionStats
+}
+
+// NewTieredMap returns a new TieredMap that will use lessThan as the
+// comparison function and compact itself whenever hotLimit entries
+// (including deletions) have accumulated in its hot list. opts
+// configure the cold list; the hot list, which holds the pending
+// writes and deletions as they are, only takes the options shaping its
+// levels. A byte limit is therefore enforced by Compact, and a value
+// codec applies to the values once they are compacted.
+func NewTieredMap(lessThan func(l, r interface{}) bool, hotLimit int, opts ...Option) *TieredMap {
+	cold := NewCustomMap(lessThan, opts...)
+	return &TieredMap{
+		hot:      NewWithOptions(cold.shapeOptions()...),
+		cold:     cold,
+		hotLimit: hotLimit,
+		counted:  true,
+	}
+}
+
+// Len returns the number of keys in t. It takes O(1) time, except
+// after Set added keys to the hot list, when it takes O(h*log(n)) time
+// for h keys in the hot list to look them up in the cold one.
+func (t *TieredMap) Len() int {
+	if !t.counted {
+		t.length = t.cold.Len()
+		for n := t.hot.header.next(); n != nil; n = n.next() {
+			_, inCold := t.cold.Get(n.key)
+			if n.value == tombstone {
+				t.length--
+			} else if !inCold {
+				t.length++
+			}
+		}
+		t.counted = true
+	}
+	return t.length
+}
+
+// Get returns the value associated with key from t (nil if the key is
+// not present in t). The second return value is true when the key is
+// present.
+func (t *TieredMap) Get(key interface{}) (value interface{}, ok bool) {
+	if value, ok = t.hot.Get(key); ok {
+		if value == tombstone {
+			return nil, false
+		}
+		return value, true
+	}
+	return t.cold.Get(key)
+}
+
+// Set sets the value associated with key in t. It only searches the
+// hot list.
+func (t *TieredMap) Set(key, value interface{}) {
+	old, existed, _ := t.hot.SetReturning(key, value)
+	if !existed {
+		// Whether key is new to t depends on the cold list.
+		t.counted = false
+	} else if old == tombstone {
+		t.tombstones--
+		t.length++
+	}
+	t.writes++
+	t.maybeCompact()
+}
+
+// Delete removes key from t. It returns the old value and whether the
+// key was present.
+func (t *TieredMap) Delete(key interface{}) (value interface{}, ok bool) {
+	if value, ok = t.Get(key); !ok {
+		return nil, false
+	}
+	t.length--
+	t.writes++
+	if _, inCold := t.cold.Get(key); inCold {
+		t.tombstones++
+		t.hot.Set(key, tombstone)
+		t.maybeCompact()
+	} else {
+		t.hot.Delete(key)
+	}
+	return value, true
+}
+
+func (t *TieredMap) maybeCompact() {
+	if t.hot.Len() >= t.hotLimit {
+		t.stats.BySize++
+		t.Compact()
+	}
+}
+
+// Compact merges the hot list into the cold one, dropping deleted
+// keys. It takes time linear in the size of t.
+func (t *TieredMap) Compact() {
+	if t.hot.Len() == 0 {
+		return
+	}
+	start := time.Now()
+
+	elements := make([][2]interface{}, 0, t.cold.Len()+t.hot.Len())
+	h, c := t.hot.header.next(), t.cold.header.next()
+	for h != nil || c != nil {
+		var key, value interface{}
+		switch {
+		case c == nil || (h != nil && t.hot.lessThan(h.key, c.key)):
+			key, value, h = h.key, h.value, h.next()
+		case h == nil || t.hot.lessThan(c.key, h.key):
+			key, value, c = c.key, t.cold.decode(c.value), c.next()
+		default:
+			// The hot entry shadows the cold one.
+			key, value, h, c = h.key, h.value, h.next(), c.next()
+		}
+		if value != tombstone {
+			elements = append(elements, [2]interface{}{key, value})
+		}
+	}
+
+	t.cold.Clear()
+	t.cold.FillBySortedSlice(elements)
+	t.hot.Clear()
+	// The byte limit of the cold list may have evicted elements.
+	t.length, t.counted = t.cold.Len(), true
+	t.tombstones = 0
+	t.stats.Compactions++
+	t.stats.LastDuration = time.Since(start)
+}
+
+// Iterator returns an Iterator that will go through all the elements of
+// t, merging the hot and cold lists on the fly.
+func (t *TieredMap) Iterator() Iterator {
+	return &tieredIterator{
+		t:      t,
+		hotGE:  t.hot.header.next(),
+		coldGE: t.cold.header.next(),
+	}
+}
+
+// tieredIterator merges iteration over the hot and cold lists of a
+// TieredMap. For each list it keeps the first node whose key is greater
+// or equal to the current key. value is the decoded value of current.
+type tieredIterator struct {
+	t              *TieredMap
+	hotGE, coldGE  *node
+	current        *node
+	value          interface{}
+	started, valid bool
+}
+
+func (i *tieredIterator) lessThan(l, r interface{}) bool {
+	return i.t.hot.lessThan(l, r)
+}
+
+// after returns the first node of a list whose key is greater than
+// the current one, given the first one which is greater or equal.
+func (i *tieredIterator) after(ge *node) *node {
+	if ge != nil && i.valid && !i.lessThan(i.current.key, ge.key) {
+		return ge.next()
+	}
+	return ge
+}
+
+// before returns the last node of the list s whose key is less than
+// the current one, given the first one which is greater or equal.
+func (i *tieredIterator) before(s *SkipList, ge *node) *node {
+	if ge == nil {
+		return s.footer
+	}
+	return ge.backward
+}
+
+// pick makes n, a node of the list s, current, and reports whether it
+// holds a live value rather than a tombstone.
+func (i *tieredIterator) pick(s *SkipList, n *node) bool {
+	i.current, i.value = n, s.decode(n.value)
+	i.valid = true
+	return i.value != tombstone
+}
+
+func (i *tieredIterator) Next() bool {
+	if !i.started {
+		i.started = true
+	} else if !i.valid {
+		return false
+	}
+	saved := *i
+	for {
+		h, c := i.after(i.hotGE), i.after(i.coldGE)
+		if h == nil && c == nil {
+			*i = saved
+			return false
+		}
+		i.hotGE, i.coldGE = h, c
+		n, s := h, i.t.hot
+		if h == nil || (c != nil && i.lessThan(c.key, h.key)) {
+			n, s = c, i.t.cold
+		}
+		if i.pick(s, n) {
+			return true
+		}
+	}
+}
+
+func (i *tieredIterator) Previous() bool {
+	if !i.valid {
+		return false
+	}
+	saved := *i
+	for {
+		h, c := i.before(i.t.hot, i.hotGE), i.before(i.t.cold, i.coldGE)
+		if h == nil && c == nil {
+			*i = saved
+			return false
+		}
+		n, s := h, i.t.hot
+		if h == nil || (c != nil && i.lessThan(h.key, c.key)) {
+			n, s = c, i.t.cold
+		}
+		if h != nil && !i.lessThan(h.key, n.key) {
+			i.hotGE = h
+		}
+		if c != nil && !i.lessThan(c.key, n.key) {
+			i.coldGE = c
+		}
+		if i.pick(s, n) {
+			return true
+		}
+	}
+}
+
+func (i *tieredIterator) Key() interface{} {
+	if !i.valid {
+		return nil
+	}
+	return i.current.key
+}
+
+func (i *tieredIterator) Value() interface{} {
+	if !i.valid {
+		return nil
+	}
+	return i.value
+}
+
+// Seek moves the iterator to the first element whose key is greater or
+// equal to key, and returns false if there is no such element.
+func (i *tieredIterator) Seek(key interface{}) bool {
+	saved := *i
+	i.hotGE = i.t.hot.getLowerBound(i.t.hot.header, key)
+	i.coldGE = i.t.cold.getLowerBound(i.t.cold.header, key)
+	i.started, i.valid = false, false
+	if !i.Next() {
+		*i = saved
+		return false
+	}
+	return true
+}
+
+func (i *tieredIterator) Close() {
+	i.t = nil
+	i.hotGE = nil
+	i.coldGE = nil
+	i.current = nil
+	i.value = nil
+	i.valid = false
+}
@@ -0,0 +1,192 @@
+package skiplist
+
+import (
+	"math/rand"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestTieredMap(t *testing.T) {
+	tm := NewTieredMap(func(l, r interface{}) bool {
+		return l.(int) < r.(int)
+	}, 16)
+	reference := make(map[int]int)
+
+	for i := 0; i < 2000; i++ {
+		k := rand.Intn(200)
+		if rand.Intn(3) == 0 {
+			_, want := reference[k]
+			if _, ok := tm.Delete(k); ok != want {
+				t.Fatalf("Delete(%d) returned %v, expected %v.", k, ok, want)
+			}
+			delete(reference, k)
+		} else {
+			tm.Set(k, i)
+			reference[k] = i
+		}
+		if tm.Len() != len(reference) {
+			t.Fatalf("Len() is %d, expected %d.", tm.Len(), len(reference))
+		}
+	}
+
+	for k := 0; k < 200; k++ {
+		v, ok := tm.Get(k)
+		if want, present := reference[k]; ok != present || (ok && v != want) {
+			t.Errorf("Get(%d) returned %v, %v, expected %v, %v.", k, v, ok, want, present)
+		}
+	}
+
+	seen := 0
+	last := -1
+	i := tm.Iterator()
+	defer i.Close()
+	for i.Next() {
+		k := i.Key().(int)
+		if k <= last {
+			t.Errorf("Keys out of order: %d after %d.", k, last)
+		}
+		if i.Value() != reference[k] {
+			t.Errorf("Wrong value for key %d: %v.", k, i.Value())
+		}
+		last = k
+		seen++
+	}
+	if seen != len(reference) {
+		t.Errorf("Iterated over %d keys, expected %d.", seen, len(reference))
+	}
+
+	for i.Previous() {
+		k := i.Key().(int)
+		if k >= last {
+			t.Errorf("Keys out of order going back: %d after %d.", k, last)
+		}
+		last = k
+		seen--
+	}
+	if seen != 1 {
+		t.Errorf("Previous stopped %d keys before the first one.", seen-1)
+	}
+
+	tm.Compact()
+	if tm.hot.Len() != 0 || tm.cold.Len() != len(reference) {
+		t.Errorf("Compact left %d hot and %d cold entries, expected 0 and %d.", tm.hot.Len(), tm.cold.Len(), len(reference))
+	}
+}
+
+func TestTieredMapSetSkipsCold(t *testing.T) {
+	tm := NewTieredMap(func(l, r interface{}) bool {
+		return l.(int) < r.(int)
+	}, 100, WithAccessProfiling())
+	for i := 0; i < 1000; i += 2 {
+		tm.Set(i, i)
+	}
+	tm.Compact()
+	visits := tm.cold.profile.visits
+	for i := 0; i < 50; i++ {
+		tm.Set(i, -i)
+	}
+	if tm.cold.profile.visits != visits {
+		t.Errorf("Set should not search the cold list, it made %d comparisons.", tm.cold.profile.visits-visits)
+	}
+	if tm.Len() != 525 {
+		t.Errorf("Len() is %d, expected 525.", tm.Len())
+	}
+	tm.Delete(2)
+	tm.Set(2, 2)
+	tm.Set(3, 3)
+	if tm.Len() != 525 {
+		t.Errorf("Len() is %d, expected 525.", tm.Len())
+	}
+}
+
+func TestTieredIteratorShadowing(t *testing.T) {
+	tm := NewTieredMap(func(l, r interface{}) bool {
+		return l.(int) < r.(int)
+	}, 100)
+	for k := 0; k < 10; k++ {
+		tm.Set(k, "cold")
+	}
+	tm.Compact()
+	tm.Set(3, "hot")
+	tm.Delete(4)
+	tm.Delete(9)
+	tm.Set(20, "hot")
+
+	var keys []interface{}
+	var values []interface{}
+	for i := tm.Iterator(); i.Next(); {
+		keys = append(keys, i.Key())
+		values = append(values, i.Value())
+	}
+	if len(keys) != 9 || keys[3] != 3 || values[3] != "hot" || keys[4] != 5 || keys[8] != 20 {
+		t.Errorf("Unexpected merged iteration: %v, %v.", keys, values)
+	}
+
+	i := tm.Iterator()
+	if !i.Seek(4) || i.Key() != 5 {
+		t.Errorf("Seek(4) should move to 5, not %v.", i.Key())
+	}
+	if !i.Previous() || i.Key() != 3 || i.Value() != "hot" {
+		t.Errorf("Previous should move to 3, not %v.", i.Key())
+	}
+	if !i.Seek(9) || i.Key() != 20 || i.Next() || i.Key() != 20 {
+		t.Errorf("Seek(9) should move to 20 and stay there, not %v.", i.Key())
+	}
+	if i.Seek(21) || i.Key() != 20 {
+		t.Errorf("Seek(21) should fail and keep the position, not %v.", i.Key())
+	}
+}
+
+func TestTieredMapByteLimit(t *testing.T) {
+	var evicted []interface{}
+	tm := NewTieredMap(intLessThan, 100, WithSizer(func(key, value interface{}) int {
+		return 1
+	}), WithByteLimit(3, func(key, value interface{}) {
+		evicted = append(evicted, key)
+	}))
+	for k := 0; k < 10; k++ {
+		tm.Set(k, k)
+	}
+	if tm.Len() != 10 || len(evicted) != 0 {
+		t.Errorf("Pending writes should not be evicted: %d keys, %v evicted.", tm.Len(), evicted)
+	}
+	tm.Compact()
+	if tm.Len() != 3 || len(evicted) != 7 {
+		t.Errorf("Compact should enforce the limit: %d keys, %v evicted.", tm.Len(), evicted)
+	}
+	tm.Delete(8)
+	tm.Set(20, 20)
+	tm.Set(21, 21)
+	if _, ok := tm.Get(8); ok || tm.Len() != 4 {
+		t.Errorf("A deleted key came back: %d keys.", tm.Len())
+	}
+}
+
+func TestTieredMapValueCodec(t *testing.T) {
+	tm := NewTieredMap(intLessThan, 100, WithValueCodec(func(value interface{}) interface{} {
+		return []byte(value.(string))
+	}, func(value interface{}) interface{} {
+		return string(value.([]byte))
+	}))
+	for k := 0; k < 5; k++ {
+		tm.Set(k, strconv.Itoa(k))
+	}
+	tm.Compact()
+	tm.Delete(1)
+	tm.Set(2, "two")
+	if v, ok := tm.Delete(3); !ok || v != "3" {
+		t.Errorf("Delete(3) returned %v, %v.", v, ok)
+	}
+	var values []interface{}
+	for i := tm.Iterator(); i.Next(); {
+		values = append(values, i.Value())
+	}
+	if !reflect.DeepEqual(values, []interface{}{"0", "two", "4"}) {
+		t.Errorf("Wrong values: %v.", values)
+	}
+	tm.Compact()
+	if v, ok := tm.Get(2); !ok || v != "two" || tm.Len() != 3 {
+		t.Errorf("Get(2) returned %v, %v after compaction.", v, ok)
+	}
+}
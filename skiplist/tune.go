@@ -0,0 +1,106 @@
+package skiplist
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// A Sample describes a workload for Tune.
+type Sample struct {
+	// LessThan orders the keys. If nil, they must implement Ordered.
+	LessThan func(l, r interface{}) bool
+	// Keys are representative keys, inserted in this order before the
+	// workload runs.
+	Keys []interface{}
+	// Size is the number of elements the tuned lists are expected to
+	// hold, len(Keys) if 0.
+	Size int
+	// ReadRatio is the fraction of the operations that are Gets, the
+	// others being Sets of random keys among Keys.
+	ReadRatio float64
+	// Ops is the number of operations timed, 10*len(Keys) if 0.
+	Ops int
+}
+
+// A Config holds the parameters recommended by Tune. It can be given to
+// Builder.Config.
+type Config struct {
+	P             float64
+	MaxLevel      int
+	NodeBlockSize int
+}
+
+// Config sets the parameters of c, leaving out those that are zero.
+func (b *Builder) Config(c Config) *Builder {
+	if c.P != 0 {
+		b.P(c.P)
+	}
+	if c.MaxLevel != 0 {
+		b.MaxLevel(c.MaxLevel)
+	}
+	if c.NodeBlockSize != 0 {
+		b.NodeBlockSize(c.NodeBlockSize)
+	}
+	return b
+}
+
+// tuneP and tuneBlockSizes are the candidate parameters of Tune.
+var (
+	tuneP          = []float64{1 / 2.0, 1 / math.E, 1 / 4.0, 1 / 8.0}
+	tuneBlockSizes = []int{0, 64}
+)
+
+// Tune times workload on lists built with the usual values of p and
+// node block sizes, with the MaxLevel suited to each p and the expected
+// size, and returns the fastest configuration. Each candidate runs 3
+// times, keeping the best time, so Tune takes about 24 times as long as
+// the workload itself. Its result depends on the machine and its load,
+// so it is meant to guide settings, not to be called on every start.
+func Tune(workload Sample) Config {
+	size := workload.Size
+	if size <= 0 {
+		size = len(workload.Keys)
+	}
+	best := Config{P: p, MaxLevel: maxLevelForSize(size, p)}
+	if len(workload.Keys) == 0 {
+		return best
+	}
+	bestTime := time.Duration(math.MaxInt64)
+	for _, p := range tuneP {
+		for _, blockSize := range tuneBlockSizes {
+			c := Config{P: p, MaxLevel: maxLevelForSize(size, p), NodeBlockSize: blockSize}
+			for round := 0; round < 3; round++ {
+				if d := runSample(workload, c); d < bestTime {
+					best, bestTime = c, d
+				}
+			}
+		}
+	}
+	return best
+}
+
+// runSample returns the time workload takes on a list configured by c.
+func runSample(workload Sample, c Config) time.Duration {
+	b := NewBuilder().Comparator(workload.LessThan).Config(c).RandSource(rand.NewSource(1))
+	s := b.SkipList()
+	ops := workload.Ops
+	if ops <= 0 {
+		ops = 10 * len(workload.Keys)
+	}
+	r := rand.New(rand.NewSource(2))
+
+	start := time.Now()
+	for _, key := range workload.Keys {
+		s.Set(key, key)
+	}
+	for i := 0; i < ops; i++ {
+		key := workload.Keys[r.Intn(len(workload.Keys))]
+		if r.Float64() < workload.ReadRatio {
+			s.Get(key)
+		} else {
+			s.Set(key, key)
+		}
+	}
+	return time.Since(start)
+}
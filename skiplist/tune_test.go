@@ -0,0 +1,33 @@
+package skiplist
+
+import "testing"
+
+func TestTune(t *testing.T) {
+	workload := Sample{
+		LessThan: func(l, r interface{}) bool {
+			return l.(int) < r.(int)
+		},
+		ReadRatio: 0.9,
+		Size:      10000,
+	}
+	if c := Tune(workload); c.P != p || c.MaxLevel != maxLevelForSize(10000, p) || c.NodeBlockSize != 0 {
+		t.Errorf("Expected the default configuration without keys, got %+v.", c)
+	}
+
+	for i := 0; i < 500; i++ {
+		workload.Keys = append(workload.Keys, i*7%500)
+	}
+	c := Tune(workload)
+	found := false
+	for _, p := range tuneP {
+		found = found || c.P == p
+	}
+	if !found || c.MaxLevel != maxLevelForSize(10000, c.P) {
+		t.Errorf("Unexpected configuration %+v.", c)
+	}
+
+	s := NewBuilder().Config(Config{P: 0.5, MaxLevel: 7, NodeBlockSize: 32}).SkipList()
+	if s.p != 0.5 || s.MaxLevel != 7 || s.nodeBlockSize != 32 {
+		t.Errorf("Builder should apply the configuration, got %v, %d, %d.", s.p, s.MaxLevel, s.nodeBlockSize)
+	}
+}
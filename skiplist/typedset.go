@@ -0,0 +1,162 @@
+package skiplist
+
+import (
+	"cmp"
+	"math/rand"
+)
+
+// A TypedSet is a set of elements of type T. Unlike Set, it stores its
+// elements as T in nodes of its own type, so that adding and looking
+// up elements does not box them into interface{} values, and mistyped
+// elements are rejected at compile time instead of making the
+// comparison function panic. It only supports the basic set operations;
+// use Set for the others.
+type TypedSet[T any] struct {
+	less   func(a, b T) bool
+	header *typedNode[T]
+	length int
+	// level is the highest level of the nodes, starting from 0.
+	level    int
+	maxLevel int
+	p        float64
+	rand     *rand.Rand
+	// update is reused by Add and Remove as their vector of the last
+	// nodes before an element at every level.
+	update []*typedNode[T]
+}
+
+// typedNode is a node of a TypedSet, linked to the next node at every
+// level it has.
+type typedNode[T any] struct {
+	elem    T
+	forward []*typedNode[T]
+}
+
+// NewTypedSet returns a new TypedSet of elements ordered by cmp.Less.
+// Only the options shaping the levels of the list are supported (see
+// NewTypedSetFunc).
+func NewTypedSet[T cmp.Ordered](opts ...Option) *TypedSet[T] {
+	return NewTypedSetFunc(cmp.Less[T], opts...)
+}
+
+// NewTypedSetFunc returns a new TypedSet of elements ordered by less,
+// which should define a linear order on T. Only the options shaping the
+// levels of the list are supported: WithMaxLevel, WithP and
+// WithRandSource are applied, the others of their kind are accepted
+// but have no effect, and any other option makes NewTypedSetFunc
+// panic.
+func NewTypedSetFunc[T any](less func(a, b T) bool, opts ...Option) *TypedSet[T] {
+	config := NewWithOptions(opts...)
+	config.checkShapeOptions("TypedSet")
+	return &TypedSet[T]{
+		less:     less,
+		header:   &typedNode[T]{forward: make([]*typedNode[T], 1, config.MaxLevel+1)},
+		maxLevel: config.MaxLevel,
+		p:        config.p,
+		rand:     config.rand,
+		update:   make([]*typedNode[T], config.MaxLevel+1),
+	}
+}
+
+// search fills s.update with the last nodes before elem at every level,
+// and returns the first node whose element is not less than elem, or
+// nil.
+func (s *TypedSet[T]) search(elem T) *typedNode[T] {
+	current := s.header
+	for i := s.level; i >= 0; i-- {
+		for current.forward[i] != nil && s.less(current.forward[i].elem, elem) {
+			current = current.forward[i]
+		}
+		s.update[i] = current
+	}
+	return current.forward[0]
+}
+
+// equal returns true if neither of a and b is less than the other.
+func (s *TypedSet[T]) equal(a, b T) bool {
+	return !s.less(a, b) && !s.less(b, a)
+}
+
+// randomLevel returns the level of a new node.
+func (s *TypedSet[T]) randomLevel() int {
+	random := rand.Float64
+	if s.rand != nil {
+		random = s.rand.Float64
+	}
+	lvl := 0
+	for lvl < s.maxLevel && random() < s.p {
+		lvl++
+	}
+	return lvl
+}
+
+// Add adds elem to s.
+func (s *TypedSet[T]) Add(elem T) {
+	candidate := s.search(elem)
+	if candidate != nil && s.equal(candidate.elem, elem) {
+		return
+	}
+	lvl := s.randomLevel()
+	for s.level < lvl {
+		s.level++
+		s.header.forward = append(s.header.forward, nil)
+		s.update[s.level] = s.header
+	}
+	n := &typedNode[T]{elem: elem, forward: make([]*typedNode[T], lvl+1)}
+	for i := 0; i <= lvl; i++ {
+		n.forward[i] = s.update[i].forward[i]
+		s.update[i].forward[i] = n
+	}
+	s.length++
+}
+
+// Remove tries to remove elem from s. It returns true if elem was
+// present.
+func (s *TypedSet[T]) Remove(elem T) bool {
+	candidate := s.search(elem)
+	if candidate == nil || !s.equal(candidate.elem, elem) {
+		return false
+	}
+	for i := range candidate.forward {
+		s.update[i].forward[i] = candidate.forward[i]
+	}
+	for s.level > 0 && s.header.forward[s.level] == nil {
+		s.header.forward = s.header.forward[:s.level]
+		s.level--
+	}
+	s.length--
+	return true
+}
+
+// Contains returns true if elem is present in s.
+func (s *TypedSet[T]) Contains(elem T) bool {
+	current := s.header
+	for i := s.level; i >= 0; i-- {
+		for current.forward[i] != nil && s.less(current.forward[i].elem, elem) {
+			current = current.forward[i]
+		}
+	}
+	next := current.forward[0]
+	return next != nil && !s.less(elem, next.elem)
+}
+
+// Len returns the number of elements in s.
+func (s *TypedSet[T]) Len() int {
+	return s.length
+}
+
+// Range calls fn for the elements of s that are greater or equal than
+// from, but less than to, in order, until fn returns false.
+func (s *TypedSet[T]) Range(from, to T, fn func(elem T) bool) {
+	current := s.header
+	for i := s.level; i >= 0; i-- {
+		for current.forward[i] != nil && s.less(current.forward[i].elem, from) {
+			current = current.forward[i]
+		}
+	}
+	for n := current.forward[0]; n != nil && s.less(n.elem, to); n = n.forward[0] {
+		if !fn(n.elem) {
+			return
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package skiplist
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestTypedSet(t *testing.T) {
+	s := NewTypedSet[int]()
+	for i := 0; i < 20; i++ {
+		s.Add(i * 3)
+	}
+	if !s.Contains(9) || s.Contains(10) || s.Len() != 20 {
+		t.Errorf("Wrong contents.")
+	}
+	if !s.Remove(9) || s.Remove(9) {
+		t.Errorf("Remove should succeed only once.")
+	}
+	var elems []int
+	s.Range(5, 20, func(elem int) bool {
+		elems = append(elems, elem)
+		return elem < 15
+	})
+	if !equalInts(elems, []int{6, 12, 15}) {
+		t.Errorf("Wrong range: %v.", elems)
+	}
+
+	type point struct{ x, y int }
+	points := NewTypedSetFunc(func(a, b point) bool {
+		return a.x < b.x || (a.x == b.x && a.y < b.y)
+	})
+	points.Add(point{1, 2})
+	points.Add(point{1, 1})
+	var first []point
+	points.Range(point{0, 0}, point{2, 0}, func(p point) bool {
+		first = append(first, p)
+		return false
+	})
+	if len(first) != 1 || first[0] != (point{1, 1}) {
+		t.Errorf("Wrong order of points: %v.", first)
+	}
+}
+
+func TestTypedSetRandom(t *testing.T) {
+	s := NewTypedSet[int](WithRandSource(rand.NewSource(1)), WithMaxLevel(8))
+	want := map[int]bool{}
+	r := rand.New(rand.NewSource(2))
+	for i := 0; i < 5000; i++ {
+		elem := r.Intn(1000)
+		if r.Intn(3) == 0 {
+			if s.Remove(elem) != want[elem] {
+				t.Fatalf("Remove(%d) disagrees with the expected contents.", elem)
+			}
+			delete(want, elem)
+		} else {
+			s.Add(elem)
+			want[elem] = true
+		}
+	}
+	if s.Len() != len(want) {
+		t.Errorf("Expected %d elements, got %d.", len(want), s.Len())
+	}
+	previous := -1
+	n := 0
+	s.Range(0, 1000, func(elem int) bool {
+		if elem <= previous || !want[elem] {
+			t.Errorf("Unexpected element %d after %d.", elem, previous)
+		}
+		previous = elem
+		n++
+		return true
+	})
+	if n != len(want) {
+		t.Errorf("Expected to range over %d elements, got %d.", len(want), n)
+	}
+	if allocs := testing.AllocsPerRun(100, func() { s.Contains(500) }); allocs != 0 {
+		t.Errorf("Expected Contains not to allocate, got %v allocations.", allocs)
+	}
+}
+
+func TestTypedSetOptions(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Expected an unsupported option to panic.")
+		}
+	}()
+	NewTypedSet[int](WithShadowCheck(nil))
+}
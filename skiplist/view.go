@@ -0,0 +1,71 @@
+package skiplist
+
+import "sort"
+
+// A SortedView presents a SkipList as a read-only sorted slice, indexed
+// from 0. Accessing an element by index takes O(log n) time using the
+// spans of the list, so code written against sorted slices (like
+// sort.Search or binary searches) works on the list without copying
+// it, with an extra logarithmic factor.
+//
+// A SortedView reflects the current contents of the list; indexes
+// shift as elements are inserted or deleted.
+type SortedView struct {
+	list *SkipList
+}
+
+// AsSortedSlice returns a SortedView of s.
+func (s *SkipList) AsSortedSlice() SortedView {
+	return SortedView{list: s}
+}
+
+// Len returns the number of elements in the view.
+func (v SortedView) Len() int {
+	return v.list.Len()
+}
+
+// At returns the key and value of the i-th element. It panics if i is
+// out of range.
+func (v SortedView) At(i int) (key, value interface{}) {
+	n := v.node(i)
+	return n.key, v.list.decode(n.value)
+}
+
+// Key returns the key of the i-th element. It panics if i is out of
+// range.
+func (v SortedView) Key(i int) interface{} {
+	return v.node(i).key
+}
+
+func (v SortedView) node(i int) *node {
+	if i < 0 || i >= v.list.Len() {
+		panic("goskiplist: index out of range")
+	}
+	return v.list.nodeByRank(uint32(i) + 1)
+}
+
+// Less reports whether the i-th key is less than the j-th one. Together
+// with Len and Swap it implements sort.Interface, so sort.IsSorted and
+// similar functions accept a SortedView.
+func (v SortedView) Less(i, j int) bool {
+	return v.list.lessThan(v.Key(i), v.Key(j))
+}
+
+// Swap panics: the order of a SkipList is determined by its keys. It is
+// only present to implement sort.Interface.
+func (v SortedView) Swap(i, j int) {
+	panic("goskiplist: SortedView is read-only")
+}
+
+// BinarySearchFunc is like slices.BinarySearchFunc for the keys of the
+// view: it returns the position where target is found, or would be
+// inserted, and whether it was found. cmp must return a negative
+// number, zero or a positive number when key is respectively less
+// than, equal to, or greater than target, consistently with the order
+// of the list.
+func (v SortedView) BinarySearchFunc(target interface{}, cmp func(key, target interface{}) int) (int, bool) {
+	i := sort.Search(v.Len(), func(i int) bool {
+		return cmp(v.Key(i), target) >= 0
+	})
+	return i, i < v.Len() && cmp(v.Key(i), target) == 0
+}
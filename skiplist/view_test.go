@@ -0,0 +1,46 @@
+package skiplist
+
+import (
+	"sort"
+	"testing"
+)
+
+func TestSortedView(t *testing.T) {
+	s := NewIntMap()
+	for i := 0; i < 100; i++ {
+		s.Set(i*3, i)
+	}
+	v := s.AsSortedSlice()
+
+	if v.Len() != 100 || !sort.IsSorted(v) {
+		t.Fatalf("Expected a sorted view of 100 elements.")
+	}
+	if k, val := v.At(10); k != 30 || val != 10 {
+		t.Errorf("At(10) should be 30, 10, not %v, %v.", k, val)
+	}
+
+	cmp := func(key, target interface{}) int {
+		return key.(int) - target.(int)
+	}
+	if i, found := v.BinarySearchFunc(42, cmp); i != 14 || !found {
+		t.Errorf("BinarySearchFunc(42) should be 14, true, not %d, %v.", i, found)
+	}
+	if i, found := v.BinarySearchFunc(43, cmp); i != 15 || found {
+		t.Errorf("BinarySearchFunc(43) should be 15, false, not %d, %v.", i, found)
+	}
+	if i, found := v.BinarySearchFunc(1000, cmp); i != 100 || found {
+		t.Errorf("BinarySearchFunc(1000) should be 100, false, not %d, %v.", i, found)
+	}
+
+	s.Delete(0)
+	if v.Len() != 99 || v.Key(0) != 3 {
+		t.Errorf("The view should reflect deletions, first key is %v.", v.Key(0))
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("At should panic for an index out of range.")
+		}
+	}()
+	v.At(99)
+}
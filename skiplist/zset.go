@@ -1,12 +1,49 @@
 // redis like sorted set
 package skiplist
 
-import "math"
+import (
+	"encoding/binary"
+	"encoding/csv"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+)
 
 type ZSet struct {
-	key2Score map[interface{}]*zsetScore
-	sl        *SkipList
-	pool      *zsetScorePool
+	key2Score     map[interface{}]*zsetScore
+	sl            *SkipList
+	pool          *zsetScorePool
+	scoreLessThan func(l, r interface{}) bool
+
+	// generation is incremented by every write, invalidating the
+	// rank cache.
+	generation     uint64
+	rankCache      map[interface{}]uint32
+	rankCacheGen   uint64
+	rankCacheLimit int
+
+	// scoreEpsilon, if positive, is the difference below which float64
+	// scores are considered equal (see SetScoreEpsilon).
+	scoreEpsilon float64
+
+	// validateScore, if not nil, vets the scores given to Add and
+	// Update.
+	validateScore func(score interface{}) error
+
+	// tieBreak, if not nil, hashes members into the counters ordering
+	// equal scores (see SetTieBreakHash). defaultTieBreak is set when it
+	// is hashMember, which only takes scalar members.
+	tieBreak        func(member interface{}) uint64
+	defaultTieBreak bool
+
+	// batch, if not nil, maps the members with buffered scores to
+	// their position in batchScores (see BeginBatch).
+	batch       map[interface{}]int
+	batchScores [][2]interface{}
 }
 
 type zsetScore struct {
@@ -48,57 +85,252 @@ func (p *zsetScorePool) Put(s *zsetScore) {
 	}
 }
 
+// zsetScoreLessThan returns the comparison function of *zsetScore keys,
+// ordered by score, then by counter.
+func zsetScoreLessThan(scoreLessThan func(l, r interface{}) bool) func(l, r interface{}) bool {
+	return func(l, r interface{}) bool {
+		lzs := l.(*zsetScore)
+		rzs := r.(*zsetScore)
+		if scoreLessThan(lzs.score, rzs.score) {
+			return true
+		} else if scoreLessThan(rzs.score, lzs.score) {
+			return false
+		} else {
+			return lzs.counter < rzs.counter
+		}
+	}
+}
+
 func NewCustomZSet(scoreLessThan func(l, r interface{}) bool) *ZSet {
 	return &ZSet{
-		key2Score: make(map[interface{}]*zsetScore),
-		sl: NewCustomMap(func(l, r interface{}) bool {
-			lzs := l.(*zsetScore)
-			rzs := r.(*zsetScore)
-			if scoreLessThan(lzs.score, rzs.score) {
-				return true
-			} else if lzs.score == rzs.score && lzs.counter < rzs.counter {
-				return true
-			} else {
-				return false
-			}
-		}),
-		pool: newzsetScorePool(128),
+		key2Score:     make(map[interface{}]*zsetScore),
+		sl:            NewCustomMap(zsetScoreLessThan(scoreLessThan)),
+		pool:          newzsetScorePool(128),
+		scoreLessThan: scoreLessThan,
+	}
+}
+
+// scoreEqual returns true if neither of l and r is less than the other,
+// or if they are float64 scores closer than the score epsilon.
+func (z *ZSet) scoreEqual(l, r interface{}) bool {
+	if z.scoreEpsilon > 0 {
+		lf, lok := l.(float64)
+		rf, rok := r.(float64)
+		if lok && rok && math.Abs(lf-rf) <= z.scoreEpsilon {
+			return true
+		}
+	}
+	return !z.scoreLessThan(l, r) && !z.scoreLessThan(r, l)
+}
+
+// SetScoreEpsilon makes z consider float64 scores differing by at most
+// epsilon as equal, so that floating-point noise in recomputed scores
+// does not make members churn and flap between ranks: Add and Update
+// keep the current score of a member if the new one is that close, and
+// score ranges are widened by epsilon on both ends. Members are still
+// ordered by their exact scores. An epsilon of 0 restores exact
+// equality.
+func (z *ZSet) SetScoreEpsilon(epsilon float64) {
+	z.scoreEpsilon = epsilon
+}
+
+// SetTieBreakHash makes z order members with equal scores by hash(member)
+// instead of the order they were added in, so that sets built from the
+// same members and scores iterate in the same order however they were
+// built, as deterministic replays need. hash must be stable across
+// processes; if it is nil, members are hashed with FNV-1a in their %#v
+// format, which is only stable for strings, numbers and booleans:
+// adding members of other kinds (pointers, structs, slices...) then
+// panics, and hash should be given for them. Members already in z are
+// reordered.
+func (z *ZSet) SetTieBreakHash(hash func(member interface{}) uint64) {
+	defaultHash := hash == nil
+	if defaultHash {
+		for member := range z.key2Score {
+			checkScalarMember(member)
+		}
+		hash = hashMember
+	}
+	z.tieBreak, z.defaultTieBreak = hash, defaultHash
+	if z.sl.Len() == 0 {
+		return
+	}
+	z.generation++
+	elements := make([][2]interface{}, 0, z.sl.Len())
+	for n := z.sl.header.next(); n != nil; n = n.next() {
+		elements = append(elements, [2]interface{}{n.value, n.key.(*zsetScore).score})
+		z.pool.Put(n.key.(*zsetScore))
+	}
+	z.sl.Clear()
+	z.refill(elements)
+}
+
+// hashMember hashes member with FNV-1a in its %#v format, which is only
+// stable across processes for scalar members (see checkMember).
+func hashMember(member interface{}) uint64 {
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%#v", member)
+	return h.Sum64()
+}
+
+// checkMember panics if z orders ties with hashMember and member is not
+// a string, a number or a boolean, before member is added to z.
+func (z *ZSet) checkMember(member interface{}) {
+	if z.defaultTieBreak {
+		checkScalarMember(member)
+	}
+}
+
+func checkScalarMember(member interface{}) {
+	switch reflect.ValueOf(member).Kind() {
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128:
+	default:
+		panic(fmt.Sprintf("goskiplist: members of type %T need a tie-break hash function", member))
 	}
 }
 
+// tieBreakScore returns a new *zsetScore for key with score, whose
+// counter is derived from the hash of key if z has a tie-break hash.
+// Counters are kept within (0, math.MaxInt64), the bounds of score
+// ranges.
+func (z *ZSet) tieBreakScore(key interface{}, score interface{}) *zsetScore {
+	zScore := z.pool.Get(score)
+	if z.tieBreak != nil {
+		zScore.counter = int64(z.tieBreak(key)%(math.MaxInt64-2)) + 1
+	}
+	return zScore
+}
+
+// newScore is like tieBreakScore, but moves the counter past those of
+// the members of z with the same score and hash, if any.
+func (z *ZSet) newScore(key interface{}, score interface{}) *zsetScore {
+	zScore := z.tieBreakScore(key, score)
+	if z.tieBreak != nil {
+		for _, ok := z.sl.Get(zScore); ok; _, ok = z.sl.Get(zScore) {
+			zScore.counter++
+		}
+	}
+	return zScore
+}
+
+// fixTies moves the counters of the [*zsetScore, key] elements sorted
+// by score and counter so that no two are equal, as hash collisions
+// could make them.
+func (z *ZSet) fixTies(elements [][2]interface{}) {
+	if z.tieBreak == nil {
+		return
+	}
+	for i := 1; i < len(elements); i++ {
+		prev, cur := elements[i-1][0].(*zsetScore), elements[i][0].(*zsetScore)
+		if !z.sl.lessThan(prev, cur) {
+			cur.counter = prev.counter + 1
+		}
+	}
+}
+
+// scoreBounds returns the keys of z.sl delimiting the members whose
+// scores are between scoreFrom and scoreTo, both included, widened by
+// the score epsilon.
+func (z *ZSet) scoreBounds(scoreFrom interface{}, scoreTo interface{}) (from, to *zsetScore) {
+	if z.scoreEpsilon > 0 {
+		scoreFrom, scoreTo = z.widen(scoreFrom, false), z.widen(scoreTo, true)
+	}
+	return &zsetScore{score: scoreFrom}, &zsetScore{score: scoreTo, counter: math.MaxInt64}
+}
+
+// widen moves the float64 score by the score epsilon, upward in the
+// score order if up is true, downward otherwise.
+func (z *ZSet) widen(score interface{}, up bool) interface{} {
+	f, ok := score.(float64)
+	if !ok {
+		return score
+	}
+	lower, upper := interface{}(f-z.scoreEpsilon), interface{}(f+z.scoreEpsilon)
+	if z.scoreLessThan(upper, lower) {
+		// The scores are in descending order.
+		lower, upper = upper, lower
+	}
+	if up {
+		return upper
+	}
+	return lower
+}
+
 func NewZSet() *ZSet {
 	return NewCustomZSet(func(l, r interface{}) bool {
 		return l.(Ordered).LessThan(r.(Ordered))
 	})
 }
 
+// SetScoreValidator makes Add and Update check scores with validate
+// before using them. Scores for which validate returns an error, like
+// NaN or values of an unexpected type, would otherwise break the score
+// order or make the comparison function panic. Add and Update report
+// such scores by returning false; TryAdd returns the error.
+func (z *ZSet) SetScoreValidator(validate func(score interface{}) error) {
+	z.validateScore = validate
+}
+
+// TryAdd is like Add, but returns the error of the score validator (see
+// SetScoreValidator) instead of false.
+func (z *ZSet) TryAdd(key interface{}, score interface{}) error {
+	if z.validateScore != nil {
+		if err := z.validateScore(score); err != nil {
+			return err
+		}
+	}
+	z.add(key, score)
+	return nil
+}
+
 func (z *ZSet) Add(key interface{}, score interface{}) bool {
+	return z.TryAdd(key, score) == nil
+}
+
+func (z *ZSet) add(key interface{}, score interface{}) {
+	z.checkMember(key)
+	if z.batch != nil {
+		z.buffer(key, score)
+		return
+	}
 	curZScore, ok := z.key2Score[key]
 	if ok {
-		if score != curZScore.score { // update
+		if !z.scoreEqual(score, curZScore.score) { // update
+			z.generation++
 			z.sl.Delete(curZScore)
 			z.pool.Put(curZScore)
-			zScore := z.pool.Get(score)
+			zScore := z.newScore(key, score)
 			z.sl.Set(zScore, key)
 			z.key2Score[key] = zScore
 		}
 	} else {
-		zScore := z.pool.Get(score)
+		z.generation++
+		zScore := z.newScore(key, score)
 		z.key2Score[key] = zScore
 		z.sl.Set(zScore, key)
 	}
-	return true
 }
 
 func (z *ZSet) Update(key interface{}, score interface{}) bool {
 	curZScore, ok := z.key2Score[key]
-	if !ok {
+	if _, pending := z.batch[key]; !ok && !pending {
 		return false
 	}
-	if score != curZScore.score { // update
+	if z.validateScore != nil && z.validateScore(score) != nil {
+		return false
+	}
+	if z.batch != nil {
+		z.buffer(key, score)
+		return true
+	}
+	if !z.scoreEqual(score, curZScore.score) { // update
+		z.generation++
 		z.sl.Delete(curZScore)
 		z.pool.Put(curZScore)
-		zScore := z.pool.Get(score)
+		zScore := z.newScore(key, score)
 		z.sl.Set(zScore, key)
 		z.key2Score[key] = zScore
 	}
@@ -106,22 +338,415 @@ func (z *ZSet) Update(key interface{}, score interface{}) bool {
 }
 
 func (z *ZSet) Remove(key interface{}) bool {
+	_, pending := z.batch[key]
+	delete(z.batch, key)
 	curZScore, ok := z.key2Score[key]
 	if !ok {
-		return false
+		return pending
 	}
+	z.generation++
 	z.sl.Delete(curZScore)
 	z.pool.Put(curZScore)
 	delete(z.key2Score, key)
 	return true
 }
 
+// RenameMember gives the member oldKey the identity newKey, keeping its
+// score and, unless a tie-break hash is set, its position among members
+// with equal scores. It returns false if oldKey is not a member or
+// newKey already is.
+func (z *ZSet) RenameMember(oldKey, newKey interface{}) bool {
+	curZScore, ok := z.key2Score[oldKey]
+	if !ok {
+		return false
+	}
+	if _, ok := z.key2Score[newKey]; ok {
+		return false
+	}
+	z.checkMember(newKey)
+	z.generation++
+	delete(z.key2Score, oldKey)
+	if z.tieBreak != nil {
+		// The position of newKey among ties depends on its hash.
+		z.sl.Delete(curZScore)
+		z.pool.Put(curZScore)
+		curZScore = z.newScore(newKey, curZScore.score)
+	}
+	z.key2Score[newKey] = curZScore
+	z.sl.Set(curZScore, newKey)
+	return true
+}
+
+// RemoveBatch removes the given members, ignoring those not in the set,
+// and returns the number of members removed. The members are removed in
+// score order in a single traversal, which is faster than calling
+// Remove for each of them when they are many. Like Remove, it discards
+// the scores buffered for them since BeginBatch.
+func (z *ZSet) RemoveBatch(keys []interface{}) int {
+	scores := make([]interface{}, 0, len(keys))
+	// pending counts the members only added since BeginBatch.
+	pending := 0
+	for _, key := range keys {
+		_, buffered := z.batch[key]
+		delete(z.batch, key)
+		if curZScore, ok := z.key2Score[key]; ok {
+			scores = append(scores, curZScore)
+			delete(z.key2Score, key)
+		} else if buffered {
+			pending++
+		}
+	}
+	if len(scores) == 0 {
+		return pending
+	}
+	z.generation++
+	sort.Slice(scores, func(i, j int) bool {
+		return z.sl.lessThan(scores[i], scores[j])
+	})
+	z.sl.deleteSorted(scores)
+	for _, zScore := range scores {
+		z.pool.Put(zScore.(*zsetScore))
+	}
+	return len(scores) + pending
+}
+
+// BeginBatch starts buffering the scores given to Add and Update, until
+// EndBatch applies them all at once. Reads do not see buffered scores,
+// and removing a member discards its buffered score. This makes sense
+// when updating the scores of a large part of the set, like when they
+// are periodically recomputed.
+func (z *ZSet) BeginBatch() {
+	if z.batch == nil {
+		z.batch = make(map[interface{}]int)
+	}
+}
+
+// buffer records score as the new score of key until EndBatch.
+func (z *ZSet) buffer(key interface{}, score interface{}) {
+	if i, ok := z.batch[key]; ok {
+		z.batchScores[i][1] = score
+		return
+	}
+	z.batch[key] = len(z.batchScores)
+	z.batchScores = append(z.batchScores, [2]interface{}{key, score})
+}
+
+// EndBatch applies the scores buffered since BeginBatch and stops
+// buffering. Members whose score changed rank after those with the same
+// score, in the order they were first buffered. Instead of deleting and
+// reinserting every changed member, the set is rebuilt with a single
+// merge, in O(n + k*log(k)) time for k changed members.
+func (z *ZSet) EndBatch() {
+	batch, batchScores := z.batch, z.batchScores
+	z.batch, z.batchScores = nil, nil
+
+	var changed [][2]interface{}
+	replaced := make(map[*zsetScore]bool)
+	for i, elem := range batchScores {
+		key, score := elem[0], elem[1]
+		if j, ok := batch[key]; !ok || j != i {
+			// Removed since it was buffered.
+			continue
+		}
+		curZScore, ok := z.key2Score[key]
+		if ok {
+			if z.scoreEqual(score, curZScore.score) {
+				continue
+			}
+			replaced[curZScore] = true
+		}
+		zScore := z.tieBreakScore(key, score)
+		z.key2Score[key] = zScore
+		changed = append(changed, [2]interface{}{zScore, key})
+	}
+	if len(changed) == 0 {
+		return
+	}
+	z.generation++
+	sort.Slice(changed, func(i, j int) bool {
+		return z.sl.lessThan(changed[i][0], changed[j][0])
+	})
+
+	elements := make([][2]interface{}, 0, len(z.key2Score))
+	iter := z.sl.Iterator()
+	ok := iter.Next()
+	for ok || len(changed) > 0 {
+		if ok && replaced[iter.Key().(*zsetScore)] {
+			ok = iter.Next()
+			continue
+		}
+		if !ok || (len(changed) > 0 && z.sl.lessThan(changed[0][0], iter.Key())) {
+			elements = append(elements, changed[0])
+			changed = changed[1:]
+		} else {
+			elements = append(elements, [2]interface{}{iter.Key(), iter.Value()})
+			ok = iter.Next()
+		}
+	}
+	z.sl.Clear()
+	z.fixTies(elements)
+	z.sl.FillBySortedSlice(elements)
+	for zScore := range replaced {
+		z.pool.Put(zScore)
+	}
+}
+
+// ApplyToAllScores replaces the score of every member with the result
+// of fn, called once per member in rank order, like when periodically
+// decaying or resetting all the scores. The set is rebuilt with a bulk
+// fill, after sorting the new scores only if fn did not preserve their
+// order. Members whose new scores are equal keep their relative order.
+// If a score validator is set and rejects any of the new scores,
+// ApplyToAllScores returns false and leaves z unchanged. Scores buffered
+// since BeginBatch are not transformed.
+func (z *ZSet) ApplyToAllScores(fn func(score interface{}) interface{}) bool {
+	elements := make([][2]interface{}, 0, len(z.key2Score))
+	monotonic := true
+	for n := z.sl.header.next(); n != nil; n = n.next() {
+		score := fn(n.key.(*zsetScore).score)
+		if z.validateScore != nil && z.validateScore(score) != nil {
+			return false
+		}
+		if last := len(elements) - 1; last >= 0 && z.scoreLessThan(score, elements[last][1]) {
+			monotonic = false
+		}
+		elements = append(elements, [2]interface{}{n.value, score})
+	}
+	if len(elements) == 0 {
+		return true
+	}
+	if !monotonic {
+		sort.SliceStable(elements, func(i, j int) bool {
+			return z.scoreLessThan(elements[i][1], elements[j][1])
+		})
+	}
+
+	z.generation++
+	for n := z.sl.header.next(); n != nil; n = n.next() {
+		z.pool.Put(n.key.(*zsetScore))
+	}
+	z.sl.Clear()
+	z.refill(elements)
+	return true
+}
+
+// refill fills the empty skip list of z with elements, given as [key,
+// score] pairs in score order, and turns them into its [*zsetScore, key]
+// elements in place.
+func (z *ZSet) refill(elements [][2]interface{}) bool {
+	for i, elem := range elements {
+		// Scores are taken in order, so that ties are ordered as
+		// elements unless they are ordered by hash.
+		zScore := z.tieBreakScore(elem[0], elem[1])
+		z.key2Score[elem[0]] = zScore
+		elements[i] = [2]interface{}{zScore, elem[0]}
+	}
+	if z.tieBreak != nil {
+		sort.SliceStable(elements, func(i, j int) bool {
+			return z.sl.lessThan(elements[i][0], elements[j][0])
+		})
+		z.fixTies(elements)
+	}
+	return z.sl.FillBySortedSlice(elements)
+}
+
+// EnableRankCache makes Rank remember the ranks of up to limit members
+// until the next write, so that repeated queries for the same members
+// are O(1). A limit of 0 disables the cache.
+func (z *ZSet) EnableRankCache(limit int) {
+	z.rankCacheLimit = limit
+	z.rankCache = nil
+	if limit > 0 {
+		z.rankCache = make(map[interface{}]uint32)
+		z.rankCacheGen = z.generation
+	}
+}
+
 func (z *ZSet) Rank(key interface{}) uint32 {
 	curZScore, ok := z.key2Score[key]
 	if !ok {
 		return 0
 	}
-	return z.sl.Rank(curZScore)
+	if z.rankCache == nil {
+		return z.sl.Rank(curZScore)
+	}
+
+	if z.rankCacheGen != z.generation {
+		z.rankCache = make(map[interface{}]uint32)
+		z.rankCacheGen = z.generation
+	} else if rank, ok := z.rankCache[key]; ok {
+		return rank
+	}
+	rank := z.sl.Rank(curZScore)
+	if len(z.rankCache) < z.rankCacheLimit {
+		z.rankCache[key] = rank
+	}
+	return rank
+}
+
+// Ranks returns the ranks of keys, like Rank, 0 for those that are
+// not members. The members are sorted by score and searched with a
+// finger (see WithFingerSearch), which climbs from where the previous
+// member was found rather than descending from the top of the list, so
+// that ranking k members takes O(k*log(n/k)) time after sorting rather
+// than O(k*log(n)).
+func (z *ZSet) Ranks(keys []interface{}) []uint32 {
+	ranks := make([]uint32, len(keys))
+	order := make([]int, 0, len(keys))
+	for i, key := range keys {
+		if _, ok := z.key2Score[key]; ok {
+			order = append(order, i)
+		}
+	}
+	sort.Slice(order, func(i, j int) bool {
+		return z.sl.lessThan(z.key2Score[keys[order[i]]], z.key2Score[keys[order[j]]])
+	})
+
+	var f finger
+	for _, i := range order {
+		// The score is in the list, right after the finger.
+		f.search(z.sl, z.key2Score[keys[i]])
+		ranks[i] = f.ranks[0] + 1
+	}
+	return ranks
+}
+
+// A RankDelta describes the ranks of a member in two ZSets.
+type RankDelta struct {
+	Key interface{}
+	// Rank and OtherRank are the ranks of Key in the compared sets,
+	// 0 if it is absent.
+	Rank      uint32
+	OtherRank uint32
+	// Delta is OtherRank - Rank, or 0 if Key is absent from either
+	// set. A positive Delta means Key ranks lower in the other set.
+	Delta int64
+}
+
+// CompareRanks returns the ranks of keys in z and in other, and how
+// they differ.
+func (z *ZSet) CompareRanks(other *ZSet, keys []interface{}) []RankDelta {
+	deltas := make([]RankDelta, len(keys))
+	for i, key := range keys {
+		d := RankDelta{Key: key, Rank: z.Rank(key), OtherRank: other.Rank(key)}
+		if d.Rank != 0 && d.OtherRank != 0 {
+			d.Delta = int64(d.OtherRank) - int64(d.Rank)
+		}
+		deltas[i] = d
+	}
+	return deltas
+}
+
+// A MovementKind tells how a member moved within the top of a ZSet.
+type MovementKind int
+
+const (
+	// Entered means the member was not in the top before.
+	Entered MovementKind = iota
+	// Left means the member is no longer in the top.
+	Left
+	// Moved means the member is still in the top, at another rank.
+	Moved
+)
+
+// A Movement describes how a member moved within the top of a ZSet.
+type Movement struct {
+	Key  interface{}
+	Kind MovementKind
+	// Rank and PrevRank are the ranks of Key in the current and the
+	// previous set, 0 if it is not in their top.
+	Rank     uint32
+	PrevRank uint32
+}
+
+// RankDiff compares the topN members of z with those of prev, like an
+// earlier snapshot of z, in O(topN) time. It returns the members that
+// entered or moved within the top, in rank order, followed by those that
+// left it, in their previous rank order. Members whose rank did not
+// change are omitted. Sorting the result by PrevRank - Rank gives the
+// biggest climbers.
+func (z *ZSet) RankDiff(prev *ZSet, topN int) []Movement {
+	prevRanks := make(map[interface{}]uint32, topN)
+	var rank uint32
+	for n := prev.sl.header.next(); n != nil && int(rank) < topN; n = n.next() {
+		rank++
+		prevRanks[n.value] = rank
+	}
+
+	var movements []Movement
+	rank = 0
+	for n := z.sl.header.next(); n != nil && int(rank) < topN; n = n.next() {
+		rank++
+		prevRank, ok := prevRanks[n.value]
+		delete(prevRanks, n.value)
+		switch {
+		case !ok:
+			movements = append(movements, Movement{Key: n.value, Kind: Entered, Rank: rank})
+		case prevRank != rank:
+			movements = append(movements, Movement{Key: n.value, Kind: Moved, Rank: rank, PrevRank: prevRank})
+		}
+	}
+
+	left := len(movements)
+	for key, prevRank := range prevRanks {
+		movements = append(movements, Movement{Key: key, Kind: Left, PrevRank: prevRank})
+	}
+	sort.Slice(movements[left:], func(i, j int) bool {
+		return movements[left+i].PrevRank < movements[left+j].PrevRank
+	})
+	return movements
+}
+
+// Exists returns true if key is a member of z. Unlike Score, it is safe
+// to call for absent members.
+func (z *ZSet) Exists(key interface{}) bool {
+	_, ok := z.key2Score[key]
+	return ok
+}
+
+// CardByScore returns the number of members with scores in [scoreFrom,
+// scoreTo], in O(log(n)).
+func (z *ZSet) CardByScore(scoreFrom interface{}, scoreTo interface{}) int {
+	lo, hi := z.scoreBounds(scoreFrom, scoreTo)
+	from, to := z.sl.countLess(lo), z.sl.countLess(hi)
+	if to < from {
+		return 0
+	}
+	return int(to - from)
+}
+
+// RangeHash returns a hash of the members with scores in [scoreFrom,
+// scoreTo] and their scores, for replicas of a ZSet to check that they
+// agree on a range without exchanging it, and narrow down differences
+// by hashing halves of ranges that do not match. It is computed on
+// demand, in O(log(n) + k) for k members in the range.
+//
+// The hash depends on the order of the scores but not on the order of
+// members with equal scores, which depends on the order they were
+// added in. Members and scores are hashed in their %#v format, so
+// values formatted alike are deemed equal.
+func (z *ZSet) RangeHash(scoreFrom interface{}, scoreTo interface{}) uint64 {
+	h := fnv.New64a()
+	var sum uint64
+	var buf [8]byte
+	lo, hi := z.scoreBounds(scoreFrom, scoreTo)
+	for n := z.sl.getLowerBound(z.sl.header, lo); n != nil && z.sl.lessThan(n.key, hi); {
+		// The members of a run of equal scores are combined by a sum,
+		// in any order, before the run is folded into sum.
+		score := n.key.(*zsetScore).score
+		var members uint64
+		for ; n != nil && z.sl.lessThan(n.key, hi) && z.scoreEqual(n.key.(*zsetScore).score, score); n = n.next() {
+			members += hashMember(n.value)
+		}
+		h.Reset()
+		binary.BigEndian.PutUint64(buf[:], sum)
+		h.Write(buf[:])
+		fmt.Fprintf(h, "%#v", score)
+		binary.BigEndian.PutUint64(buf[:], members)
+		h.Write(buf[:])
+		sum = h.Sum64()
+	}
+	return sum
 }
 
 func (z *ZSet) Score(key interface{}) interface{} {
@@ -153,7 +778,7 @@ func (z *ZSet) RangeByRank(rankFrom uint32, rankTo uint32) [][2]interface{} { //
 }
 
 func (z *ZSet) RangeByScore(scoreFrom interface{}, scoreTo interface{}) []interface{} { // [scoreFrom, scoreTo]
-	iter := z.sl.Range(&zsetScore{score: scoreFrom}, &zsetScore{score: scoreTo, counter: math.MaxInt64})
+	iter := z.sl.Range(z.scoreBounds(scoreFrom, scoreTo))
 	keys := make([]interface{}, 0, 8)
 	rangeIter := iter.(*rangeIterator)
 	for rangeIter.Next() {
@@ -162,10 +787,240 @@ func (z *ZSet) RangeByScore(scoreFrom interface{}, scoreTo interface{}) []interf
 	return keys
 }
 
+// AroundScore returns the k members ranked just before score and the k
+// members ranked from it on, as {member, score} pairs in rank order, like
+// RangeByRank: members whose score equals score (within the score
+// epsilon) are counted after it. Fewer members are returned near the
+// ends of z. It takes O(log(n) + k) time.
+func (z *ZSet) AroundScore(score interface{}, k int) [][2]interface{} {
+	if k <= 0 {
+		return nil
+	}
+	lo, _ := z.scoreBounds(score, score)
+	// before is the number of members ranked before score.
+	before := z.sl.countLess(lo)
+	rankFrom := uint32(1)
+	if before > uint32(k) {
+		rankFrom = before - uint32(k) + 1
+	}
+	return z.RangeByRank(rankFrom, before+uint32(k))
+}
+
+// derive returns a new, empty ZSet with the same score order, score
+// epsilon, score validator and tie-break hash as z, whose counters
+// follow those of z, for the methods building a ZSet out of z.
+func (z *ZSet) derive() *ZSet {
+	d := NewCustomZSet(z.scoreLessThan)
+	d.pool.counter = z.pool.counter
+	d.scoreEpsilon = z.scoreEpsilon
+	d.validateScore = z.validateScore
+	d.tieBreak, d.defaultTieBreak = z.tieBreak, z.defaultTieBreak
+	return d
+}
+
+// ExtractScoreRange removes the members with scores in [scoreFrom,
+// scoreTo] and returns them as a new ZSet using the same score order,
+// score epsilon, score validator and tie-break hash. Members with equal
+// scores keep their relative order, in the new set and relative to
+// members added to it later.
+func (z *ZSet) ExtractScoreRange(scoreFrom interface{}, scoreTo interface{}) *ZSet {
+	extracted := z.derive()
+	var elements [][2]interface{}
+	iter := z.sl.Range(z.scoreBounds(scoreFrom, scoreTo))
+	for iter.Next() {
+		elements = append(elements, [2]interface{}{iter.Key(), iter.Value()})
+	}
+	if len(elements) == 0 {
+		return extracted
+	}
+	z.generation++
+	for _, elem := range elements {
+		z.sl.Delete(elem[0])
+		delete(z.key2Score, elem[1])
+		extracted.key2Score[elem[1]] = elem[0].(*zsetScore)
+	}
+	extracted.sl.FillBySortedSlice(elements)
+	return extracted
+}
+
+// SubsetView returns a new ZSet holding the members of z among members,
+// with their scores, for rank and range queries restricted to them,
+// like a leaderboard of friends. Members with equal scores keep their
+// relative order, and members that are not in z are ignored. It takes
+// O(k*log(k)) time for k members, whatever the size of z, and the
+// result is a copy: later changes to z do not affect it.
+func (z *ZSet) SubsetView(members []interface{}) *ZSet {
+	view := z.derive()
+	elements := make([][2]interface{}, 0, len(members))
+	for _, member := range members {
+		zs, ok := z.key2Score[member]
+		if !ok || view.key2Score[member] != nil {
+			continue
+		}
+		// The scores are copied, as z recycles them.
+		zs = &zsetScore{score: zs.score, counter: zs.counter}
+		view.key2Score[member] = zs
+		elements = append(elements, [2]interface{}{zs, member})
+	}
+	sort.Slice(elements, func(i, j int) bool {
+		return view.sl.lessThan(elements[i][0], elements[j][0])
+	})
+	view.sl.FillBySortedSlice(elements)
+	return view
+}
+
+// Histogram counts the members in the score buckets delimited by
+// buckets, which must be sorted in ascending order. The returned slice
+// has len(buckets)+1 counts: the first for scores less than
+// buckets[0], the i-th for scores in [buckets[i-1], buckets[i]), and
+// the last for scores greater or equal to the last boundary. It takes
+// O(len(buckets) * log(n)) time.
+func (z *ZSet) Histogram(buckets []interface{}) []int {
+	counts := make([]int, len(buckets)+1)
+	var previous uint32
+	for i, boundary := range buckets {
+		less := z.sl.countLess(&zsetScore{score: boundary})
+		counts[i] = int(less - previous)
+		previous = less
+	}
+	counts[len(buckets)] = z.sl.Len() - int(previous)
+	return counts
+}
+
+// scoreWeight converts a numeric score to a float64 weight. Negative
+// scores weigh nothing.
+func scoreWeight(score interface{}) float64 {
+	return math.Max(scoreFloat(score), 0)
+}
+
+// scoreFloat converts a numeric score to a float64.
+func scoreFloat(score interface{}) float64 {
+	var w float64
+	switch score := score.(type) {
+	case int:
+		w = float64(score)
+	case int8:
+		w = float64(score)
+	case int16:
+		w = float64(score)
+	case int32:
+		w = float64(score)
+	case int64:
+		w = float64(score)
+	case uint:
+		w = float64(score)
+	case uint8:
+		w = float64(score)
+	case uint16:
+		w = float64(score)
+	case uint32:
+		w = float64(score)
+	case uint64:
+		w = float64(score)
+	case float32:
+		w = float64(score)
+	case float64:
+		w = score
+	default:
+		panic(fmt.Sprintf("goskiplist: score of type %T is not numeric", score))
+	}
+	return w
+}
+
+// WeightedRandom returns n members picked at random (with replacement)
+// with probabilities proportional to their scores, which must be
+// numeric. It returns nil if the total score is not positive.
+//
+// The first call makes z maintain the cumulative scores of its members
+// alongside its skip list, which takes O(n) time once and makes every
+// subsequent write slightly more expensive. Each pick then takes
+// O(log n) time.
+func (z *ZSet) WeightedRandom(n int) []interface{} {
+	if z.sl.augment == nil {
+		z.sl.setAugmentation(&Monoid{
+			Lift: func(key, value interface{}) interface{} {
+				return scoreWeight(key.(*zsetScore).score)
+			},
+			Combine: func(a, b interface{}) interface{} {
+				return a.(float64) + b.(float64)
+			},
+			Identity: 0.0,
+		})
+	}
+
+	total := z.sl.Aggregate().(float64)
+	if total <= 0 {
+		return nil
+	}
+	members := make([]interface{}, n)
+	for i := range members {
+		members[i] = z.sl.nodeAtWeight(z.sl.random() * total).value
+	}
+	return members
+}
+
 func (z *ZSet) Card() int { // 集合元素个数
 	return len(z.key2Score)
 }
 
+// Audit checks that the members of z, their scores and the skip list
+// ordering them agree: every member is linked once, in score order,
+// under the score recorded for it, the spans of the skip list give the
+// right ranks, and so do the cached ranks, if any. It returns an error
+// describing the first inconsistency found, or nil. It takes
+// O(n*log(n)) time, and is meant for periodic self-checks of services
+// that would otherwise serve wrong ranks silently.
+func (z *ZSet) Audit() error {
+	s := z.sl
+	if s.length != len(z.key2Score) {
+		return fmt.Errorf("goskiplist: zset audit: skiplist length is %d, Card is %d", s.length, len(z.key2Score))
+	}
+	// ranks maps the linked nodes to their positions at level 0.
+	ranks := make(map[*node]uint32, s.length)
+	var previous *zsetScore
+	for n := s.header.next(); n != nil; n = n.next() {
+		zs, ok := n.key.(*zsetScore)
+		if !ok {
+			return fmt.Errorf("goskiplist: zset audit: key %v of %v is not a score", n.key, n.value)
+		}
+		if z.key2Score[n.value] != zs {
+			return fmt.Errorf("goskiplist: zset audit: %v is linked with score %v, recorded %v", n.value, zs.score, z.Score(n.value))
+		}
+		if previous != nil && !s.lessThan(previous, zs) {
+			return fmt.Errorf("goskiplist: zset audit: %v is out of order, with score %v and counter %d", n.value, zs.score, zs.counter)
+		}
+		if z.tieBreak == nil && (zs.counter <= 0 || zs.counter > z.pool.counter) {
+			return fmt.Errorf("goskiplist: zset audit: %v has counter %d, issued %d", n.value, zs.counter, z.pool.counter)
+		}
+		previous = zs
+		ranks[n] = uint32(len(ranks) + 1)
+	}
+	if len(ranks) != s.length {
+		return fmt.Errorf("goskiplist: zset audit: %d members linked, Card is %d", len(ranks), s.length)
+	}
+	for i := 0; i <= s.level(); i++ {
+		var rank uint32
+		for x := s.header; x.levels[i].forward != nil; x = x.levels[i].forward {
+			rank += x.levels[i].span
+			if next := x.levels[i].forward; ranks[next] != rank {
+				return fmt.Errorf("goskiplist: zset audit: %v has rank %d at level %d, expected %d", next.value, rank, i, ranks[next])
+			}
+		}
+	}
+	if z.rankCache != nil && z.rankCacheGen == z.generation {
+		for key, rank := range z.rankCache {
+			zs, ok := z.key2Score[key]
+			if !ok {
+				return fmt.Errorf("goskiplist: zset audit: rank of %v is cached, but it is not a member", key)
+			}
+			if want := z.sl.Rank(zs); rank != want {
+				return fmt.Errorf("goskiplist: zset audit: cached rank of %v is %d, expected %d", key, rank, want)
+			}
+		}
+	}
+	return nil
+}
+
 func (z *ZSet) Foreach(fn func(key interface{}, score interface{})) {
 	iter := z.sl.Iterator()
 	for iter.Next() {
@@ -173,7 +1028,66 @@ func (z *ZSet) Foreach(fn func(key interface{}, score interface{})) {
 	}
 }
 
+// ForeachScore calls fn for the members whose score is equal to score,
+// in the order they got it.
+func (z *ZSet) ForeachScore(score interface{}, fn func(key interface{}, score interface{})) {
+	iter := z.sl.Range(z.scoreBounds(score, score))
+	for iter.Next() {
+		fn(iter.Value(), iter.Key().(*zsetScore).score)
+	}
+}
+
+// CSVOptions configures WriteCSV.
+type CSVOptions struct {
+	// Comma is the field delimiter (',' if zero). Use '\t' for TSV.
+	Comma rune
+	// Header, if true, makes WriteCSV start with a header record.
+	Header bool
+	// FormatMember and FormatScore format members and scores
+	// (fmt.Sprint if nil).
+	FormatMember func(member interface{}) string
+	FormatScore  func(score interface{}) string
+}
+
+// WriteCSV writes a member, score, rank record for every member of z,
+// in rank order, to w. Records are written as the set is iterated, so
+// no intermediate copy of the set is made.
+func (z *ZSet) WriteCSV(w io.Writer, opts CSVOptions) error {
+	formatMember, formatScore := opts.FormatMember, opts.FormatScore
+	if formatMember == nil {
+		formatMember = func(member interface{}) string { return fmt.Sprint(member) }
+	}
+	if formatScore == nil {
+		formatScore = func(score interface{}) string { return fmt.Sprint(score) }
+	}
+
+	cw := csv.NewWriter(w)
+	if opts.Comma != 0 {
+		cw.Comma = opts.Comma
+	}
+	if opts.Header {
+		if err := cw.Write([]string{"member", "score", "rank"}); err != nil {
+			return err
+		}
+	}
+
+	record := make([]string, 3)
+	rank := 0
+	for iter := z.sl.Iterator(); iter.Next(); {
+		rank++
+		record[0] = formatMember(iter.Value())
+		record[1] = formatScore(iter.Key().(*zsetScore).score)
+		record[2] = strconv.Itoa(rank)
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
 func (z *ZSet) Clear() {
+	z.generation++
 	z.key2Score = make(map[interface{}]*zsetScore)
 	z.sl.Clear()
 }
@@ -187,12 +1101,52 @@ func (z *ZSet) Marshal() [][2]interface{} {
 	return elements
 }
 
+// MarshalAppend is like Marshal, appending the elements to buf and
+// returning the extended slice, so that periodic snapshots can reuse
+// the same buffer by passing buf[:0].
+func (z *ZSet) MarshalAppend(buf [][2]interface{}) [][2]interface{} {
+	for n := z.sl.header.next(); n != nil; n = n.next() {
+		buf = append(buf, [2]interface{}{n.value, n.key.(*zsetScore).score})
+	}
+	return buf
+}
+
+// All returns an iterator (an iter.Seq2, for use with range) over the
+// members of z and their scores, in rank order, for streaming the
+// elements of Marshal without materializing them. z must not be
+// modified during the iteration.
+func (z *ZSet) All() func(yield func(key interface{}, score interface{}) bool) {
+	return func(yield func(key interface{}, score interface{}) bool) {
+		for n := z.sl.header.next(); n != nil; n = n.next() {
+			if !yield(n.value, n.key.(*zsetScore).score) {
+				return
+			}
+		}
+	}
+}
+
+// IterWithRank returns an iterator (an iter.Seq2, for use with range)
+// over the ranks of the members of z, starting from 1, and the members
+// with their scores, as [member, score] pairs like those of
+// RangeByRank. Ranks are counted during the iteration, so exporting a
+// ranked leaderboard takes O(n) time instead of calling Rank for every
+// member. z must not be modified during the iteration.
+func (z *ZSet) IterWithRank() func(yield func(rank uint32, member [2]interface{}) bool) {
+	return func(yield func(rank uint32, member [2]interface{}) bool) {
+		var rank uint32
+		for n := z.sl.header.next(); n != nil; n = n.next() {
+			rank++
+			if !yield(rank, [2]interface{}{n.value, n.key.(*zsetScore).score}) {
+				return
+			}
+		}
+	}
+}
+
 func (z *ZSet) Unmarshal(elements [][2]interface{}) bool {
-	for i, elem := range elements {
-		zScore := z.pool.Get(elem[1])
-		z.key2Score[elem[0]] = zScore
-		elements[i][0] = zScore
-		elements[i][1] = elem[0]
+	for _, elem := range elements {
+		z.checkMember(elem[0])
 	}
-	return z.sl.FillBySortedSlice(elements)
+	z.generation++
+	return z.refill(elements)
 }
@@ -1,7 +1,14 @@
 package skiplist
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
 	"math/rand"
+	"reflect"
+	"strconv"
 	"testing"
 )
 
@@ -80,6 +87,838 @@ func TestZSetRank(t *testing.T) {
 	}
 }
 
+func TestZSetEqualScores(t *testing.T) {
+	zs := NewCustomZSet(func(l, r interface{}) bool {
+		return l.(*big.Int).Cmp(r.(*big.Int)) < 0
+	})
+	zs.Add("foo", big.NewInt(10))
+	zs.Add("bar", big.NewInt(10))
+	zs.Add("baz", big.NewInt(5))
+	if zs.Rank("baz") != 1 || zs.Rank("foo") != 2 || zs.Rank("bar") != 3 {
+		t.Errorf("rank perform wrong")
+	}
+	zs.Add("foo", big.NewInt(10))
+	if zs.Rank("foo") != 2 {
+		t.Errorf("adding an equal score should not change rank")
+	}
+	if !zs.Remove("bar") || zs.Card() != 2 || zs.Rank("foo") != 2 {
+		t.Errorf("remove perform wrong")
+	}
+}
+
+func TestZSetRemoveBatch(t *testing.T) {
+	zs := NewCustomZSet(func(l, r interface{}) bool {
+		return l.(int) < r.(int)
+	})
+	for i := 0; i < 1000; i++ {
+		zs.Add(i, (i*7)%100)
+	}
+	var keys []interface{}
+	for i := 999; i >= 0; i -= 3 {
+		keys = append(keys, i, i)
+	}
+	keys = append(keys, "missing")
+	if n := zs.RemoveBatch(keys); n != 334 {
+		t.Errorf("remove batch should remove 334 members, removed %d", n)
+	}
+	if zs.Card() != 666 || zs.sl.Len() != 666 {
+		t.Errorf("remove batch perform wrong: %d members", zs.Card())
+	}
+	var previous interface{}
+	rank := uint32(0)
+	zs.Foreach(func(key interface{}, score interface{}) {
+		rank++
+		if (999-key.(int))%3 == 0 || zs.Rank(key) != rank {
+			t.Errorf("remove batch perform wrong at %v", key)
+		}
+		if previous != nil && score.(int) < previous.(int) {
+			t.Errorf("remove batch broke the order at %v", key)
+		}
+		previous = score
+	})
+	if zs.RemoveBatch(keys) != 0 {
+		t.Errorf("removing absent members should remove nothing")
+	}
+}
+
+func TestZSetExistsAndCardByScore(t *testing.T) {
+	zs := NewCustomZSet(func(l, r interface{}) bool {
+		return l.(int) < r.(int)
+	})
+	for i := 0; i < 100; i++ {
+		zs.Add(i, i/10)
+	}
+	if !zs.Exists(42) || zs.Exists(100) {
+		t.Errorf("exists perform wrong")
+	}
+	for _, c := range []struct{ from, to, card int }{
+		{0, 9, 100}, {3, 3, 10}, {2, 4, 30}, {-5, 0, 10}, {9, 20, 10}, {5, 2, 0}, {10, 20, 0},
+	} {
+		if card := zs.CardByScore(c.from, c.to); card != c.card {
+			t.Errorf("card by score [%d, %d] should be %d, not %d", c.from, c.to, c.card, card)
+		}
+	}
+}
+
+func TestZSetScoreValidator(t *testing.T) {
+	zs := NewCustomZSet(func(l, r interface{}) bool {
+		return l.(float64) < r.(float64)
+	})
+	zs.SetScoreValidator(func(score interface{}) error {
+		f, ok := score.(float64)
+		if !ok {
+			return fmt.Errorf("score %v is not a float64", score)
+		}
+		if math.IsNaN(f) {
+			return errors.New("score is NaN")
+		}
+		return nil
+	})
+	if err := zs.TryAdd("foo", 1.0); err != nil {
+		t.Errorf("try add perform wrong: %v", err)
+	}
+	if err := zs.TryAdd("bar", math.NaN()); err == nil || zs.Exists("bar") {
+		t.Errorf("NaN score should be rejected")
+	}
+	if zs.Add("bar", 2) || zs.Exists("bar") {
+		t.Errorf("int score should be rejected")
+	}
+	if zs.Update("foo", math.NaN()) || zs.Score("foo") != 1.0 {
+		t.Errorf("update with NaN score should be rejected")
+	}
+	if !zs.Update("foo", 3.0) || !zs.Add("bar", 2.0) || zs.Rank("bar") != 1 {
+		t.Errorf("valid scores should be accepted")
+	}
+}
+
+func TestZSetScoreEpsilon(t *testing.T) {
+	for _, descending := range []bool{false, true} {
+		zs := NewCustomZSet(func(l, r interface{}) bool {
+			return (l.(float64) < r.(float64)) != descending
+		})
+		zs.SetScoreEpsilon(1e-9)
+		zs.Add("foo", 0.3)
+		zs.Add("bar", 0.3)
+		zs.Add("baz", 1.0)
+		rank := zs.Rank("foo")
+		zs.Update("foo", 0.1+0.2)
+		if zs.Score("foo") != 0.3 || zs.Rank("foo") != rank {
+			t.Errorf("noise should not update scores: %v, rank %d", zs.Score("foo"), zs.Rank("foo"))
+		}
+		if keys := zs.RangeByScore(0.1+0.2, 0.3); len(keys) != 2 {
+			t.Errorf("range by score should include noisy scores: %v", keys)
+		}
+		from, to := 0.30000000001, 1-1e-12
+		if descending {
+			from, to = to, from
+		}
+		if zs.CardByScore(from, to) != 3 {
+			t.Errorf("card by score perform wrong: %d", zs.CardByScore(from, to))
+		}
+		zs.Update("foo", 0.31)
+		if zs.Score("foo") != 0.31 {
+			t.Errorf("scores beyond epsilon should update: %v", zs.Score("foo"))
+		}
+	}
+}
+
+func TestZSetRenameMember(t *testing.T) {
+	zs := NewCustomZSet(func(l, r interface{}) bool {
+		return l.(int) < r.(int)
+	})
+	zs.EnableRankCache(10)
+	zs.Add("a", 1)
+	zs.Add("b", 1)
+	zs.Add("c", 1)
+	if zs.Rank("b") != 2 {
+		t.Errorf("rank perform wrong")
+	}
+	if !zs.RenameMember("b", "z") {
+		t.Fatalf("rename member perform wrong")
+	}
+	if zs.Exists("b") || zs.Rank("b") != 0 || zs.Rank("z") != 2 || zs.Score("z") != 1 {
+		t.Errorf("renamed member should keep its rank")
+	}
+	if ranked := zs.RangeByRank(2, 2); ranked[0][0] != "z" {
+		t.Errorf("renamed member should be in the list: %v", ranked)
+	}
+	if zs.RenameMember("missing", "y") || zs.RenameMember("a", "c") || zs.Card() != 3 {
+		t.Errorf("rename member should fail for missing or existing members")
+	}
+}
+
+func TestZSetForeachScore(t *testing.T) {
+	zs := NewCustomZSet(func(l, r interface{}) bool {
+		return l.(int) < r.(int)
+	})
+	for _, m := range []string{"d", "a", "c", "b"} {
+		zs.Add(m, 10)
+	}
+	zs.Add("low", 5)
+	zs.Add("high", 15)
+	zs.Update("a", 11)
+	zs.Update("a", 10)
+	var members []interface{}
+	zs.ForeachScore(10, func(key interface{}, score interface{}) {
+		members = append(members, key)
+	})
+	expected := []interface{}{"d", "c", "b", "a"}
+	if len(members) != len(expected) {
+		t.Fatalf("foreach score perform wrong: %v", members)
+	}
+	for i := range expected {
+		if members[i] != expected[i] {
+			t.Errorf("foreach score perform wrong: %v", members)
+			break
+		}
+	}
+	zs.ForeachScore(7, func(key interface{}, score interface{}) {
+		t.Errorf("no member has score 7, got %v", key)
+	})
+}
+
+func TestZSetBatch(t *testing.T) {
+	zs := NewCustomZSet(func(l, r interface{}) bool {
+		return l.(int) < r.(int)
+	})
+	for i := 0; i < 100; i++ {
+		zs.Add(i, i)
+	}
+	zs.BeginBatch()
+	for i := 0; i < 100; i += 2 {
+		zs.Update(i, 1000-i)
+	}
+	zs.Add("new", 500)
+	zs.Add("gone", 1)
+	zs.Remove("gone")
+	zs.Remove(99)
+	zs.Update(1, 7)
+	zs.Update(1, 1)
+	if zs.Rank(0) != 1 || zs.Card() != 99 || zs.Exists("new") {
+		t.Errorf("batched scores should not be visible before end batch")
+	}
+	zs.EndBatch()
+
+	if zs.Card() != 100 || zs.sl.Len() != 100 || zs.Exists("gone") || zs.Exists(99) {
+		t.Fatalf("end batch perform wrong: %d members", zs.Card())
+	}
+	if zs.Rank(1) != 1 || zs.Rank(97) != 49 || zs.Rank("new") != 50 || zs.Rank(98) != 51 || zs.Rank(0) != 100 {
+		t.Errorf("end batch perform wrong: %v", zs.RangeByRank(45, 55))
+	}
+	zs.Add(40, 958)
+	if zs.Rank(42) != 79 || zs.Rank(40) != 80 {
+		t.Errorf("ties should rank after batched members: %d", zs.Rank(40))
+	}
+}
+
+func TestZSetBatchRemoveBatch(t *testing.T) {
+	zs := NewCustomZSet(func(l, r interface{}) bool {
+		return l.(int) < r.(int)
+	})
+	zs.Add("a", 1)
+	zs.Add("b", 2)
+	zs.BeginBatch()
+	zs.Update("a", 10)
+	zs.Add("c", 3)
+	if n := zs.RemoveBatch([]interface{}{"a", "c", "missing"}); n != 2 {
+		t.Errorf("remove batch should remove 2 members, removed %d", n)
+	}
+	zs.EndBatch()
+	if zs.Card() != 1 || zs.Exists("a") || zs.Exists("c") || zs.Rank("b") != 1 {
+		t.Errorf("remove batch should discard buffered scores: %d members", zs.Card())
+	}
+}
+
+func TestZSetApplyToAllScores(t *testing.T) {
+	zs := NewCustomZSet(func(l, r interface{}) bool {
+		return l.(int) < r.(int)
+	})
+	for i := 0; i < 100; i++ {
+		zs.Add(i, i)
+	}
+	// Decay, merging neighbouring scores.
+	zs.ApplyToAllScores(func(score interface{}) interface{} {
+		return score.(int) / 10
+	})
+	if zs.Card() != 100 || zs.sl.Len() != 100 || zs.Score(57) != 5 {
+		t.Fatalf("apply to all scores perform wrong: %v", zs.Score(57))
+	}
+	for i := 0; i < 100; i++ {
+		if zs.Rank(i) != uint32(i+1) {
+			t.Errorf("apply to all scores should keep ties in order: rank of %d is %d", i, zs.Rank(i))
+		}
+	}
+	zs.Add(50, 0)
+	if zs.Rank(50) != 11 {
+		t.Errorf("updated member should rank after ties: %d", zs.Rank(50))
+	}
+
+	// Reverse the order, keeping ties in order.
+	zs.ApplyToAllScores(func(score interface{}) interface{} {
+		return -score.(int)
+	})
+	if zs.Rank(90) != 1 || zs.Rank(99) != 10 || zs.Rank(0) != 90 || zs.Rank(50) != 100 {
+		t.Errorf("apply to all scores perform wrong: %v", zs.RangeByRank(1, 10))
+	}
+
+	zs.SetScoreValidator(func(score interface{}) error {
+		if score.(int) > 0 {
+			return errors.New("positive")
+		}
+		return nil
+	})
+	if zs.ApplyToAllScores(func(score interface{}) interface{} { return score.(int) + 5 }) || zs.Score(99) != -9 {
+		t.Errorf("rejected scores should leave the set unchanged: %v", zs.Score(99))
+	}
+}
+
+func TestZSetMarshalAppend(t *testing.T) {
+	zs := NewCustomZSet(func(l, r interface{}) bool {
+		return l.(int) < r.(int)
+	})
+	for i := 0; i < 10; i++ {
+		zs.Add(i, 10-i)
+	}
+	buf := make([][2]interface{}, 0, 16)
+	buf = zs.MarshalAppend(buf[:0])
+	buf = zs.MarshalAppend(buf[:0])
+	marshaled := zs.Marshal()
+	if len(buf) != 10 || cap(buf) != 16 {
+		t.Fatalf("marshal append should reuse the buffer: len %d, cap %d", len(buf), cap(buf))
+	}
+	for i := range marshaled {
+		if buf[i] != marshaled[i] {
+			t.Errorf("marshal append perform wrong: %v", buf[i])
+		}
+	}
+
+	for rank, member := range zs.IterWithRank() {
+		if zs.Rank(member[0]) != rank || member != marshaled[rank-1] {
+			t.Errorf("iter with rank perform wrong: %d, %v", rank, member)
+		}
+		if rank == 7 {
+			break
+		}
+	}
+
+	i := 0
+	for key, score := range zs.All() {
+		if key != marshaled[i][0] || score != marshaled[i][1] {
+			t.Errorf("all perform wrong: %v, %v", key, score)
+		}
+		if i++; i == 5 {
+			break
+		}
+	}
+}
+
+func TestZSetRankCache(t *testing.T) {
+	zs := NewCustomZSet(func(l, r interface{}) bool {
+		return l.(int) < r.(int)
+	})
+	zs.EnableRankCache(2)
+	for i := 0; i < 10; i++ {
+		zs.Add(i, i)
+	}
+	for j := 0; j < 2; j++ {
+		for i := 0; i < 10; i++ {
+			if zs.Rank(i) != uint32(i+1) {
+				t.Errorf("rank perform wrong")
+			}
+		}
+	}
+	if len(zs.rankCache) != 2 {
+		t.Errorf("rank cache should be limited to 2 members, has %d", len(zs.rankCache))
+	}
+
+	zs.Update(9, -1)
+	if zs.Rank(9) != 1 || zs.Rank(0) != 2 {
+		t.Errorf("rank cache not invalidated by update")
+	}
+	zs.Remove(9)
+	if zs.Rank(0) != 1 || zs.Rank(9) != 0 {
+		t.Errorf("rank cache not invalidated by remove")
+	}
+	zs.Add(-5, -5)
+	if zs.Rank(0) != 2 {
+		t.Errorf("rank cache not invalidated by add")
+	}
+
+	zs.EnableRankCache(0)
+	if zs.rankCache != nil || zs.Rank(0) != 2 {
+		t.Errorf("rank cache not disabled")
+	}
+}
+
+func TestZSetHistogram(t *testing.T) {
+	zs := NewCustomZSet(func(l, r interface{}) bool {
+		return l.(int) < r.(int)
+	})
+	for i := 0; i < 100; i++ {
+		zs.Add(i, i%50)
+	}
+	counts := zs.Histogram([]interface{}{0, 10, 25, 49, 60})
+	expected := []int{0, 20, 30, 48, 2, 0}
+	for i := range expected {
+		if counts[i] != expected[i] {
+			t.Errorf("histogram perform wrong: %v", counts)
+			break
+		}
+	}
+	if counts := zs.Histogram(nil); len(counts) != 1 || counts[0] != 100 {
+		t.Errorf("histogram perform wrong: %v", counts)
+	}
+}
+
+func TestZSetCompareRanks(t *testing.T) {
+	weekly := NewCustomZSet(func(l, r interface{}) bool {
+		return l.(int) > r.(int)
+	})
+	allTime := NewCustomZSet(func(l, r interface{}) bool {
+		return l.(int) > r.(int)
+	})
+	weekly.Add("foo", 10)
+	weekly.Add("bar", 20)
+	allTime.Add("foo", 300)
+	allTime.Add("bar", 100)
+	allTime.Add("baz", 200)
+
+	deltas := weekly.CompareRanks(allTime, []interface{}{"foo", "bar", "baz"})
+	expected := []RankDelta{
+		{Key: "foo", Rank: 2, OtherRank: 1, Delta: -1},
+		{Key: "bar", Rank: 1, OtherRank: 3, Delta: 2},
+		{Key: "baz", Rank: 0, OtherRank: 2, Delta: 0},
+	}
+	for i := range expected {
+		if deltas[i] != expected[i] {
+			t.Errorf("compare ranks perform wrong: %+v", deltas[i])
+		}
+	}
+}
+
+func TestZSetRankDiff(t *testing.T) {
+	greater := func(l, r interface{}) bool {
+		return l.(int) > r.(int)
+	}
+	prev, cur := NewCustomZSet(greater), NewCustomZSet(greater)
+	for _, key := range []string{"a", "b", "c", "d", "e", "f"} {
+		prev.Add(key, 100-len(prev.key2Score))
+	}
+	// b climbs, x enters, a and c drop, d leaves the top 4.
+	for key, score := range map[string]int{"b": 200, "x": 150, "a": 99, "c": 98, "d": 10, "e": 96} {
+		cur.Add(key, score)
+	}
+
+	movements := cur.RankDiff(prev, 4)
+	expected := []Movement{
+		{Key: "b", Kind: Moved, Rank: 1, PrevRank: 2},
+		{Key: "x", Kind: Entered, Rank: 2},
+		{Key: "a", Kind: Moved, Rank: 3, PrevRank: 1},
+		{Key: "c", Kind: Moved, Rank: 4, PrevRank: 3},
+		{Key: "d", Kind: Left, PrevRank: 4},
+	}
+	if len(movements) != len(expected) {
+		t.Fatalf("rank diff perform wrong: %+v", movements)
+	}
+	for i := range expected {
+		if movements[i] != expected[i] {
+			t.Errorf("rank diff perform wrong: %+v", movements[i])
+		}
+	}
+	if movements := cur.RankDiff(cur, 10); len(movements) != 0 {
+		t.Errorf("rank diff of a set with itself should be empty: %+v", movements)
+	}
+}
+
+func TestZSetWeightedRandom(t *testing.T) {
+	zs := NewCustomZSet(func(l, r interface{}) bool {
+		return l.(int) < r.(int)
+	})
+	if zs.WeightedRandom(1) != nil {
+		t.Errorf("weighted random on empty set should return nil")
+	}
+	zs.Add("never", 0)
+	zs.Add("rare", 1)
+	zs.Add("common", 9)
+	zs.Add("removed", 100)
+	zs.Remove("removed")
+
+	counts := make(map[interface{}]int)
+	for _, m := range zs.WeightedRandom(10000) {
+		counts[m]++
+	}
+	if counts["never"] != 0 || counts["removed"] != 0 || counts["rare"] < 700 || counts["rare"] > 1300 {
+		t.Errorf("weighted random perform wrong: %v", counts)
+	}
+
+	// Writes after the first call keep the cumulative scores current.
+	zs.Update("rare", 90)
+	zs.Add("new", 1)
+	counts = make(map[interface{}]int)
+	for _, m := range zs.WeightedRandom(10000) {
+		counts[m]++
+	}
+	if counts["rare"] < 8500 || counts["new"] == 0 {
+		t.Errorf("weighted random perform wrong after update: %v", counts)
+	}
+
+	for i := 0; i < 1000; i++ {
+		zs.Add(i, i%7)
+	}
+	for i := 0; i < 1000; i += 3 {
+		zs.Remove(i)
+	}
+	var total float64
+	zs.Foreach(func(key interface{}, score interface{}) {
+		total += float64(score.(int))
+	})
+	if got := zs.sl.Aggregate().(float64); got != total {
+		t.Errorf("cumulative score is %v, expected %v", got, total)
+	}
+}
+
+func TestZSetWriteCSV(t *testing.T) {
+	zs := NewCustomZSet(func(l, r interface{}) bool {
+		return l.(float64) > r.(float64)
+	})
+	zs.Add("foo", 1.5)
+	zs.Add("bar, inc", 3.0)
+
+	var buf bytes.Buffer
+	if err := zs.WriteCSV(&buf, CSVOptions{Header: true}); err != nil {
+		t.Fatalf("write csv failed: %v", err)
+	}
+	if got := buf.String(); got != "member,score,rank\n\"bar, inc\",3,1\nfoo,1.5,2\n" {
+		t.Errorf("write csv perform wrong: %q", got)
+	}
+
+	buf.Reset()
+	err := zs.WriteCSV(&buf, CSVOptions{
+		Comma: '\t',
+		FormatScore: func(score interface{}) string {
+			return strconv.FormatFloat(score.(float64), 'f', 2, 64)
+		},
+	})
+	if err != nil || buf.String() != "bar, inc\t3.00\t1\nfoo\t1.50\t2\n" {
+		t.Errorf("write tsv perform wrong: %q, %v", buf.String(), err)
+	}
+}
+
+func TestZSetExtractScoreRange(t *testing.T) {
+	zs := NewCustomZSet(func(l, r interface{}) bool {
+		return l.(int) < r.(int)
+	})
+	for i := 0; i < 20; i++ {
+		zs.Add(i, i/2)
+	}
+	band := zs.ExtractScoreRange(3, 5)
+	if zs.Card() != 14 || band.Card() != 6 {
+		t.Fatalf("extract score range perform wrong: %d, %d", zs.Card(), band.Card())
+	}
+	for i, ks := range band.RangeByRank(1, 6) {
+		if ks[0].(int) != i+6 || ks[1].(int) != (i+6)/2 {
+			t.Errorf("extract score range perform wrong: %v", ks)
+		}
+	}
+	if zs.Rank(6) != 0 || zs.Rank(12) != 7 {
+		t.Errorf("extracted members should be removed")
+	}
+
+	band.Add("late", 4)
+	if band.Rank("late") != 5 || band.Rank(9) != 4 {
+		t.Errorf("ties should be ordered after extracted members")
+	}
+	if empty := zs.ExtractScoreRange(100, 200); empty.Card() != 0 || zs.Card() != 14 {
+		t.Errorf("extract score range perform wrong on empty band")
+	}
+}
+
+func TestZSetExtractScoreRangeSettings(t *testing.T) {
+	zs := NewCustomZSet(func(l, r interface{}) bool {
+		return l.(float64) < r.(float64)
+	})
+	zs.SetScoreEpsilon(0.01)
+	zs.SetScoreValidator(func(score interface{}) error {
+		if math.IsNaN(score.(float64)) {
+			return errors.New("NaN score")
+		}
+		return nil
+	})
+	for i := 0; i < 10; i++ {
+		zs.Add(i, float64(i))
+	}
+	for _, derived := range []*ZSet{zs.ExtractScoreRange(2.0, 5.0), zs.SubsetView([]interface{}{6, 7})} {
+		member := derived.RangeByRank(1, 1)[0][0]
+		score := derived.Score(member)
+		derived.Update(member, score.(float64)+0.001)
+		if derived.Score(member) != score {
+			t.Errorf("derived zset should keep the score epsilon")
+		}
+		if derived.Add("nan", math.NaN()) || derived.Exists("nan") {
+			t.Errorf("derived zset should keep the score validator")
+		}
+	}
+}
+
+func TestZSetRangeHash(t *testing.T) {
+	byFloat := func(l, r interface{}) bool {
+		return l.(float64) < r.(float64)
+	}
+	a, b := NewCustomZSet(byFloat), NewCustomZSet(byFloat)
+	for i := 0; i < 100; i++ {
+		a.Add(i, float64(i/4))
+		b.Add(99-i, float64((99-i)/4))
+	}
+	if a.RangeHash(0.0, 100.0) != b.RangeHash(0.0, 100.0) {
+		t.Errorf("range hash should not depend on the order of ties")
+	}
+	if a.RangeHash(0.0, 10.0) == a.RangeHash(0.0, 11.0) {
+		t.Errorf("range hash should cover the whole range")
+	}
+	if a.RangeHash(100.0, 200.0) != 0 {
+		t.Errorf("range hash of an empty range should be 0")
+	}
+
+	b.Update(50, 13.0)
+	if a.RangeHash(0.0, 10.0) != b.RangeHash(0.0, 10.0) {
+		t.Errorf("range hash perform wrong outside the difference")
+	}
+	if a.RangeHash(10.0, 20.0) == b.RangeHash(10.0, 20.0) {
+		t.Errorf("range hash should change with a score")
+	}
+	b.Update(50, 12.0)
+	b.Remove(51)
+	b.Add("51", 12.0)
+	if a.RangeHash(10.0, 20.0) == b.RangeHash(10.0, 20.0) {
+		t.Errorf("range hash should change with a member")
+	}
+
+	c := NewCustomZSet(byFloat)
+	c.Add(1, 1.0)
+	c.Add(2, 2.0)
+	d := NewCustomZSet(byFloat)
+	d.Add(2, 1.0)
+	d.Add(1, 2.0)
+	if c.RangeHash(0.0, 3.0) == d.RangeHash(0.0, 3.0) {
+		t.Errorf("range hash should depend on the order of scores")
+	}
+}
+
+func TestZSetAroundScore(t *testing.T) {
+	z := NewCustomZSet(func(l, r interface{}) bool {
+		return l.(float64) < r.(float64)
+	})
+	for i := 0; i < 10; i++ {
+		z.Add(i, float64(i*100))
+	}
+	members := func(pairs [][2]interface{}) []interface{} {
+		var keys []interface{}
+		for _, p := range pairs {
+			keys = append(keys, p[0])
+		}
+		return keys
+	}
+	if got := members(z.AroundScore(450.0, 2)); !reflect.DeepEqual(got, []interface{}{3, 4, 5, 6}) {
+		t.Errorf("around score perform wrong: %v", got)
+	}
+	if got := members(z.AroundScore(500.0, 1)); !reflect.DeepEqual(got, []interface{}{4, 5}) {
+		t.Errorf("around score perform wrong on an equal score: %v", got)
+	}
+	if got := members(z.AroundScore(-1.0, 3)); !reflect.DeepEqual(got, []interface{}{0, 1, 2}) {
+		t.Errorf("around score perform wrong at the start: %v", got)
+	}
+	if got := z.AroundScore(2000.0, 2); len(got) != 2 || got[1][0] != 9 || got[1][1] != 900.0 {
+		t.Errorf("around score perform wrong at the end: %v", got)
+	}
+	if got := z.AroundScore(450.0, 0); got != nil {
+		t.Errorf("around score perform wrong with k = 0: %v", got)
+	}
+}
+
+func TestZSetAudit(t *testing.T) {
+	z := NewCustomZSet(func(l, r interface{}) bool {
+		return l.(float64) < r.(float64)
+	})
+	z.EnableRankCache(10)
+	for i := 0; i < 100; i++ {
+		z.Add(i, float64(i%7))
+	}
+	z.Update(3, 2.5)
+	z.Remove(50)
+	z.Rank(10)
+	if err := z.Audit(); err != nil {
+		t.Fatalf("audit perform wrong: %v", err)
+	}
+	z.SetTieBreakHash(nil)
+	z.BeginBatch()
+	z.Add(200, 1.0)
+	z.EndBatch()
+	if err := z.Audit(); err != nil {
+		t.Fatalf("audit perform wrong with tie breaks: %v", err)
+	}
+
+	z.Rank(20)
+	z.rankCache[20]++
+	if err := z.Audit(); err == nil {
+		t.Errorf("audit should detect a wrong cached rank")
+	}
+	delete(z.rankCache, 20)
+
+	z.key2Score[1] = &zsetScore{score: 1.0, counter: 1}
+	if err := z.Audit(); err == nil {
+		t.Errorf("audit should detect a wrong score")
+	}
+	z.key2Score[1] = z.sl.header.next().key.(*zsetScore)
+	if err := z.Audit(); err == nil {
+		t.Errorf("audit should detect a shared score")
+	}
+	delete(z.key2Score, 1)
+	if err := z.Audit(); err == nil {
+		t.Errorf("audit should detect a missing member")
+	}
+}
+
+func TestZSetRanks(t *testing.T) {
+	z := NewCustomZSet(func(l, r interface{}) bool {
+		return l.(float64) < r.(float64)
+	})
+	for i := 0; i < 1000; i++ {
+		z.Add(i, float64(i*7%1000))
+	}
+	keys := []interface{}{999, 3, -1, 500, 3, 0, 2000, 42}
+	ranks := z.Ranks(keys)
+	for i, key := range keys {
+		if ranks[i] != z.Rank(key) {
+			t.Errorf("ranks perform wrong for %v: %d, expected %d", key, ranks[i], z.Rank(key))
+		}
+	}
+	if ranks := z.Ranks(nil); len(ranks) != 0 {
+		t.Errorf("ranks perform wrong without keys: %v", ranks)
+	}
+
+	all := make([]interface{}, 0, 1000)
+	for i := 999; i >= 0; i -= 3 {
+		all = append(all, i)
+	}
+	ranks = z.Ranks(all)
+	for i, key := range all {
+		if ranks[i] != z.Rank(key) {
+			t.Errorf("ranks perform wrong for %v: %d, expected %d", key, ranks[i], z.Rank(key))
+		}
+	}
+}
+
+func TestZSetSubsetView(t *testing.T) {
+	z := NewCustomZSet(func(l, r interface{}) bool {
+		return l.(float64) < r.(float64)
+	})
+	for i := 0; i < 100; i++ {
+		z.Add(i, float64(i%10))
+	}
+	friends := []interface{}{95, 3, 13, 200, 40, 3}
+	view := z.SubsetView(friends)
+	if view.Card() != 4 {
+		t.Fatalf("subset view perform wrong: %d members", view.Card())
+	}
+	if got := view.RangeByRank(1, 4); !reflect.DeepEqual(got, [][2]interface{}{{40, 0.0}, {3, 3.0}, {13, 3.0}, {95, 5.0}}) {
+		t.Errorf("subset view perform wrong: %v", got)
+	}
+	if err := view.Audit(); err != nil {
+		t.Errorf("subset view perform wrong: %v", err)
+	}
+
+	z.Update(40, 9.0)
+	z.Remove(3)
+	if view.Rank(40) != 1 || view.Rank(3) != 2 {
+		t.Errorf("subset view should not follow the set")
+	}
+	view.Add(50, 4.0)
+	if view.Rank(95) != 5 || view.Rank(50) != 4 || z.Rank(50) != 5 {
+		t.Errorf("subset view perform wrong after Add: %d", view.Rank(95))
+	}
+}
+
+func TestZSetTieBreakHash(t *testing.T) {
+	byInt := func(l, r interface{}) bool {
+		return l.(int) < r.(int)
+	}
+	a, b := NewCustomZSet(byInt), NewCustomZSet(byInt)
+	a.SetTieBreakHash(nil)
+	b.SetTieBreakHash(nil)
+	for i := 0; i < 100; i++ {
+		a.Add(i, i%5)
+		b.Add(99-i, (99-i)%5)
+	}
+	order := func(z *ZSet) (members []interface{}) {
+		for member := range z.All() {
+			members = append(members, member)
+		}
+		return members
+	}
+	if !reflect.DeepEqual(order(a), order(b)) {
+		t.Errorf("tie break hash perform wrong: %v, %v", order(a), order(b))
+	}
+
+	c := NewCustomZSet(byInt)
+	c.SetTieBreakHash(nil)
+	c.Unmarshal(a.Marshal())
+	if !reflect.DeepEqual(order(a), order(c)) {
+		t.Errorf("tie break hash perform wrong after unmarshal: %v", order(c))
+	}
+
+	d := NewCustomZSet(byInt)
+	for i := 0; i < 100; i++ {
+		d.Add(i, i%5)
+	}
+	d.SetTieBreakHash(nil)
+	if !reflect.DeepEqual(order(a), order(d)) {
+		t.Errorf("setting the tie break hash should reorder members: %v", order(d))
+	}
+
+	// A constant hash makes all the members collide.
+	e := NewCustomZSet(byInt)
+	e.SetTieBreakHash(func(interface{}) uint64 { return 7 })
+	for i := 0; i < 10; i++ {
+		e.Add(i, 0)
+	}
+	f := NewCustomZSet(byInt)
+	f.SetTieBreakHash(func(interface{}) uint64 { return 7 })
+	f.Unmarshal(e.Marshal())
+	if e.Card() != 10 || f.Card() != 10 || e.Rank(9) != 10 || f.Rank(9) != 10 {
+		t.Errorf("tie break hash perform wrong on collisions: %d, %d", e.Card(), f.Card())
+	}
+
+	g := NewCustomZSet(byInt)
+	g.SetTieBreakHash(nil)
+	g.Add(customType(1), 0)
+	for _, member := range []interface{}{new(int), struct{ p *int }{}, []int{1}} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("tie break hash perform wrong: %T members should need a hash function", member)
+				}
+			}()
+			g.Add(member, 0)
+		}()
+	}
+	if g.Card() != 1 || g.Audit() != nil {
+		t.Errorf("tie break hash perform wrong: rejected members should not be added")
+	}
+	h := NewCustomZSet(byInt)
+	h.Add(new(int), 0)
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("tie break hash perform wrong: existing pointer members should need a hash function")
+			}
+		}()
+		h.SetTieBreakHash(nil)
+	}()
+	if !h.Add(new(int), 0) || h.Card() != 2 || h.Audit() != nil {
+		t.Errorf("tie break hash perform wrong: rejecting a hash function should keep the members")
+	}
+}
+
 func shuffleArray(array []int) {
 	for len(array) != 0 {
 		pos := rand.Intn(len(array))